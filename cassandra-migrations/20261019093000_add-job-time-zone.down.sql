@@ -0,0 +1 @@
+ALTER TABLE jobs DROP time_zone;
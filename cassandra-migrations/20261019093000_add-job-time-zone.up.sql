@@ -0,0 +1 @@
+ALTER TABLE jobs ADD time_zone text;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS job_events (
+  user_id text,
+  event_id text,
+  type text,
+  job_id text,
+  execution_id text,
+  status text,
+  created_at timestamp,
+  PRIMARY KEY (user_id, event_id)
+) WITH default_time_to_live = 86400;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS execution_output (
+  execution_id text,
+  seq int,
+  data text,
+  created_at timestamp,
+  PRIMARY KEY (execution_id, seq)
+);
@@ -0,0 +1,28 @@
+ALTER TABLE jobs ADD labels map<text, text>;
+
+CREATE TABLE IF NOT EXISTS webhooks (
+  user_id text,
+  webhook_id text,
+  url text,
+  job_id text,
+  label_selector text,
+  event_types list<text>,
+  secret text,
+  created_at timestamp,
+  PRIMARY KEY (user_id, webhook_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  webhook_id text,
+  delivery_id text,
+  event_id text,
+  event_type text,
+  payload text,
+  status text,
+  attempts int,
+  status_code int,
+  error_message text,
+  created_at timestamp,
+  updated_at timestamp,
+  PRIMARY KEY (webhook_id, delivery_id)
+) WITH CLUSTERING ORDER BY (delivery_id DESC);
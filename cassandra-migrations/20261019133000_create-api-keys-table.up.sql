@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+  key_id text,
+  user_id text,
+  subject text,
+  name text,
+  key_hash text,
+  scopes list<text>,
+  expires_at timestamp,
+  last_used_at timestamp,
+  revoked_at timestamp,
+  created_at timestamp,
+  PRIMARY KEY (key_id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE job_grants;
+ALTER TABLE jobs DROP project;
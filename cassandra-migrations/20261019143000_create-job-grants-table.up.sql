@@ -0,0 +1,12 @@
+ALTER TABLE jobs ADD project text;
+
+CREATE TABLE IF NOT EXISTS job_grants (
+  resource_type text,
+  resource_id text,
+  subject_type text,
+  subject_id text,
+  role text,
+  created_by text,
+  created_at timestamp,
+  PRIMARY KEY ((resource_type, resource_id), subject_type, subject_id)
+);
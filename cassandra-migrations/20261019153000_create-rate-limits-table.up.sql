@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+  bucket_key text,
+  tokens double,
+  updated_at timestamp,
+  PRIMARY KEY (bucket_key)
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS usage_counters (
+  user_id text,
+  day text,
+  executions counter,
+  sandbox_ms counter,
+  PRIMARY KEY ((user_id, day))
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+  resource_type text,
+  resource_id text,
+  created_at timestamp,
+  entry_id text,
+  action text,
+  actor_id text,
+  actor_source text,
+  api_key_id text,
+  request_id text,
+  before text,
+  after text,
+  PRIMARY KEY ((resource_type, resource_id), created_at, entry_id)
+) WITH CLUSTERING ORDER BY (created_at DESC, entry_id DESC);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS workers (
+  worker_id text,
+  host text,
+  capacity int,
+  languages list<text>,
+  version text,
+  current_load int,
+  registered_at timestamp,
+  last_heartbeat timestamp,
+  PRIMARY KEY (worker_id)
+);
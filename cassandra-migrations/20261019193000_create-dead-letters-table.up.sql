@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS dead_letters (
+  dead_letter_id text,
+  job_id text,
+  user_id text,
+  worker_id text,
+  reason text,
+  deliveries int,
+  body text,
+  dead_lettered_at timestamp,
+  PRIMARY KEY (dead_letter_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE job_executions ADD attempt int;
@@ -0,0 +1,3 @@
+-- job_execution_attempts is kept, since executions recorded after the upgrade exist only there.
+-- job_executions was never changed, so the previous release reads it as before.
+SELECT table_name FROM system_schema.tables WHERE keyspace_name = 'system_schema' AND table_name = 'tables';
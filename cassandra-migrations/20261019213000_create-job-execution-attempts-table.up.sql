@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS job_execution_attempts (
+  execution_id text,
+  job_id text,
+  attempt int,
+  worker_id text,
+  start_time timestamp,
+  end_time timestamp,
+  status text,
+  output text,
+  error_message text,
+  PRIMARY KEY (job_id, worker_id, status, execution_id)
+);
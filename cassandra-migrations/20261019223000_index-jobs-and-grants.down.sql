@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS job_grants_subject_id_idx;
+DROP INDEX IF EXISTS jobs_project_idx;
+DROP INDEX IF EXISTS jobs_job_id_idx;
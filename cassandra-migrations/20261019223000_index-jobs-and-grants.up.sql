@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS jobs_job_id_idx ON jobs (job_id);
+CREATE INDEX IF NOT EXISTS jobs_project_idx ON jobs (project);
+CREATE INDEX IF NOT EXISTS job_grants_subject_id_idx ON job_grants (subject_id);
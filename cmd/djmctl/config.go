@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/julianstephens/distributed-job-manager/pkg/client"
+)
+
+const (
+	authAPIKey            = "api-key"
+	authClientCredentials = "client-credentials"
+	authDevice            = "device"
+)
+
+// settings are read from the environment; flags override the server and auth method.
+type settings struct {
+	Server       string   `env:"DJM_SERVER" envDefault:"http://localhost:8080/api/v1"`
+	Auth         string   `env:"DJM_AUTH"`
+	APIKey       string   `env:"DJM_API_KEY"`
+	ClientID     string   `env:"DJM_CLIENT_ID"`
+	ClientSecret string   `env:"DJM_CLIENT_SECRET"`
+	Auth0Domain  string   `env:"DJM_AUTH0_DOMAIN"`
+	TokenURL     string   `env:"DJM_TOKEN_URL"`
+	DeviceURL    string   `env:"DJM_DEVICE_URL"`
+	Audience     string   `env:"DJM_AUDIENCE"`
+	Scopes       []string `env:"DJM_SCOPES" envSeparator:" " envDefault:"openid offline_access read:jobs write:jobs read:schedules"`
+}
+
+var envUsage = []string{
+	"DJM_SERVER          job service API, default http://localhost:8080/api/v1",
+	"DJM_AUTH            api-key, client-credentials or device; picked from the other variables if unset",
+	"DJM_API_KEY         API key to authenticate with",
+	"DJM_CLIENT_ID       OAuth2 client for client credentials and device code auth",
+	"DJM_CLIENT_SECRET   OAuth2 client secret for client credentials auth",
+	"DJM_AUTH0_DOMAIN    Auth0 tenant whose endpoints to use",
+	"DJM_TOKEN_URL       OAuth2 token endpoint, instead of the Auth0 tenant's",
+	"DJM_DEVICE_URL      OAuth2 device authorization endpoint, instead of the Auth0 tenant's",
+	"DJM_AUDIENCE        audience of requested tokens",
+	"DJM_SCOPES          space separated scopes requested by device code auth",
+}
+
+// globals are the flags every command accepts.
+type globals struct {
+	server string
+	auth   string
+	output string
+}
+
+// newFlagSet returns a flag set for a command with the global flags registered. argsUsage
+// describes its positional arguments.
+func newFlagSet(name string, argsUsage string) (*flag.FlagSet, *globals) {
+	g := &globals{}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&g.server, "server", "", "job service API, overrides DJM_SERVER")
+	fs.StringVar(&g.auth, "auth", "", "api-key, client-credentials or device, overrides DJM_AUTH")
+	fs.StringVar(&g.output, "o", outputTable, "output format: table, json or yaml")
+	fs.StringVar(&g.output, "output", outputTable, "same as -o")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: djmctl %s [flags] %s\n\nFlags:\n", name, argsUsage)
+		fs.PrintDefaults()
+	}
+	return fs, g
+}
+
+func loadSettings(g *globals) (*settings, error) {
+	var s settings
+	if err := env.Parse(&s); err != nil {
+		return nil, err
+	}
+	if g.server != "" {
+		s.Server = g.server
+	}
+	if g.auth != "" {
+		s.Auth = g.auth
+	}
+	if s.Auth == "" {
+		switch {
+		case s.APIKey != "":
+			s.Auth = authAPIKey
+		case s.ClientSecret != "":
+			s.Auth = authClientCredentials
+		default:
+			s.Auth = authDevice
+		}
+	}
+	return &s, nil
+}
+
+// newClient returns a job service client authenticated as the settings select.
+func newClient(g *globals) (*client.Client, error) {
+	if err := checkOutput(g.output); err != nil {
+		return nil, err
+	}
+
+	s, err := loadSettings(g)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth client.Authenticator
+	switch s.Auth {
+	case authAPIKey:
+		if s.APIKey == "" {
+			return nil, errors.New("api-key auth needs DJM_API_KEY")
+		}
+		auth = client.StaticToken(s.APIKey)
+	case authClientCredentials:
+		if s.ClientID == "" || s.ClientSecret == "" {
+			return nil, errors.New("client-credentials auth needs DJM_CLIENT_ID and DJM_CLIENT_SECRET")
+		}
+		switch {
+		case s.TokenURL != "":
+			auth = client.NewClientCredentials(s.TokenURL, s.ClientID, s.ClientSecret, s.Audience)
+		case s.Auth0Domain != "":
+			auth = client.NewAuth0ClientCredentials(s.Auth0Domain, s.ClientID, s.ClientSecret, s.Audience)
+		default:
+			return nil, errors.New("client-credentials auth needs DJM_TOKEN_URL or DJM_AUTH0_DOMAIN")
+		}
+	case authDevice:
+		device, err := newDeviceCode(s)
+		if err != nil {
+			return nil, err
+		}
+		auth = device
+	default:
+		return nil, fmt.Errorf("unknown auth method %q", s.Auth)
+	}
+
+	return client.New(s.Server, client.WithAuth(auth)), nil
+}
+
+// newDeviceCode returns a device code authenticator that restores the token saved by the last
+// login and saves every renewed token.
+func newDeviceCode(s *settings) (*client.DeviceCode, error) {
+	if s.ClientID == "" {
+		return nil, errors.New("device auth needs DJM_CLIENT_ID")
+	}
+
+	var device *client.DeviceCode
+	switch {
+	case s.TokenURL != "" && s.DeviceURL != "":
+		device = client.NewDeviceCode(s.DeviceURL, s.TokenURL, s.ClientID, s.Audience, s.Scopes...)
+	case s.Auth0Domain != "":
+		device = client.NewAuth0DeviceCode(s.Auth0Domain, s.ClientID, s.Audience, s.Scopes...)
+	default:
+		return nil, errors.New("device auth needs DJM_TOKEN_URL and DJM_DEVICE_URL, or DJM_AUTH0_DOMAIN")
+	}
+
+	token, err := loadToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil {
+		device.SetToken(*token)
+	}
+	device.OnToken = func(token client.Token) {
+		if err := saveToken(token); err != nil {
+			fmt.Fprintln(os.Stderr, "djmctl: unable to save token:", err)
+		}
+	}
+
+	return device, nil
+}
+
+// tokenPath is where the device code token is kept between runs.
+func tokenPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "djmctl", "token.json"), nil
+}
+
+func loadToken() (*client.Token, error) {
+	path, err := tokenPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read saved token: %w", err)
+	}
+
+	var token client.Token
+	if err = json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("unable to parse saved token %s: %w", path, err)
+	}
+
+	return &token, nil
+}
+
+func saveToken(token client.Token) error {
+	path, err := tokenPath()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
+
+// parseTime accepts RFC 3339 timestamps or durations from now such as '10m'.
+func parseTime(val string) (time.Time, error) {
+	if d, err := time.ParseDuration(val); err == nil {
+		return time.Now().Add(d), nil
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a duration", val)
+	}
+	return t, nil
+}
+
+// labelFlag collects repeated key=value flags.
+type labelFlag map[string]string
+
+func (l labelFlag) String() string {
+	pairs := make([]string, 0, len(l))
+	for k, v := range l {
+		pairs = append(pairs, k+"="+v)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (l labelFlag) Set(val string) error {
+	k, v, ok := strings.Cut(val, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("label %q is not key=value", val)
+	}
+	l[k] = v
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/client"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+func deadLettersList(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("dead-letters list", "")
+	jobID := fs.String("job", "", "only dead letters of this job")
+	limit := fs.Int("limit", 0, "show at most this many dead letters, 0 for all")
+	var w watchFlags
+	w.register(fs)
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	return show(ctx, g.output, w, func(ctx context.Context) (any, table, error) {
+		letters := []models.DeadLetter{}
+		for letter, err := range c.IterDeadLetters(ctx, client.DeadLetterQuery{JobID: *jobID}, 100) {
+			if err != nil {
+				return nil, table{}, err
+			}
+			letters = append(letters, letter)
+			if *limit > 0 && len(letters) == *limit {
+				break
+			}
+		}
+		return letters, deadLettersTable(letters), nil
+	})
+}
+
+func deadLettersGet(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("dead-letters get", "<dead-letter-id>")
+	ids, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	letter, err := c.GetDeadLetter(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	return printResult(ctx, g.output, letter, deadLetterTable(*letter))
+}
+
+func deadLettersReplay(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("dead-letters replay", "<dead-letter-id>")
+	ids, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	letter, err := c.ReplayDeadLetter(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	if g.output == outputTable {
+		fmt.Printf("replayed job %s from dead letter %s\n", letter.JobID, letter.DeadLetterID)
+		return nil
+	}
+	return printResult(ctx, g.output, letter, table{})
+}
+
+func deadLettersDelete(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("dead-letters delete", "<dead-letter-id>")
+	ids, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	if err = c.DeleteDeadLetter(ctx, ids[0]); err != nil {
+		return err
+	}
+
+	if g.output == outputTable {
+		fmt.Printf("deleted dead letter %s\n", ids[0])
+	}
+	return nil
+}
+
+func deadLettersPurge(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("dead-letters purge", "")
+	jobID := fs.String("job", "", "only purge the dead letters of this job")
+	all := fs.Bool("all", false, "purge every dead letter")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if (*jobID == "") == !*all {
+		fs.Usage()
+		return errors.New("dead-letters purge needs exactly one of -job or -all")
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	purged, err := c.PurgeDeadLetters(ctx, *jobID)
+	if err != nil {
+		return err
+	}
+
+	return printResult(ctx, g.output, models.DeadLetterPurge{Purged: purged}, table{rows: [][]string{{fmt.Sprintf("purged %d dead letter(s)", purged)}}})
+}
+
+func deadLettersTable(letters []models.DeadLetter) table {
+	t := table{header: []string{"ID", "JOB ID", "WORKER", "DELIVERIES", "DEAD-LETTERED", "REASON"}}
+	for _, letter := range letters {
+		t.rows = append(t.rows, []string{letter.DeadLetterID, letter.JobID, letter.WorkerID, strconv.Itoa(letter.Deliveries), formatTime(letter.DeadLetteredAt), letter.Reason})
+	}
+	return t
+}
+
+func deadLetterTable(letter models.DeadLetter) table {
+	return table{rows: [][]string{
+		{"ID", letter.DeadLetterID},
+		{"Job", letter.JobID},
+		{"Owner", letter.UserID},
+		{"Worker", letter.WorkerID},
+		{"Reason", letter.Reason},
+		{"Deliveries", strconv.Itoa(letter.Deliveries)},
+		{"Dead-lettered", formatTime(letter.DeadLetteredAt)},
+		{"Body", letter.Body},
+	}}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+func executionsList(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("executions list", "<job-id>")
+	var w watchFlags
+	w.register(fs)
+	ids, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	return show(ctx, g.output, w, func(ctx context.Context) (any, table, error) {
+		executions, err := c.ListJobExecutions(ctx, ids[0])
+		if err != nil {
+			return nil, table{}, err
+		}
+		return executions, executionsTable(executions), nil
+	})
+}
+
+func executionsLogs(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("executions logs", "<execution-id>")
+	follow := fs.Bool("f", false, "keep printing new output until the execution finishes")
+	fs.BoolVar(follow, "follow", false, "same as -f")
+	ids, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	return c.StreamExecutionLogs(ctx, ids[0], *follow, os.Stdout)
+}
+
+func executionsTable(executions []models.JobExecution) table {
+	t := table{header: []string{"ID", "ATTEMPT", "STATUS", "WORKER", "STARTED", "ENDED", "DURATION", "ERROR"}}
+	for _, exec := range executions {
+		var duration string
+		if !exec.StartTime.IsZero() && exec.EndTime.After(exec.StartTime) {
+			duration = exec.EndTime.Sub(exec.StartTime).Round(time.Millisecond).String()
+		}
+		var attempt string
+		// executions recorded before attempts were numbered have none
+		if exec.Attempt > 0 {
+			attempt = strconv.Itoa(exec.Attempt)
+		}
+		t.rows = append(t.rows, []string{exec.ExecutionID, attempt, exec.Status, exec.WorkerID, formatTime(exec.StartTime), formatTime(exec.EndTime), duration, exec.ErrorMessage})
+	}
+	return t
+}
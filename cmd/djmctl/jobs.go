@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// jobFlags are the job fields create and update accept.
+type jobFlags struct {
+	name        string
+	description string
+	frequency   string
+	timeZone    string
+	start       string
+	project     string
+	labels      labelFlag
+	maxRetries  int
+	payload     string
+	dryRun      bool
+}
+
+func (f *jobFlags) register(fs *flag.FlagSet) {
+	f.labels = labelFlag{}
+	fs.StringVar(&f.name, "name", "", "job name")
+	fs.StringVar(&f.description, "description", "", "job description")
+	fs.StringVar(&f.frequency, "frequency", "", "one-time, hourly, daily, weekly, monthly or a cron expression")
+	fs.StringVar(&f.timeZone, "time-zone", "", "IANA time zone the frequency is evaluated in")
+	fs.StringVar(&f.start, "start", "", "first run, as an RFC 3339 time or a duration from now such as 10m")
+	fs.StringVar(&f.project, "project", "", "project the job belongs to")
+	fs.Var(f.labels, "label", "key=value label, may be repeated")
+	fs.IntVar(&f.maxRetries, "max-retries", 0, "times a failed run is retried")
+	fs.StringVar(&f.payload, "payload", "", "markdown file with the job's code blocks, - for stdin")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "only validate the payload")
+}
+
+func jobsList(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("jobs list", "")
+	limit := fs.Int("limit", 0, "show at most this many jobs, 0 for all")
+	var w watchFlags
+	w.register(fs)
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	return show(ctx, g.output, w, func(ctx context.Context) (any, table, error) {
+		jobs := []models.Job{}
+		for job, err := range c.IterJobs(ctx, 100) {
+			if err != nil {
+				return nil, table{}, err
+			}
+			jobs = append(jobs, job)
+			if *limit > 0 && len(jobs) == *limit {
+				break
+			}
+		}
+		return jobs, jobsTable(jobs), nil
+	})
+}
+
+func jobsGet(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("jobs get", "<job-id>")
+	var w watchFlags
+	w.register(fs)
+	ids, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	return show(ctx, g.output, w, func(ctx context.Context) (any, table, error) {
+		job, err := c.GetJob(ctx, ids[0])
+		if err != nil {
+			return nil, table{}, err
+		}
+		return job, jobTable(*job), nil
+	})
+}
+
+func jobsCreate(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("jobs create", "")
+	var f jobFlags
+	f.register(fs)
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if f.name == "" || f.frequency == "" || f.payload == "" {
+		fs.Usage()
+		return errors.New("jobs create needs --name, --frequency and --payload")
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	payload, err := readInput(f.payload)
+	if err != nil {
+		return err
+	}
+
+	if f.dryRun {
+		res, err := c.ValidatePayload(ctx, payload)
+		if err != nil {
+			return err
+		}
+		return printValidation(ctx, g.output, *res)
+	}
+
+	start := time.Now()
+	if f.start != "" {
+		if start, err = parseTime(f.start); err != nil {
+			return err
+		}
+	}
+
+	job, err := c.CreateJob(ctx, models.Job{
+		JobName:        f.name,
+		JobDescription: f.description,
+		Frequency:      f.frequency,
+		TimeZone:       f.timeZone,
+		Labels:         f.labels,
+		Project:        f.project,
+		Payload:        payload,
+		MaxRetries:     f.maxRetries,
+		ExecutionTime:  start,
+	})
+	if err != nil {
+		return err
+	}
+
+	return printResult(ctx, g.output, job, jobTable(*job))
+}
+
+func jobsUpdate(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("jobs update", "<job-id>")
+	var f jobFlags
+	f.register(fs)
+	ids, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	// only the flags given are sent, so fields can be cleared by passing an empty value
+	var updates models.JobUpdateRequest
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "name":
+			updates.JobName = &f.name
+		case "description":
+			updates.JobDescription = &f.description
+		case "frequency":
+			updates.Frequency = &f.frequency
+		case "time-zone":
+			updates.TimeZone = &f.timeZone
+		case "project":
+			updates.Project = &f.project
+		case "label":
+			labels := map[string]string(f.labels)
+			updates.Labels = &labels
+		case "max-retries":
+			updates.MaxRetries = &f.maxRetries
+		}
+	})
+	if f.start != "" {
+		start, err := parseTime(f.start)
+		if err != nil {
+			return err
+		}
+		updates.ExecutionTime = &start
+	}
+	if f.payload != "" {
+		payload, err := readInput(f.payload)
+		if err != nil {
+			return err
+		}
+		updates.Payload = &payload
+	}
+	if updates == (models.JobUpdateRequest{}) {
+		fs.Usage()
+		return errors.New("jobs update needs at least one field to change")
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	if f.dryRun {
+		res, err := c.ValidateJobUpdate(ctx, ids[0], updates)
+		if err != nil {
+			return err
+		}
+		return printValidation(ctx, g.output, *res)
+	}
+
+	job, err := c.UpdateJob(ctx, ids[0], updates)
+	if err != nil {
+		return err
+	}
+
+	return printResult(ctx, g.output, job, jobTable(*job))
+}
+
+func jobsDelete(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("jobs delete", "<job-id>")
+	ids, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	if err = c.DeleteJob(ctx, ids[0]); err != nil {
+		return err
+	}
+
+	if g.output == outputTable {
+		fmt.Printf("deleted job %s\n", ids[0])
+	}
+	return nil
+}
+
+func jobsRun(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("jobs run", "<job-id>")
+	ids, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	schedule, err := c.RunJob(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	return printResult(ctx, g.output, schedule, table{rows: [][]string{
+		{"Job", schedule.JobID},
+		{"Next run", formatTime(schedule.NextRunTime)},
+	}})
+}
+
+func jobsCancel(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("jobs cancel", "<job-id>")
+	ids, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	status := models.JobStatusCancelled
+	job, err := c.UpdateJob(ctx, ids[0], models.JobUpdateRequest{Status: &status})
+	if err != nil {
+		return err
+	}
+
+	return printResult(ctx, g.output, job, jobTable(*job))
+}
+
+func jobsTable(jobs []models.Job) table {
+	t := table{header: []string{"ID", "NAME", "PROJECT", "FREQUENCY", "STATUS", "EXECUTION TIME", "UPDATED"}}
+	for _, job := range jobs {
+		t.rows = append(t.rows, []string{job.JobID, job.JobName, job.Project, job.Frequency, job.Status, formatTime(job.ExecutionTime), formatTime(job.UpdatedAt)})
+	}
+	return t
+}
+
+func jobTable(job models.Job) table {
+	return table{rows: [][]string{
+		{"ID", job.JobID},
+		{"Name", job.JobName},
+		{"Description", job.JobDescription},
+		{"Owner", job.UserID},
+		{"Project", job.Project},
+		{"Labels", formatLabels(job.Labels)},
+		{"Status", job.Status},
+		{"Frequency", job.Frequency},
+		{"Time zone", job.TimeZone},
+		{"Execution time", formatTime(job.ExecutionTime)},
+		{"Retries", fmt.Sprintf("%d of %d", job.RetryCount, job.MaxRetries)},
+		{"Created", formatTime(job.CreatedAt)},
+		{"Updated", formatTime(job.UpdatedAt)},
+	}}
+}
+
+// printValidation prints the result of a dry run and fails if the payload is invalid, so scripts
+// can check payloads by exit status.
+func printValidation(ctx context.Context, format string, res models.PayloadValidation) error {
+	t := table{header: []string{"BLOCK", "LANGUAGE", "LINE", "COLUMN", "MESSAGE"}}
+	for _, d := range res.Diagnostics {
+		t.rows = append(t.rows, []string{position(d.Block), d.Language, position(d.Line), position(d.Column), d.Message})
+	}
+	if len(t.rows) == 0 {
+		t = table{rows: [][]string{{fmt.Sprintf("payload is valid, %d code block(s) compiled", res.Blocks)}}}
+	}
+
+	if err := printResult(ctx, format, res, t); err != nil {
+		return err
+	}
+	if !res.Valid {
+		return fmt.Errorf("payload has %d problem(s)", len(res.Diagnostics))
+	}
+	return nil
+}
+
+// position formats a 1-based block, line or column number, which is 0 when unknown.
+func position(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// readInput reads a file, or stdin if path is '-'.
+func readInput(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/client"
+)
+
+func login(ctx context.Context, args []string) error {
+	flags, g := newFlagSet("login", "")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	s, err := loadSettings(g)
+	if err != nil {
+		return err
+	}
+
+	device, err := newDeviceCode(s)
+	if err != nil {
+		return err
+	}
+
+	err = device.Login(ctx, func(auth client.DeviceAuthorization) {
+		fmt.Fprintf(os.Stderr, "Open %s and enter the code %s\n", auth.VerificationURI, auth.UserCode)
+		if auth.VerificationURIComplete != "" {
+			fmt.Fprintf(os.Stderr, "or open %s\n", auth.VerificationURIComplete)
+		}
+		fmt.Fprintln(os.Stderr, "Waiting for approval...")
+	})
+	if err != nil {
+		return err
+	}
+
+	path, _ := tokenPath()
+	fmt.Fprintf(os.Stderr, "Logged in, token saved to %s\n", path)
+	return nil
+}
+
+func logout(_ context.Context, args []string) error {
+	flags, _ := newFlagSet("logout", "")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	path, err := tokenPath()
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Command djmctl manages DJM jobs from the command line.
+//
+// It talks to the job service through pkg/client, authenticating with an API key, a client
+// credentials grant or, for people, a device authorization grant started with 'djmctl login'.
+// Results are printed as tables, JSON or YAML and most read commands can watch for changes.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/client"
+)
+
+// command is a djmctl subcommand. Commands parse their own flags.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+// group is a set of commands acting on one kind of resource.
+type group struct {
+	name     string
+	aliases  []string
+	summary  string
+	commands []command
+}
+
+var groups = []group{
+	{
+		name:    "jobs",
+		aliases: []string{"job"},
+		summary: "Create, inspect and run jobs",
+		commands: []command{
+			{"list", "List your jobs", jobsList},
+			{"get", "Show a job", jobsGet},
+			{"create", "Create a job", jobsCreate},
+			{"update", "Update a job", jobsUpdate},
+			{"delete", "Delete a job", jobsDelete},
+			{"run", "Run a job now", jobsRun},
+			{"cancel", "Cancel a job", jobsCancel},
+		},
+	},
+	{
+		name:    "executions",
+		aliases: []string{"execution", "exec"},
+		summary: "Inspect job executions",
+		commands: []command{
+			{"list", "List the executions of a job", executionsList},
+			{"logs", "Print the output of an execution", executionsLogs},
+		},
+	},
+	{
+		name:    "schedules",
+		aliases: []string{"schedule"},
+		summary: "Inspect job schedules",
+		commands: []command{
+			{"inspect", "Show when a job runs next", schedulesInspect},
+		},
+	},
+	{
+		name:    "workers",
+		aliases: []string{"worker"},
+		summary: "Inspect registered workers",
+		commands: []command{
+			{"list", "List workers and their health", workersList},
+		},
+	},
+	{
+		name:    "dead-letters",
+		aliases: []string{"dead-letter", "dlq"},
+		summary: "Inspect and replay jobs the work queue gave up on (admin)",
+		commands: []command{
+			{"list", "List dead-lettered jobs", deadLettersList},
+			{"get", "Show a dead letter and why it was dead-lettered", deadLettersGet},
+			{"replay", "Send a dead-lettered job to the work queue again", deadLettersReplay},
+			{"delete", "Delete a dead letter", deadLettersDelete},
+			{"purge", "Delete many dead letters", deadLettersPurge},
+		},
+	},
+	{
+		name:    "manifests",
+		aliases: []string{"manifest"},
+		summary: "Manage jobs declaratively",
+		commands: []command{
+			{"apply", "Make your jobs match a set of manifests", manifestsApply},
+			{"export", "Print your jobs as manifests", manifestsExport},
+		},
+	},
+}
+
+var topLevel = []command{
+	{"login", "Sign in with a device code", login},
+	{"logout", "Forget the saved device code token", logout},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if errors.Is(err, client.ErrLoginRequired) {
+			err = fmt.Errorf("%w, run 'djmctl login' first", err)
+		}
+		fmt.Fprintln(os.Stderr, "djmctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(os.Stdout)
+		return nil
+	}
+
+	for _, cmd := range topLevel {
+		if cmd.name == args[0] {
+			return cmd.run(ctx, args[1:])
+		}
+	}
+
+	for _, g := range groups {
+		if g.name != args[0] && !slices.Contains(g.aliases, args[0]) {
+			continue
+		}
+		if len(args) < 2 || args[1] == "help" || args[1] == "-h" || args[1] == "--help" {
+			printGroupUsage(os.Stdout, g)
+			return nil
+		}
+		for _, cmd := range g.commands {
+			if cmd.name == args[1] {
+				return cmd.run(ctx, args[2:])
+			}
+		}
+		printGroupUsage(os.Stderr, g)
+		return fmt.Errorf("unknown %s command %q", g.name, args[1])
+	}
+
+	printUsage(os.Stderr)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: djmctl <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, g := range groups {
+		fmt.Fprintf(w, "  %-12s %s\n", g.name, g.summary)
+	}
+	for _, cmd := range topLevel {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'djmctl <command> help' for the commands of a group and 'djmctl <command> <subcommand> -h' for its flags.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Environment:")
+	for _, line := range envUsage {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
+
+func printGroupUsage(w io.Writer, g group) {
+	fmt.Fprintf(w, "Usage: djmctl %s <command> [flags] [args]\n", g.name)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range g.commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// parseArgs parses flags given before, between or after positional arguments, which the flag
+// package alone stops at, and checks the number of positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string, want int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		// everything after a '--' terminator is positional
+		if len(args) >= len(rest)+1 && args[len(args)-len(rest)-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	if want >= 0 && len(positional) != want {
+		fs.Usage()
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d: %s", fs.Name(), want, len(positional), strings.Join(positional, " "))
+	}
+
+	return positional, nil
+}
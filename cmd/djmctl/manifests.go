@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// fileFlag collects repeated file flags.
+type fileFlag []string
+
+func (f *fileFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileFlag) Set(val string) error {
+	*f = append(*f, val)
+	return nil
+}
+
+func manifestsApply(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("manifests apply", "")
+	var files fileFlag
+	fs.Var(&files, "f", "YAML or JSON file of manifests, - for stdin, may be repeated")
+	prune := fs.Bool("prune", false, "delete your jobs that no manifest names")
+	dryRun := fs.Bool("dry-run", false, "only show what applying would change")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fs.Usage()
+		return errors.New("manifests apply needs at least one -f file")
+	}
+
+	req := models.ManifestApplyRequest{Manifests: []models.JobManifest{}, Prune: *prune}
+	for _, path := range files {
+		manifests, err := readManifests(path)
+		if err != nil {
+			return err
+		}
+		req.Manifests = append(req.Manifests, manifests...)
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	var plan *models.ManifestPlan
+	if *dryRun {
+		plan, err = c.PlanManifests(ctx, req)
+	} else {
+		plan, err = c.ApplyManifests(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = printResult(ctx, g.output, plan, changesTable(plan.Changes)); err != nil {
+		return err
+	}
+	if g.output == outputTable {
+		fmt.Println()
+		fmt.Println(planSummary(*plan))
+	}
+	return nil
+}
+
+func manifestsExport(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("manifests export", "")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	set, err := c.ExportManifests(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"NAME", "PROJECT", "FREQUENCY", "START", "LABELS"}}
+	for _, m := range set.Manifests {
+		t.rows = append(t.rows, []string{m.Name, m.Project, m.Schedule.Frequency, formatTime(m.Schedule.Start), formatLabels(m.Labels)})
+	}
+
+	return printResult(ctx, g.output, set, t)
+}
+
+// readManifests reads the manifests of a file. Each YAML document, or the JSON document, is
+// either a manifest set as 'manifests export' writes it or a single manifest.
+func readManifests(path string) ([]models.JobManifest, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// the first decoder looks at each document to pick what to decode it into; the second,
+	// strict one keeps pace with it and rejects misspelt fields
+	probe := yaml.NewDecoder(strings.NewReader(data))
+	strict := yaml.NewDecoder(strings.NewReader(data))
+	strict.KnownFields(true)
+
+	var manifests []models.JobManifest
+	for i := 1; ; i++ {
+		var fields map[string]any
+		if err := probe.Decode(&fields); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: document %d: %w", path, i, err)
+		}
+
+		var err error
+		switch _, isSet := fields["manifests"]; {
+		case len(fields) == 0:
+			err = strict.Decode(new(any))
+		case isSet:
+			var set models.ManifestSet
+			err = strict.Decode(&set)
+			manifests = append(manifests, set.Manifests...)
+		default:
+			var m models.JobManifest
+			err = strict.Decode(&m)
+			manifests = append(manifests, m)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: document %d: %w", path, i, err)
+		}
+	}
+
+	return manifests, nil
+}
+
+func changesTable(changes []models.ManifestChange) table {
+	t := table{header: []string{"ACTION", "NAME", "JOB ID", "CHANGED"}}
+	for _, change := range changes {
+		fields := make([]string, len(change.Fields))
+		for i, f := range change.Fields {
+			fields[i] = f.Field
+		}
+		t.rows = append(t.rows, []string{change.Action, change.Name, change.JobID, strings.Join(fields, ", ")})
+	}
+	return t
+}
+
+// planSummary counts the changes of a plan by action.
+func planSummary(plan models.ManifestPlan) string {
+	counts := make(map[string]int)
+	for _, change := range plan.Changes {
+		counts[change.Action]++
+	}
+
+	summary := fmt.Sprintf("%d to create, %d to update, %d to delete, %d unchanged",
+		counts[models.ManifestActionCreate], counts[models.ManifestActionUpdate], counts[models.ManifestActionDelete], counts[models.ManifestActionUnchanged])
+	if !plan.Applied {
+		return "Plan: " + summary + ". Nothing was changed."
+	}
+	return "Applied: " + summary + "."
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func checkOutput(format string) error {
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, want table, json or yaml", format)
+}
+
+// table is how a result is shown in table output. A table without a header lists fields of a
+// single resource.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// render writes v as JSON or YAML, or t in table output.
+func render(w io.Writer, format string, v any, t table) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		// sigs.k8s.io/yaml goes through JSON, so the models' json tags name the fields
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	if t.header != nil {
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	}
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// watchFlags are the flags of commands that can keep watching a result.
+type watchFlags struct {
+	watch    bool
+	interval time.Duration
+}
+
+func (w *watchFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&w.watch, "w", false, "keep watching and print the result again when it changes")
+	fs.BoolVar(&w.watch, "watch", false, "same as -w")
+	fs.DurationVar(&w.interval, "interval", 2*time.Second, "how often to check for changes when watching")
+}
+
+// show prints what fetch returns. When watching it fetches again every interval until ctx is
+// cancelled and prints the result each time it changes, redrawing tables in place.
+func show(ctx context.Context, format string, w watchFlags, fetch func(ctx context.Context) (any, table, error)) error {
+	var last []byte
+	for {
+		v, t, err := fetch(ctx)
+		if err != nil {
+			if w.watch && ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err = render(&buf, format, v, t); err != nil {
+			return err
+		}
+
+		if !bytes.Equal(buf.Bytes(), last) {
+			if w.watch && format == outputTable {
+				// move to the top left and clear the terminal
+				fmt.Fprint(os.Stdout, "\033[H\033[2J")
+				fmt.Fprintf(os.Stdout, "Every %v, last changed %s\n\n", w.interval, time.Now().Format(time.TimeOnly))
+			} else if w.watch && format == outputYAML && last != nil {
+				fmt.Fprintln(os.Stdout, "---")
+			}
+			if _, err = os.Stdout.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			last = buf.Bytes()
+		}
+
+		if !w.watch {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.interval):
+		}
+	}
+}
+
+// formatTime shows times in the local time zone, leaving unset ones blank.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func formatLabels(labels map[string]string) string {
+	return labelFlag(labels).String()
+}
+
+// printResult writes a single result without watching.
+func printResult(ctx context.Context, format string, v any, t table) error {
+	return show(ctx, format, watchFlags{}, func(context.Context) (any, table, error) {
+		return v, t, nil
+	})
+}
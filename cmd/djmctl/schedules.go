@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// scheduleInspection is a job's schedule along with the job it runs.
+type scheduleInspection struct {
+	Job      models.Job         `json:"job"`
+	Schedule models.JobSchedule `json:"schedule"`
+}
+
+func schedulesInspect(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("schedules inspect", "<job-id>")
+	var w watchFlags
+	w.register(fs)
+	ids, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	return show(ctx, g.output, w, func(ctx context.Context) (any, table, error) {
+		job, err := c.GetJob(ctx, ids[0])
+		if err != nil {
+			return nil, table{}, err
+		}
+		schedule, err := c.GetSchedule(ctx, ids[0])
+		if err != nil {
+			return nil, table{}, err
+		}
+
+		return scheduleInspection{Job: *job, Schedule: *schedule}, table{rows: [][]string{
+			{"Job", job.JobID},
+			{"Name", job.JobName},
+			{"Status", job.Status},
+			{"Frequency", job.Frequency},
+			{"Time zone", job.TimeZone},
+			{"Next run", relativeTime(schedule.NextRunTime)},
+			{"Last run", relativeTime(schedule.LastRunTime)},
+		}}, nil
+	})
+}
+
+// relativeTime shows a time along with how far it is from now, to the second.
+func relativeTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	d := time.Until(t).Round(time.Second)
+	if d >= 0 {
+		return formatTime(t) + " (in " + d.String() + ")"
+	}
+	return formatTime(t) + " (" + (-d).String() + " ago)"
+}
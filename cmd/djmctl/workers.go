@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+func workersList(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("workers list", "")
+	health := fs.String("health", "", "only workers in this health: healthy, stale or dead")
+	var w watchFlags
+	w.register(fs)
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+
+	return show(ctx, g.output, w, func(ctx context.Context) (any, table, error) {
+		workers, err := c.ListWorkers(ctx, *health)
+		if err != nil {
+			return nil, table{}, err
+		}
+		return workers, workersTable(workers), nil
+	})
+}
+
+func workersTable(workers []models.WorkerNode) table {
+	t := table{header: []string{"ID", "HOST", "HEALTH", "LOAD", "LANGUAGES", "VERSION", "LAST HEARTBEAT"}}
+	for _, worker := range workers {
+		t.rows = append(t.rows, []string{
+			worker.WorkerID,
+			worker.Host,
+			worker.Health,
+			fmt.Sprintf("%d/%d", worker.CurrentLoad, worker.Capacity),
+			strings.Join(worker.Languages, ","),
+			worker.Version,
+			relativeTime(worker.LastHeartbeat),
+		})
+	}
+	return t
+}
@@ -13,6 +13,9 @@ export interface Job {
   job_name: string;
   job_description: string;
   frequency: string;
+  time_zone?: string;
+  labels?: Record<string, string>;
+  project?: string;
   status: string;
   payload: string;
   retry_count: number;
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// ListAPIKeys retrieves the API keys created by the caller.
+func (c *Client) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	res, err := call[[]models.APIKey](ctx, c, http.MethodGet, "/api-keys/", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+// CreateAPIKey creates an API key. The plaintext key is only returned here; pass it to
+// StaticToken to authenticate with it.
+func (c *Client) CreateAPIKey(ctx context.Context, key models.APIKeyCreateRequest) (*models.APIKeyCreateResponse, error) {
+	res, err := call[models.APIKeyCreateResponse](ctx, c, http.MethodPost, "/api-keys", nil, key)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// RevokeAPIKey revokes an API key so it can no longer authenticate.
+func (c *Client) RevokeAPIKey(ctx context.Context, keyID string) (*models.APIKey, error) {
+	keyID, err := escape(keyID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.APIKey](ctx, c, http.MethodDelete, "/api-keys/"+keyID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// AuditQuery filters ListAuditEntries. ResourceID requires ResourceType.
+type AuditQuery struct {
+	ResourceType string
+	ResourceID   string
+	ActorID      string
+	From         *time.Time
+	To           *time.Time
+	ListOptions
+}
+
+func (q AuditQuery) values() url.Values {
+	params := q.ListOptions.values()
+	if q.ResourceType != "" {
+		params.Set("resource_type", q.ResourceType)
+	}
+	if q.ResourceID != "" {
+		params.Set("resource_id", q.ResourceID)
+	}
+	if q.ActorID != "" {
+		params.Set("actor_id", q.ActorID)
+	}
+	if q.From != nil {
+		params.Set("from", q.From.Format(time.RFC3339))
+	}
+	if q.To != nil {
+		params.Set("to", q.To.Format(time.RFC3339))
+	}
+	return params
+}
+
+// ListAuditEntries retrieves a page of the audit log entries matching q. Requires the admin scope.
+func (c *Client) ListAuditEntries(ctx context.Context, q AuditQuery) (*Page[models.AuditEntry], error) {
+	res, err := call[[]models.AuditEntry](ctx, c, http.MethodGet, "/audit", q.values(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Page[models.AuditEntry]{Items: res.Data, NextPageToken: res.NextPageToken}, nil
+}
+
+// IterAuditEntries walks every audit log entry matching q, fetching pageSize entries per request.
+func (c *Client) IterAuditEntries(ctx context.Context, q AuditQuery, pageSize int) iter.Seq2[models.AuditEntry, error] {
+	return paginate(ctx, pageSize, func(ctx context.Context, opts ListOptions) (*Page[models.AuditEntry], error) {
+		q.ListOptions = opts
+		return c.ListAuditEntries(ctx, q)
+	})
+}
@@ -0,0 +1,443 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/utils"
+)
+
+// Authenticator adds credentials to an outgoing request.
+type Authenticator interface {
+	Authorize(ctx context.Context, req *http.Request) error
+}
+
+// Refresher is implemented by authenticators whose credentials can be renewed. The client
+// invalidates them and retries once when the API responds with 401.
+type Refresher interface {
+	Invalidate()
+}
+
+// StaticToken authenticates with a fixed bearer token, e.g. an API key.
+type StaticToken string
+
+func (t StaticToken) Authorize(_ context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+// tokenExpiryLeeway is subtracted from a token's lifetime so it is renewed before it expires.
+const tokenExpiryLeeway = 30 * time.Second
+
+// ClientCredentials authenticates with an OAuth2 client credentials grant and caches the
+// access token until shortly before it expires.
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Audience     string
+	HTTPClient   *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewClientCredentials(tokenURL string, clientID string, clientSecret string, audience string) *ClientCredentials {
+	return &ClientCredentials{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Audience:     audience,
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewAuth0ClientCredentials uses the token endpoint of the given Auth0 tenant domain.
+func NewAuth0ClientCredentials(domain string, clientID string, clientSecret string, audience string) *ClientCredentials {
+	return NewClientCredentials(fmt.Sprintf("https://%s/oauth/token", domain), clientID, clientSecret, audience)
+}
+
+func (a *ClientCredentials) Authorize(ctx context.Context, req *http.Request) error {
+	token, err := a.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (a *ClientCredentials) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+}
+
+// Token returns the cached access token, requesting a new one if needed.
+func (a *ClientCredentials) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Now().Before(a.expiresAt) {
+		return a.token, nil
+	}
+
+	formData := url.Values{}
+	formData.Set("grant_type", "client_credentials")
+	formData.Set("client_id", a.ClientID)
+	formData.Set("client_secret", a.ClientSecret)
+	if a.Audience != "" {
+		formData.Set("audience", a.Audience)
+	}
+
+	var tokenRes tokenResponse
+	if err := postForm(ctx, a.HTTPClient, a.TokenURL, formData, &tokenRes); err != nil {
+		return "", fmt.Errorf("unable to request access token: %w", err)
+	}
+	if tokenRes.AccessToken == "" {
+		return "", fmt.Errorf("no access token returned from %s", a.TokenURL)
+	}
+
+	a.token = tokenRes.AccessToken
+	a.expiresAt = time.Now().Add(time.Duration(tokenRes.ExpiresIn)*time.Second - tokenExpiryLeeway)
+
+	return a.token, nil
+}
+
+// Token is an access token along with the refresh token that renews it, if the provider issued
+// one.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// ErrLoginRequired is returned by DeviceCode when it holds no token it can use or refresh, so the
+// user has to approve a new device authorization with Login.
+var ErrLoginRequired = errors.New("login required")
+
+// DeviceAuthorization is a pending device authorization. The user approves it by opening
+// VerificationURI and entering UserCode, or by opening VerificationURIComplete.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+// deviceCodeGrantType is the grant type of the device authorization grant (RFC 8628).
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// DeviceCode authenticates a user of a command-line tool with an OAuth2 device authorization
+// grant. Login has the user approve the tool in a browser; afterwards the access token is renewed
+// with its refresh token for as long as the provider allows. OnToken, if set, is called with
+// every token obtained so it can be kept between runs and restored with SetToken.
+type DeviceCode struct {
+	DeviceURL  string
+	TokenURL   string
+	ClientID   string
+	Audience   string
+	Scopes     []string
+	HTTPClient *http.Client
+	OnToken    func(Token)
+
+	mu    sync.Mutex
+	token Token
+}
+
+func NewDeviceCode(deviceURL string, tokenURL string, clientID string, audience string, scopes ...string) *DeviceCode {
+	return &DeviceCode{
+		DeviceURL:  deviceURL,
+		TokenURL:   tokenURL,
+		ClientID:   clientID,
+		Audience:   audience,
+		Scopes:     scopes,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewAuth0DeviceCode uses the device authorization and token endpoints of the given Auth0 tenant
+// domain.
+func NewAuth0DeviceCode(domain string, clientID string, audience string, scopes ...string) *DeviceCode {
+	return NewDeviceCode(fmt.Sprintf("https://%s/oauth/device/code", domain), fmt.Sprintf("https://%s/oauth/token", domain), clientID, audience, scopes...)
+}
+
+func (a *DeviceCode) Authorize(ctx context.Context, req *http.Request) error {
+	token, err := a.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Invalidate drops the access token but keeps the refresh token, so the next request renews it.
+func (a *DeviceCode) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token.AccessToken = ""
+}
+
+// SetToken restores a token obtained earlier, e.g. by a previous run of the tool.
+func (a *DeviceCode) SetToken(token Token) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = token
+}
+
+// Token returns the access token, renewing it with the refresh token if it has expired.
+func (a *DeviceCode) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token.AccessToken != "" && time.Now().Before(a.token.Expiry) {
+		return a.token.AccessToken, nil
+	}
+	if a.token.RefreshToken == "" {
+		return "", ErrLoginRequired
+	}
+
+	formData := url.Values{}
+	formData.Set("grant_type", "refresh_token")
+	formData.Set("client_id", a.ClientID)
+	formData.Set("refresh_token", a.token.RefreshToken)
+
+	var tokenRes tokenResponse
+	if err := postForm(ctx, a.HTTPClient, a.TokenURL, formData, &tokenRes); err != nil {
+		var oauthErr *oauthError
+		if errors.As(err, &oauthErr) && oauthErr.Code == "invalid_grant" {
+			a.token = Token{}
+			return "", ErrLoginRequired
+		}
+		return "", fmt.Errorf("unable to refresh access token: %w", err)
+	}
+
+	a.setToken(tokenRes)
+
+	return a.token.AccessToken, nil
+}
+
+// Login starts a device authorization, passes it to prompt to show the user, and waits until the
+// user approves or denies it, or it expires.
+func (a *DeviceCode) Login(ctx context.Context, prompt func(DeviceAuthorization)) error {
+	formData := url.Values{}
+	formData.Set("client_id", a.ClientID)
+	if len(a.Scopes) > 0 {
+		formData.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	if a.Audience != "" {
+		formData.Set("audience", a.Audience)
+	}
+
+	var auth DeviceAuthorization
+	if err := postForm(ctx, a.HTTPClient, a.DeviceURL, formData, &auth); err != nil {
+		return fmt.Errorf("unable to start device authorization: %w", err)
+	}
+	prompt(auth)
+
+	// RFC 8628 has clients poll every 5 seconds unless told otherwise
+	interval := time.Duration(max(auth.Interval, 5)) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+	defer cancel()
+
+	pollData := url.Values{}
+	pollData.Set("grant_type", deviceCodeGrantType)
+	pollData.Set("device_code", auth.DeviceCode)
+	pollData.Set("client_id", a.ClientID)
+
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return errors.New("device authorization expired before it was approved")
+			}
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		var tokenRes tokenResponse
+		err := postForm(ctx, a.HTTPClient, a.TokenURL, pollData, &tokenRes)
+		if err == nil {
+			a.mu.Lock()
+			a.setToken(tokenRes)
+			a.mu.Unlock()
+			return nil
+		}
+
+		var oauthErr *oauthError
+		if !errors.As(err, &oauthErr) {
+			return fmt.Errorf("unable to poll device authorization: %w", err)
+		}
+		switch oauthErr.Code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return fmt.Errorf("device authorization failed: %w", err)
+		}
+	}
+}
+
+// setToken stores a token response, keeping the current refresh token if the provider did not
+// rotate it. The caller must hold a.mu.
+func (a *DeviceCode) setToken(res tokenResponse) {
+	a.token = Token{
+		AccessToken:  res.AccessToken,
+		RefreshToken: utils.If(res.RefreshToken != "", res.RefreshToken, a.token.RefreshToken),
+		Expiry:       time.Now().Add(time.Duration(res.ExpiresIn)*time.Second - tokenExpiryLeeway),
+	}
+	if a.OnToken != nil {
+		a.OnToken(a.token)
+	}
+}
+
+// LocalToken authenticates with HS256 tokens it signs itself, for a job service running in local
+// auth mode. Tokens are reissued shortly before they expire.
+type LocalToken struct {
+	Secret   []byte
+	Issuer   string
+	Audience string
+	Subject  string
+	Scopes   []string
+	TTL      time.Duration
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewLocalToken(secret string, issuer string, audience string, subject string, scopes ...string) *LocalToken {
+	return &LocalToken{
+		Secret:   []byte(secret),
+		Issuer:   issuer,
+		Audience: audience,
+		Subject:  subject,
+		Scopes:   scopes,
+		TTL:      time.Hour,
+	}
+}
+
+func (a *LocalToken) Authorize(ctx context.Context, req *http.Request) error {
+	token, err := a.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (a *LocalToken) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+}
+
+// Token returns the cached token, signing a new one if needed.
+func (a *LocalToken) Token(_ context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Now().Before(a.expiresAt) {
+		return a.token, nil
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   a.Issuer,
+		"sub":   a.Subject,
+		"scope": strings.Join(a.Scopes, " "),
+		"iat":   now.Unix(),
+		"exp":   now.Add(a.TTL).Unix(),
+	}
+	if a.Audience != "" {
+		claims["aud"] = a.Audience
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.Secret)
+	if err != nil {
+		return "", fmt.Errorf("unable to sign local token: %w", err)
+	}
+
+	a.token = token
+	a.expiresAt = now.Add(a.TTL - tokenExpiryLeeway)
+
+	return a.token, nil
+}
+
+// NewServiceAuth returns the authenticator an internal service uses to call the job service:
+// a self-signed admin token in local auth mode, or otherwise a client credentials grant against
+// the OIDC token endpoint, falling back to the Auth0 tenant's.
+func NewServiceAuth(conf *models.Config, subject string, clientID string, clientSecret string) Authenticator {
+	if conf.Auth.Mode == models.AuthModeLocal {
+		return NewLocalToken(conf.JWTSecretKey, conf.Auth.LocalIssuer(), conf.Auth.Audience, subject, "admin")
+	}
+
+	audience := utils.If(conf.Auth.Audience != "", conf.Auth.Audience, conf.Auth0.Audience)
+	if conf.Auth.TokenURL != "" {
+		return NewClientCredentials(conf.Auth.TokenURL, clientID, clientSecret, audience)
+	}
+	return NewAuth0ClientCredentials(conf.Auth0.Domain, clientID, clientSecret, audience)
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// oauthError is an error response of an OAuth2 endpoint. Code is empty if the body was not a
+// standard OAuth2 error.
+type oauthError struct {
+	Status      string `json:"-"`
+	Body        string `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *oauthError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Body)
+}
+
+// postForm posts form-encoded data to an OAuth2 endpoint and decodes its JSON response into out.
+func postForm(ctx context.Context, httpClient *http.Client, endpoint string, formData url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(formData.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		oauthErr := &oauthError{Status: res.Status, Body: string(body)}
+		_ = json.Unmarshal(body, oauthErr)
+		return oauthErr
+	}
+
+	if err = json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("unable to parse response of %s: %w", endpoint, err)
+	}
+
+	return nil
+}
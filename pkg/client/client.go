@@ -0,0 +1,291 @@
+// Package client is a Go SDK for the DJM job service API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const userAgent = "djm-go-client/0.1.0"
+
+// Client calls the job service API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	auth       Authenticator
+	retry      RetryPolicy
+}
+
+// RetryPolicy controls how failed requests are retried. Network errors, 429s and 5xx responses
+// are retried for idempotent methods; non-idempotent methods are only retried on 429.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// Response is the envelope every job service endpoint responds with.
+type Response[T any] struct {
+	Message       string `json:"message"`
+	Data          T      `json:"data"`
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+// Page is a single page of a list endpoint.
+type Page[T any] struct {
+	Items         []T
+	NextPageToken string
+}
+
+// ListOptions selects a page of a list endpoint. A zero Limit returns every item.
+type ListOptions struct {
+	Limit     int
+	PageToken string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAuth sets how requests are authenticated.
+func WithAuth(auth Authenticator) Option {
+	return func(c *Client) {
+		c.auth = auth
+	}
+}
+
+// WithRetry overrides DefaultRetryPolicy.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// New creates a client for the job service at baseURL, e.g. http://localhost:8080/api/v1.
+// Requests carry the trace context of their ctx unless WithHTTPClient replaces the transport.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (o ListOptions) values() url.Values {
+	params := url.Values{}
+	if o.Limit > 0 {
+		params.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.PageToken != "" {
+		params.Set("page_token", o.PageToken)
+	}
+	return params
+}
+
+// call sends a request and decodes the response envelope's data into T.
+func call[T any](ctx context.Context, c *Client, method string, path string, query url.Values, body any) (res Response[T], err error) {
+	err = c.do(ctx, method, path, query, body, &res)
+	return
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body any, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("unable to marshal request body: %w", err)
+		}
+	}
+
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	res, err := c.send(ctx, method, endpoint, payload)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return newAPIError(method, endpoint, res, resBody)
+	}
+
+	if out == nil || len(resBody) == 0 {
+		return nil
+	}
+
+	if err = json.Unmarshal(resBody, out); err != nil {
+		return fmt.Errorf("unable to unmarshal response from %s %s: %w", method, endpoint, err)
+	}
+
+	return nil
+}
+
+// send performs the request, retrying according to the client's retry policy. The returned
+// response body must be closed by the caller.
+func (c *Client) send(ctx context.Context, method string, endpoint string, payload []byte) (*http.Response, error) {
+	refreshed := false
+
+	for attempt := 1; ; attempt++ {
+		req, err := c.newRequest(ctx, method, endpoint, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= c.retry.MaxAttempts || !isIdempotent(method) {
+				return nil, err
+			}
+			if err = c.wait(ctx, attempt, 0); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if res.StatusCode == http.StatusUnauthorized && !refreshed {
+			if r, ok := c.auth.(Refresher); ok {
+				drain(res)
+				r.Invalidate()
+				refreshed = true
+				attempt--
+				continue
+			}
+		}
+
+		if attempt >= c.retry.MaxAttempts || !shouldRetry(method, res.StatusCode) {
+			return res, nil
+		}
+
+		retryAfter := parseRetryAfter(res.Header.Get("Retry-After"))
+		drain(res)
+		if err = c.wait(ctx, attempt, retryAfter); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, method string, endpoint string, payload []byte) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.auth != nil {
+		if err = c.auth.Authorize(ctx, req); err != nil {
+			return nil, fmt.Errorf("unable to authorize request: %w", err)
+		}
+	}
+
+	return req, nil
+}
+
+// wait sleeps before the next attempt, using retryAfter when the server provided one and
+// exponential backoff with full jitter otherwise.
+func (c *Client) wait(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	delay := retryAfter
+	if delay <= 0 {
+		backoff := c.retry.BaseDelay << (attempt - 1)
+		if backoff <= 0 || backoff > c.retry.MaxDelay {
+			backoff = c.retry.MaxDelay
+		}
+		delay = time.Duration(rand.Int64N(int64(backoff) + 1))
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func shouldRetry(method string, status int) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	return status >= 500 && status != http.StatusNotImplemented && isIdempotent(method)
+}
+
+func parseRetryAfter(val string) time.Duration {
+	if val == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(val); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func drain(res *http.Response) {
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+}
+
+var errMissingID = errors.New("id must not be empty")
+
+func escape(id string) (string, error) {
+	if id == "" {
+		return "", errMissingID
+	}
+	return url.PathEscape(id), nil
+}
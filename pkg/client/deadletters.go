@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// DeadLetterQuery filters ListDeadLetters.
+type DeadLetterQuery struct {
+	JobID string
+	ListOptions
+}
+
+func (q DeadLetterQuery) values() url.Values {
+	params := q.ListOptions.values()
+	if q.JobID != "" {
+		params.Set("job_id", q.JobID)
+	}
+	return params
+}
+
+// ListDeadLetters retrieves a page of the dead letters matching q. Requires the admin scope.
+func (c *Client) ListDeadLetters(ctx context.Context, q DeadLetterQuery) (*Page[models.DeadLetter], error) {
+	res, err := call[[]models.DeadLetter](ctx, c, http.MethodGet, "/dead-letters", q.values(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Page[models.DeadLetter]{Items: res.Data, NextPageToken: res.NextPageToken}, nil
+}
+
+// IterDeadLetters walks every dead letter matching q, fetching pageSize dead letters per request.
+func (c *Client) IterDeadLetters(ctx context.Context, q DeadLetterQuery, pageSize int) iter.Seq2[models.DeadLetter, error] {
+	return paginate(ctx, pageSize, func(ctx context.Context, opts ListOptions) (*Page[models.DeadLetter], error) {
+		q.ListOptions = opts
+		return c.ListDeadLetters(ctx, q)
+	})
+}
+
+// GetDeadLetter retrieves a single dead letter. Requires the admin scope.
+func (c *Client) GetDeadLetter(ctx context.Context, deadLetterID string) (*models.DeadLetter, error) {
+	deadLetterID, err := escape(deadLetterID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.DeadLetter](ctx, c, http.MethodGet, "/dead-letters/"+deadLetterID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// ReplayDeadLetter publishes a dead-lettered job message to the work queue again, returning the
+// dead letter it removed. Requires the admin scope.
+func (c *Client) ReplayDeadLetter(ctx context.Context, deadLetterID string) (*models.DeadLetter, error) {
+	deadLetterID, err := escape(deadLetterID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.DeadLetter](ctx, c, http.MethodPost, "/dead-letters/"+deadLetterID+"/replay", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// DeleteDeadLetter removes a dead letter without replaying it. Requires the admin scope.
+func (c *Client) DeleteDeadLetter(ctx context.Context, deadLetterID string) error {
+	deadLetterID, err := escape(deadLetterID)
+	if err != nil {
+		return err
+	}
+	_, err = call[string](ctx, c, http.MethodDelete, "/dead-letters/"+deadLetterID, nil, nil)
+	return err
+}
+
+// PurgeDeadLetters removes every dead letter, or those of jobID if it is set, and returns how many
+// were removed. Requires the admin scope.
+func (c *Client) PurgeDeadLetters(ctx context.Context, jobID string) (int, error) {
+	query := url.Values{}
+	if jobID != "" {
+		query.Set("job_id", jobID)
+	}
+	res, err := call[models.DeadLetterPurge](ctx, c, http.MethodDelete, "/dead-letters", query, nil)
+	if err != nil {
+		return 0, err
+	}
+	return res.Data.Purged, nil
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// StreamEvents subscribes to job and execution state transitions, resuming after lastEventID
+// when it is set. fn is called for each event until ctx is cancelled, the server ends the
+// stream or fn returns an error, which StreamEvents then returns.
+func (c *Client) StreamEvents(ctx context.Context, lastEventID string, fn func(models.JobEvent) error) error {
+	header := http.Header{"Accept": {"text/event-stream"}}
+	if lastEventID != "" {
+		header.Set("Last-Event-ID", lastEventID)
+	}
+
+	res, err := c.openStream(ctx, "/events", nil, header)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return readEvents(res.Body, func(data string) error {
+		var event models.JobEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("unable to unmarshal event: %w", err)
+		}
+		return fn(event)
+	})
+}
+
+// openStream starts a long-lived GET request. It is not retried and ignores the HTTP client's
+// timeout, so the caller's ctx alone bounds how long the stream stays open.
+func (c *Client) openStream(ctx context.Context, path string, query url.Values, header http.Header) (*http.Response, error) {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	streamClient := *c.httpClient
+	streamClient.Timeout = 0
+
+	for refreshed := false; ; refreshed = true {
+		req, err := c.newRequest(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		res, err := streamClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode == http.StatusUnauthorized && !refreshed {
+			if r, ok := c.auth.(Refresher); ok {
+				drain(res)
+				r.Invalidate()
+				continue
+			}
+		}
+
+		if res.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			return nil, newAPIError(http.MethodGet, endpoint, res, body)
+		}
+
+		return res, nil
+	}
+}
+
+// readEvents parses a server-sent event stream, calling fn with the data of each event.
+func readEvents(r io.Reader, fn func(data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if err := fn(strings.Join(data, "\n")); err != nil {
+					return err
+				}
+				data = data[:0]
+			}
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// CreateExecution registers a new execution of a job.
+func (c *Client) CreateExecution(ctx context.Context, execution models.JobExecution) (*models.JobExecution, error) {
+	res, err := call[models.JobExecution](ctx, c, http.MethodPost, "/executions/", nil, execution)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// UpdateExecution applies a partial update to an execution.
+func (c *Client) UpdateExecution(ctx context.Context, id string, updates models.JobExecutionUpdateRequest) (*models.JobExecution, error) {
+	id, err := escape(id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.JobExecution](ctx, c, http.MethodPatch, "/executions/"+id, nil, updates)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// AppendExecutionOutput sends a chunk of a running execution's output.
+func (c *Client) AppendExecutionOutput(ctx context.Context, id string, chunk models.ExecutionOutputChunk) error {
+	id, err := escape(id)
+	if err != nil {
+		return err
+	}
+	_, err = call[models.ExecutionOutputChunk](ctx, c, http.MethodPost, "/executions/"+id+"/output", nil, chunk)
+	return err
+}
+
+// ListJobExecutions returns the executions of a job, most recently started first.
+func (c *Client) ListJobExecutions(ctx context.Context, jobID string) ([]models.JobExecution, error) {
+	jobID, err := escape(jobID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[[]models.JobExecution](ctx, c, http.MethodGet, "/jobs/"+jobID+"/executions", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+// StreamExecutionLogs copies an execution's output to w. With follow set it keeps copying new
+// output until the execution finishes or ctx is cancelled.
+func (c *Client) StreamExecutionLogs(ctx context.Context, id string, follow bool, w io.Writer) error {
+	id, err := escape(id)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if follow {
+		query.Set("follow", "true")
+	}
+
+	res, err := c.openStream(ctx, "/executions/"+id+"/logs", query, http.Header{"Accept": {"text/plain"}})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	_, err = io.Copy(w, res.Body)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// ListJobGrants retrieves the roles granted on a job.
+func (c *Client) ListJobGrants(ctx context.Context, jobID string) ([]models.JobGrant, error) {
+	return c.listGrants(ctx, "/jobs/", jobID)
+}
+
+// GrantJobRole gives a user or group a role on a job, replacing any role they already had.
+func (c *Client) GrantJobRole(ctx context.Context, jobID string, grant models.JobGrant) (*models.JobGrant, error) {
+	return c.saveGrant(ctx, "/jobs/", jobID, grant)
+}
+
+// RevokeJobRole removes the role a user or group was granted on a job.
+func (c *Client) RevokeJobRole(ctx context.Context, jobID string, subjectType string, subjectID string) error {
+	return c.deleteGrant(ctx, "/jobs/", jobID, subjectType, subjectID)
+}
+
+// ListProjectGrants retrieves the roles granted on a project.
+func (c *Client) ListProjectGrants(ctx context.Context, project string) ([]models.JobGrant, error) {
+	return c.listGrants(ctx, "/projects/", project)
+}
+
+// GrantProjectRole gives a user or group a role on every job in a project, replacing any role
+// they already had.
+func (c *Client) GrantProjectRole(ctx context.Context, project string, grant models.JobGrant) (*models.JobGrant, error) {
+	return c.saveGrant(ctx, "/projects/", project, grant)
+}
+
+// RevokeProjectRole removes the role a user or group was granted on a project.
+func (c *Client) RevokeProjectRole(ctx context.Context, project string, subjectType string, subjectID string) error {
+	return c.deleteGrant(ctx, "/projects/", project, subjectType, subjectID)
+}
+
+func (c *Client) listGrants(ctx context.Context, prefix string, id string) ([]models.JobGrant, error) {
+	id, err := escape(id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[[]models.JobGrant](ctx, c, http.MethodGet, prefix+id+"/grants", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+func (c *Client) saveGrant(ctx context.Context, prefix string, id string, grant models.JobGrant) (*models.JobGrant, error) {
+	id, err := escape(id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.JobGrant](ctx, c, http.MethodPut, prefix+id+"/grants", nil, grant)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+func (c *Client) deleteGrant(ctx context.Context, prefix string, id string, subjectType string, subjectID string) error {
+	id, err := escape(id)
+	if err != nil {
+		return err
+	}
+	subjectType, err = escape(subjectType)
+	if err != nil {
+		return err
+	}
+	subjectID, err = escape(subjectID)
+	if err != nil {
+		return err
+	}
+	_, err = call[string](ctx, c, http.MethodDelete, prefix+id+"/grants/"+subjectType+"/"+subjectID, nil, nil)
+	return err
+}
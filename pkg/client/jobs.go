@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// ListJobs retrieves a page of the jobs the caller owns or has been granted a role on, or of
+// every job for admins. Pages may hold fewer jobs than the limit.
+func (c *Client) ListJobs(ctx context.Context, opts ListOptions) (*Page[models.Job], error) {
+	res, err := call[[]models.Job](ctx, c, http.MethodGet, "/jobs/", opts.values(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Page[models.Job]{Items: res.Data, NextPageToken: res.NextPageToken}, nil
+}
+
+// IterJobs walks every job, fetching pageSize jobs per request.
+func (c *Client) IterJobs(ctx context.Context, pageSize int) iter.Seq2[models.Job, error] {
+	return paginate(ctx, pageSize, c.ListJobs)
+}
+
+// GetJob retrieves a single job.
+func (c *Client) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	id, err := escape(id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.Job](ctx, c, http.MethodGet, "/jobs/"+id, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// CreateJob submits a new job.
+func (c *Client) CreateJob(ctx context.Context, job models.Job) (*models.Job, error) {
+	res, err := call[models.Job](ctx, c, http.MethodPost, "/jobs", nil, job)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// ValidatePayload parses a job payload and compile-checks its code blocks without saving
+// anything. Problems with the payload are reported in the result's diagnostics, not as an error.
+func (c *Client) ValidatePayload(ctx context.Context, payload string) (*models.PayloadValidation, error) {
+	res, err := call[models.PayloadValidation](ctx, c, http.MethodPost, "/jobs/validate", nil, models.PayloadValidationRequest{Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// ValidateJobUpdate validates the payload a job would have after a partial update without
+// applying it.
+func (c *Client) ValidateJobUpdate(ctx context.Context, id string, updates models.JobUpdateRequest) (*models.PayloadValidation, error) {
+	id, err := escape(id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.PayloadValidation](ctx, c, http.MethodPatch, "/jobs/"+id, url.Values{"dry_run": {"true"}}, updates)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// UpdateJob applies a partial update to a job.
+func (c *Client) UpdateJob(ctx context.Context, id string, updates models.JobUpdateRequest) (*models.Job, error) {
+	id, err := escape(id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.Job](ctx, c, http.MethodPatch, "/jobs/"+id, nil, updates)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// DeleteJob removes a job.
+func (c *Client) DeleteJob(ctx context.Context, id string) error {
+	id, err := escape(id)
+	if err != nil {
+		return err
+	}
+	_, err = call[string](ctx, c, http.MethodDelete, "/jobs/"+id, nil, nil)
+	return err
+}
+
+// RunJob schedules a job to run at the scheduler's next poll.
+func (c *Client) RunJob(ctx context.Context, id string) (*models.JobSchedule, error) {
+	id, err := escape(id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.JobSchedule](ctx, c, http.MethodPost, "/jobs/"+id+"/run", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// paginate turns a list method into an iterator that follows page tokens until exhausted.
+func paginate[T any](ctx context.Context, pageSize int, list func(context.Context, ListOptions) (*Page[T], error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		opts := ListOptions{Limit: pageSize}
+		for {
+			page, err := list(ctx, opts)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if page.NextPageToken == "" || pageSize <= 0 {
+				return
+			}
+			opts.PageToken = page.NextPageToken
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// ExportManifests retrieves the caller's jobs as manifests.
+func (c *Client) ExportManifests(ctx context.Context) (*models.ManifestSet, error) {
+	res, err := call[models.ManifestSet](ctx, c, http.MethodGet, "/manifests", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// PlanManifests returns the changes applying req would make without making them.
+func (c *Client) PlanManifests(ctx context.Context, req models.ManifestApplyRequest) (*models.ManifestPlan, error) {
+	res, err := call[models.ManifestPlan](ctx, c, http.MethodPost, "/manifests/apply", url.Values{"dry_run": {"true"}}, req)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// ApplyManifests makes the caller's jobs match req and returns the changes made.
+func (c *Client) ApplyManifests(ctx context.Context, req models.ManifestApplyRequest) (*models.ManifestPlan, error) {
+	res, err := call[models.ManifestPlan](ctx, c, http.MethodPost, "/manifests/apply", nil, req)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// ScheduleQuery filters ListSchedules by next run time.
+type ScheduleQuery struct {
+	NextRunFrom   *time.Time
+	NextRunBefore *time.Time
+	ListOptions
+}
+
+func (q ScheduleQuery) values() url.Values {
+	params := q.ListOptions.values()
+	if q.NextRunFrom != nil {
+		params.Set("next_run_time[gte]", q.NextRunFrom.Format(time.RFC3339))
+	}
+	if q.NextRunBefore != nil {
+		params.Set("next_run_time[lt]", q.NextRunBefore.Format(time.RFC3339))
+	}
+	return params
+}
+
+// ListSchedules retrieves a page of job schedules matching q.
+func (c *Client) ListSchedules(ctx context.Context, q ScheduleQuery) (*Page[models.JobSchedule], error) {
+	res, err := call[[]models.JobSchedule](ctx, c, http.MethodGet, "/schedules/", q.values(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Page[models.JobSchedule]{Items: res.Data, NextPageToken: res.NextPageToken}, nil
+}
+
+// IterSchedules walks every job schedule matching q, fetching pageSize schedules per request.
+func (c *Client) IterSchedules(ctx context.Context, q ScheduleQuery, pageSize int) iter.Seq2[models.JobSchedule, error] {
+	return paginate(ctx, pageSize, func(ctx context.Context, opts ListOptions) (*Page[models.JobSchedule], error) {
+		q.ListOptions = opts
+		return c.ListSchedules(ctx, q)
+	})
+}
+
+// GetSchedule retrieves the schedule of a job.
+func (c *Client) GetSchedule(ctx context.Context, jobID string) (*models.JobSchedule, error) {
+	jobID, err := escape(jobID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.JobSchedule](ctx, c, http.MethodGet, "/schedules/"+jobID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// CreateSchedule creates a job schedule.
+func (c *Client) CreateSchedule(ctx context.Context, schedule models.JobSchedule) (*models.JobSchedule, error) {
+	res, err := call[models.JobSchedule](ctx, c, http.MethodPost, "/schedules", nil, schedule)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// UpdateSchedule applies a partial update to the schedule of a job.
+func (c *Client) UpdateSchedule(ctx context.Context, jobID string, updates models.JobScheduleUpdateRequest) (*models.JobSchedule, error) {
+	jobID, err := escape(jobID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.JobSchedule](ctx, c, http.MethodPatch, "/schedules/"+jobID, nil, updates)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// DeleteSchedule removes the schedule of a job.
+func (c *Client) DeleteSchedule(ctx context.Context, jobID string) error {
+	jobID, err := escape(jobID)
+	if err != nil {
+		return err
+	}
+	_, err = call[string](ctx, c, http.MethodDelete, "/schedules/"+jobID, nil, nil)
+	return err
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// GetUsage retrieves a user's current usage against each of their quotas. An empty userID gets
+// the caller's usage; other users' usage requires the admin scope.
+func (c *Client) GetUsage(ctx context.Context, userID string) (*models.Usage, error) {
+	query := url.Values{}
+	if userID != "" {
+		query.Set("user_id", userID)
+	}
+	res, err := call[models.Usage](ctx, c, http.MethodGet, "/usage", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// ListWebhooks retrieves the caller's webhooks, without their signing secrets.
+func (c *Client) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	res, err := call[[]models.Webhook](ctx, c, http.MethodGet, "/webhooks/", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+// GetWebhook retrieves a single webhook, without its signing secret.
+func (c *Client) GetWebhook(ctx context.Context, webhookID string) (*models.Webhook, error) {
+	webhookID, err := escape(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.Webhook](ctx, c, http.MethodGet, "/webhooks/"+webhookID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// CreateWebhook registers a webhook. The returned webhook carries the signing secret, which the
+// API does not return again.
+func (c *Client) CreateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error) {
+	res, err := call[models.Webhook](ctx, c, http.MethodPost, "/webhooks", nil, webhook)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// DeleteWebhook removes a webhook.
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID string) error {
+	webhookID, err := escape(webhookID)
+	if err != nil {
+		return err
+	}
+	_, err = call[string](ctx, c, http.MethodDelete, "/webhooks/"+webhookID, nil, nil)
+	return err
+}
+
+// ListWebhookDeliveries retrieves a page of a webhook's delivery log, newest first.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, webhookID string, opts ListOptions) (*Page[models.WebhookDelivery], error) {
+	webhookID, err := escape(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[[]models.WebhookDelivery](ctx, c, http.MethodGet, "/webhooks/"+webhookID+"/deliveries", opts.values(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Page[models.WebhookDelivery]{Items: res.Data, NextPageToken: res.NextPageToken}, nil
+}
+
+// RedeliverWebhook sends the payload of a previous delivery again, returning the new delivery.
+func (c *Client) RedeliverWebhook(ctx context.Context, webhookID string, deliveryID string) (*models.WebhookDelivery, error) {
+	webhookID, err := escape(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	deliveryID, err = escape(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.WebhookDelivery](ctx, c, http.MethodPost, "/webhooks/"+webhookID+"/deliveries/"+deliveryID+"/redeliver", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
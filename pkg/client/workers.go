@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+// ListWorkers retrieves the registered workers, or only those in the given health if it is set.
+func (c *Client) ListWorkers(ctx context.Context, health string) ([]models.WorkerNode, error) {
+	query := url.Values{}
+	if health != "" {
+		query.Set("health", health)
+	}
+	res, err := call[[]models.WorkerNode](ctx, c, http.MethodGet, "/workers/", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+// GetWorker retrieves a single registered worker.
+func (c *Client) GetWorker(ctx context.Context, workerID string) (*models.WorkerNode, error) {
+	workerID, err := escape(workerID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.WorkerNode](ctx, c, http.MethodGet, "/workers/"+workerID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// RegisterWorker records a worker that started, replacing any earlier registration of its ID.
+func (c *Client) RegisterWorker(ctx context.Context, workerID string, registration models.WorkerRegistration) (*models.WorkerNode, error) {
+	workerID, err := escape(workerID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.WorkerNode](ctx, c, http.MethodPut, "/workers/"+workerID, nil, registration)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+// SendWorkerHeartbeat reports that a worker is alive. It fails with ErrNotFound if the worker is
+// not registered.
+func (c *Client) SendWorkerHeartbeat(ctx context.Context, workerID string, heartbeat models.WorkerHeartbeat) (*models.WorkerNode, error) {
+	workerID, err := escape(workerID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call[models.WorkerNode](ctx, c, http.MethodPost, "/workers/"+workerID+"/heartbeat", nil, heartbeat)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
@@ -0,0 +1,147 @@
+// Package compile checks job payloads without saving them.
+//
+// Parsing and language checks run in the job service. Compiling is delegated to a worker over
+// RabbitMQ: requests are sent to the compile queue and answered on the direct reply-to
+// pseudo-queue, so the job service needs no sandboxes of its own.
+package compile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
+	"github.com/oklog/ulid/v2"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// directReplyTo is RabbitMQ's pseudo-queue for answering a request on the channel that sent it.
+const directReplyTo = "amq.rabbitmq.reply-to"
+
+// ErrUnavailable is returned when no worker answers a compile check in time.
+var ErrUnavailable = errors.New("no worker answered the compile check")
+
+// Client sends compile checks to workers and waits for their answers.
+type Client struct {
+	conf    *models.Config
+	ch      *amqp091.Channel
+	pubMu   sync.Mutex
+	mu      sync.Mutex
+	pending map[string]chan models.CompileResponse
+}
+
+func NewClient(conf *models.Config, conn *amqp091.Connection) (*Client, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = DeclareQueue(ch, conf); err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	replies, err := ch.Consume(directReplyTo, "", true, true, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("unable to consume compile replies: %w", err)
+	}
+
+	c := &Client{
+		conf:    conf,
+		ch:      ch,
+		pending: make(map[string]chan models.CompileResponse),
+	}
+	go c.listen(replies)
+
+	return c, nil
+}
+
+// DeclareQueue declares the queue compile checks are sent to.
+func DeclareQueue(ch *amqp091.Channel, conf *models.Config) (amqp091.Queue, error) {
+	q, err := ch.QueueDeclare(conf.Compile.QueueName, true, false, false, false, nil)
+	if err != nil {
+		return q, fmt.Errorf("unable to declare compile queue: %w", err)
+	}
+	return q, nil
+}
+
+// Channel returns the channel compile checks are sent and answered on.
+func (c *Client) Channel() *amqp091.Channel {
+	return c.ch
+}
+
+// Check compile-checks blocks on a worker and returns the compiler diagnostics.
+func (c *Client) Check(ctx context.Context, blocks []models.CompileBlock) ([]models.PayloadDiagnostic, error) {
+	body, err := json.Marshal(models.CompileRequest{Blocks: blocks})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.conf.Compile.Timeout)
+	defer cancel()
+
+	id := ulid.Make().String()
+	reply := make(chan models.CompileResponse, 1)
+	c.mu.Lock()
+	c.pending[id] = reply
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	pubCtx, span, headers := tracing.StartPublish(ctx, c.conf.Compile.QueueName, nil)
+	c.pubMu.Lock()
+	err = c.ch.PublishWithContext(pubCtx, "", c.conf.Compile.QueueName, false, false, amqp091.Publishing{
+		ContentType:   "application/json",
+		CorrelationId: id,
+		ReplyTo:       directReplyTo,
+		// a check nobody picked up in time is no longer awaited
+		Expiration: strconv.FormatInt(c.conf.Compile.Timeout.Milliseconds(), 10),
+		Headers:    headers,
+		Body:       body,
+	})
+	c.pubMu.Unlock()
+	span.End()
+	if err != nil {
+		return nil, fmt.Errorf("unable to send compile check: %w", err)
+	}
+
+	select {
+	case res := <-reply:
+		if res.Error != "" {
+			return nil, fmt.Errorf("compile check failed: %s", res.Error)
+		}
+		return res.Diagnostics, nil
+	case <-ctx.Done():
+		return nil, ErrUnavailable
+	}
+}
+
+func (c *Client) listen(replies <-chan amqp091.Delivery) {
+	for d := range replies {
+		var res models.CompileResponse
+		if err := json.Unmarshal(d.Body, &res); err != nil {
+			res.Error = "malformed compile response"
+		}
+
+		c.mu.Lock()
+		reply, ok := c.pending[d.CorrelationId]
+		delete(c.pending, d.CorrelationId)
+		c.mu.Unlock()
+
+		if ok {
+			reply <- res
+		}
+	}
+}
+
+func (c *Client) Close() error {
+	return c.ch.Close()
+}
@@ -0,0 +1,64 @@
+package compile
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/utils"
+)
+
+// Checker compile-checks code blocks.
+type Checker interface {
+	Check(ctx context.Context, blocks []models.CompileBlock) ([]models.PayloadDiagnostic, error)
+}
+
+// Validate parses a markdown payload and checks its code blocks with ValidateBlocks.
+func Validate(ctx context.Context, checker Checker, payload string) (models.PayloadValidation, error) {
+	parser := &utils.Parser{}
+	if err := parser.Parse(payload); err != nil {
+		return models.PayloadValidation{
+			Diagnostics: []models.PayloadDiagnostic{{Message: fmt.Sprintf("unable to parse payload: %v", err)}},
+		}, nil
+	}
+
+	return ValidateBlocks(ctx, checker, parser.Result)
+}
+
+// ValidateBlocks checks that there is at least one code block, that each is in a supported
+// language and that each compiles. Blocks are only compiled once every other check passes. An
+// error is returned only if the check itself could not be made.
+func ValidateBlocks(ctx context.Context, checker Checker, blocks []utils.CodeBlock) (models.PayloadValidation, error) {
+	res := models.PayloadValidation{
+		Blocks:      len(blocks),
+		Diagnostics: []models.PayloadDiagnostic{},
+	}
+
+	if len(blocks) == 0 {
+		res.Diagnostics = append(res.Diagnostics, models.PayloadDiagnostic{Message: "payload has no code blocks"})
+	}
+
+	supported := utils.GetSupportedLanguages()
+	toCompile := make([]models.CompileBlock, 0, len(blocks))
+	for i, block := range blocks {
+		switch {
+		case block.Language == "":
+			res.Diagnostics = append(res.Diagnostics, models.PayloadDiagnostic{Block: i + 1, Message: "code block has no language"})
+		case supported[block.Language] == "":
+			res.Diagnostics = append(res.Diagnostics, models.PayloadDiagnostic{Block: i + 1, Language: block.Language, Message: fmt.Sprintf("%s is not a supported code language", block.Language)})
+		}
+		toCompile = append(toCompile, models.CompileBlock{Language: block.Language, Content: block.Content})
+	}
+
+	if len(res.Diagnostics) == 0 {
+		diagnostics, err := checker.Check(ctx, toCompile)
+		if err != nil {
+			return res, err
+		}
+		res.Diagnostics = append(res.Diagnostics, diagnostics...)
+	}
+
+	res.Valid = len(res.Diagnostics) == 0
+
+	return res, nil
+}
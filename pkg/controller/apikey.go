@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/julianstephens/distributed-job-manager/pkg/utils"
+)
+
+type APIKeyController struct {
+	Controller
+	repo *repository.APIKeyRepository
+}
+
+func NewAPIKeyController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger) *APIKeyController {
+	return &APIKeyController{
+		Controller: Controller{
+			DB:     db,
+			Config: config,
+			Logger: logger,
+		},
+		repo: repository.NewAPIKeyRepository(db, logger),
+	}
+}
+
+// GetAPIKeys godoc
+// @Summary Get all API keys
+// @Description retrieves the API keys created by the user, including revoked and expired keys, or every key for admins
+// @Tags api-keys
+// @Security ApiKey
+// @Success 200 {object} httputil.HTTPResponse[[]models.APIKey]
+// @Failure 500 {object} httputil.HTTPError
+// @Router /api-keys [get]
+func (a *APIKeyController) GetAPIKeys(c *gin.Context) {
+	userId := httputil.GetUserId(c)
+	isAdmin := c.GetBool("isAdmin")
+
+	keys, err := a.repo.WithContext(c.Request.Context()).GetAPIKeys(userId, isAdmin)
+	if err != nil {
+		httputil.NewError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.NewResponse(c, *keys, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description creates an API key for the user, or for one of the user's service accounts when service_account is set.
+// @Description Keys may only hold scopes the caller holds, and keys created with an expiring API key expire with it at the latest.
+// @Description The response contains the key, which is not returned again.
+// @Tags api-keys
+// @Security ApiKey
+// @Success 201 {object} httputil.HTTPResponse[models.APIKeyCreateResponse]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /api-keys [post]
+func (a *APIKeyController) CreateAPIKey(c *gin.Context) {
+	userId := httputil.GetUserId(c)
+	isAdmin := c.GetBool("isAdmin")
+
+	var keyData models.APIKeyCreateRequest
+	if err := c.ShouldBindJSON(&keyData); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	if !isAdmin {
+		callerScopes := c.GetStringSlice("scopes")
+		for _, scope := range keyData.Scopes {
+			if !slices.Contains(callerScopes, scope) {
+				httputil.NewError(c, http.StatusForbidden, fmt.Errorf("cannot grant scope not held by caller: %s", scope))
+				return
+			}
+		}
+	}
+
+	// a key must not outlive the key that created it
+	if callerExpiry, ok := c.Get("apiKeyExpiresAt"); ok {
+		if expiresAt := callerExpiry.(time.Time); keyData.ExpiresAt == nil || keyData.ExpiresAt.After(expiresAt) {
+			keyData.ExpiresAt = &expiresAt
+		}
+	}
+
+	res, err := a.repo.WithContext(c.Request.Context()).CreateAPIKey(keyData, userId)
+	if err != nil {
+		httputil.NewError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the plaintext key must never reach the audit log
+	a.audit(c, models.AuditActionCreate, models.AuditResourceAPIKey, res.KeyID, nil, res.APIKey)
+
+	httputil.NewResponse(c, *res, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Post})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description revokes an API key so it can no longer authenticate
+// @Tags api-keys
+// @Security ApiKey
+// @Success 200 {object} httputil.HTTPResponse[models.APIKey]
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /api-keys/:id [delete]
+func (a *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	userId := httputil.GetUserId(c)
+	keyId := httputil.GetId(c)
+	isAdmin := c.GetBool("isAdmin")
+
+	key, err := a.repo.WithContext(c.Request.Context()).RevokeAPIKey(keyId, userId, isAdmin)
+	if err != nil {
+		httputil.NewError(c, utils.If(errors.Is(err, repository.ErrAPIKeyNotFound), http.StatusNotFound, http.StatusInternalServerError), err)
+		return
+	}
+
+	a.audit(c, models.AuditActionDelete, models.AuditResourceAPIKey, keyId, key, nil)
+
+	httputil.NewResponse(c, *key, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Delete})
+}
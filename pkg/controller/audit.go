@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+)
+
+type AuditController struct {
+	Controller
+	repo *repository.AuditRepository
+}
+
+func NewAuditController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger) *AuditController {
+	return &AuditController{
+		Controller: Controller{
+			DB:     db,
+			Config: config,
+			Logger: logger,
+		},
+		repo: repository.NewAuditRepository(db, logger),
+	}
+}
+
+// GetAuditEntries godoc
+// @Summary Get audit log entries
+// @Description retrieves a page of the audit log, optionally filtered by resource, actor and time range. Entries for a single resource are returned newest first. Requires the admin scope.
+// @Tags audit
+// @Security ApiKey
+// @Param resource_type query string false "type of resource, required with resource_id"
+// @Param resource_id query string false "id of resource"
+// @Param actor_id query string false "user or service that made the change"
+// @Param from query string false "RFC 3339 time of the earliest entry"
+// @Param to query string false "RFC 3339 time entries must be before"
+// @Param limit query int false "page size"
+// @Param page_token query string false "token from a previous page"
+// @Success 200 {object} httputil.HTTPResponse[[]models.AuditEntry]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /audit [get]
+func (a *AuditController) GetAuditEntries(c *gin.Context) {
+	var query models.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	page, ok := httputil.GetPage(c)
+	if !ok {
+		return
+	}
+
+	entries, nextToken, err := a.repo.WithContext(c.Request.Context()).GetEntries(query, page)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	httputil.NewResponse(c, *entries, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get, NextPageToken: nextToken})
+}
@@ -1,10 +1,22 @@
 package controller
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/compile"
+	"github.com/julianstephens/distributed-job-manager/pkg/events"
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
+	"github.com/julianstephens/distributed-job-manager/pkg/manifests"
+	"github.com/julianstephens/distributed-job-manager/pkg/middleware"
 	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
 	"github.com/julianstephens/distributed-job-manager/pkg/store"
 )
 
@@ -12,6 +24,89 @@ type Controller struct {
 	DB     *store.DBSession
 	Config *models.Config
 	Logger *graylogger.GrayLogger
+	Events *events.Bus
 }
 
 var ErrMissingJobId = errors.New("no job id provided")
+
+// publishEvent broadcasts a state transition. Failures are logged rather than returned so a
+// subscriber outage never fails the mutation that caused the event.
+func (c *Controller) publishEvent(ctx context.Context, event models.JobEvent) {
+	if c.Events == nil {
+		return
+	}
+	if err := c.Events.Publish(ctx, event); err != nil {
+		c.Logger.Error(fmt.Sprintf("failed to publish %s event for job %s", event.Type, event.JobID), &err)
+	}
+}
+
+// audit records a mutation in the audit log, snapshotting before and after as JSON. Either may be
+// nil. Failures are logged rather than returned so an audit outage never fails the mutation.
+func (c *Controller) audit(ctx *gin.Context, action string, resourceType string, resourceId string, before any, after any) {
+	actorId := httputil.GetUserId(ctx)
+	entry := models.AuditEntry{
+		ResourceType: resourceType,
+		ResourceID:   resourceId,
+		Action:       action,
+		ActorID:      actorId,
+		ActorSource:  c.actorSource(ctx, actorId),
+		APIKeyID:     ctx.GetString("apiKeyId"),
+		RequestID:    ctx.GetString("requestId"),
+		Before:       snapshot(before),
+		After:        snapshot(after),
+	}
+
+	if err := repository.NewAuditRepository(c.DB, c.Logger).WithContext(ctx.Request.Context()).RecordEntry(entry); err != nil {
+		c.Logger.Error(fmt.Sprintf("failed to audit %s of %s %s by %s", action, resourceType, resourceId, actorId), &err)
+	}
+}
+
+// actorSource tells the scheduler and workers apart from users. Their tokens carry the service's
+// subject in local auth mode and '<client id>@clients' with OIDC client credentials.
+func (c *Controller) actorSource(ctx *gin.Context, subject string) string {
+	isClient := func(clientId string) bool {
+		return clientId != "" && subject == clientId+"@clients"
+	}
+
+	switch {
+	case subject == models.SchedulerSubject || isClient(c.Config.Schedule.Auth0ClientID):
+		return models.AuditSourceScheduler
+	case strings.HasPrefix(subject, models.WorkerSubjectPrefix) || isClient(c.Config.Auth0Worker.ClientId):
+		return models.AuditSourceWorker
+	case ctx.GetString("authSource") == middleware.AuthSourceAPIKey:
+		return models.AuditSourceAPIKey
+	default:
+		return models.AuditSourceUser
+	}
+}
+
+// snapshot marshals a resource for the audit log, returning nil for a nil resource.
+func snapshot(resource any) json.RawMessage {
+	if resource == nil {
+		return nil
+	}
+	data, err := json.Marshal(resource)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
+
+// errorStatus maps repository errors to the HTTP status they are reported with.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrJobNotFound), errors.Is(err, repository.ErrExecutionNotFound), errors.Is(err, repository.ErrWorkerNotFound),
+		errors.Is(err, repository.ErrDeadLetterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrForbidden), errors.Is(err, repository.ErrQuotaExceeded):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrInvalidPageToken), errors.Is(err, manifests.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrExecutionChanged):
+		return http.StatusConflict
+	case errors.Is(err, compile.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
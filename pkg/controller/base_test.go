@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"job of another tenant", repository.ErrJobNotFound, http.StatusNotFound},
+		{"execution of another tenant", repository.ErrExecutionNotFound, http.StatusNotFound},
+		{"wrapped job not found", fmt.Errorf("unable to schedule job: %w", repository.ErrJobNotFound), http.StatusNotFound},
+		{"insufficient role", repository.ErrForbidden, http.StatusForbidden},
+		{"quota used up", fmt.Errorf("%w: user alice has run 10 executions today", repository.ErrQuotaExceeded), http.StatusForbidden},
+		{"invalid page token", repository.ErrInvalidPageToken, http.StatusBadRequest},
+		{"execution changed meanwhile", repository.ErrExecutionChanged, http.StatusConflict},
+		{"unexpected failure", errors.New("unable to get job"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorStatus(tt.err); got != tt.want {
+				t.Errorf("errorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/deadletters"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+)
+
+type DeadLetterController struct {
+	Controller
+	repo    *repository.DeadLetterRepository
+	manager *deadletters.Manager
+}
+
+func NewDeadLetterController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger, manager *deadletters.Manager) *DeadLetterController {
+	return &DeadLetterController{
+		Controller: Controller{
+			DB:     db,
+			Config: config,
+			Logger: logger,
+		},
+		repo:    repository.NewDeadLetterRepository(db, logger),
+		manager: manager,
+	}
+}
+
+// GetDeadLetters godoc
+// @Summary Get dead letters
+// @Description retrieves a page of the job messages the work queue gave up on, optionally of a single job. Requires the admin scope.
+// @Tags dead-letters
+// @Security ApiKey
+// @Param job_id query string false "id of job"
+// @Param limit query int false "page size"
+// @Param page_token query string false "token from a previous page"
+// @Success 200 {object} httputil.HTTPResponse[[]models.DeadLetter]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /dead-letters [get]
+func (d *DeadLetterController) GetDeadLetters(c *gin.Context) {
+	var query models.DeadLetterQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	page, ok := httputil.GetPage(c)
+	if !ok {
+		return
+	}
+
+	letters, nextToken, err := d.repo.WithContext(c.Request.Context()).GetDeadLetters(query, page)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	httputil.NewResponse(c, *letters, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get, NextPageToken: nextToken})
+}
+
+// GetDeadLetter godoc
+// @Summary Get a dead letter
+// @Description retrieves a dead-lettered job message along with why it was dead-lettered. Requires the admin scope.
+// @Tags dead-letters
+// @Security ApiKey
+// @Param id path string true "dead letter id"
+// @Success 200 {object} httputil.HTTPResponse[models.DeadLetter]
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /dead-letters/:id [get]
+func (d *DeadLetterController) GetDeadLetter(c *gin.Context) {
+	letter, err := d.repo.WithContext(c.Request.Context()).GetDeadLetter(httputil.GetId(c))
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	httputil.NewResponse(c, *letter, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
+// ReplayDeadLetter godoc
+// @Summary Replay a dead letter
+// @Description publishes a dead-lettered job message to the work queue again and removes the dead letter. Requires the admin scope.
+// @Tags dead-letters
+// @Security ApiKey
+// @Param id path string true "dead letter id"
+// @Success 202 {object} httputil.HTTPResponse[models.DeadLetter]
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /dead-letters/:id/replay [post]
+func (d *DeadLetterController) ReplayDeadLetter(c *gin.Context) {
+	letter, err := d.repo.WithContext(c.Request.Context()).GetDeadLetter(httputil.GetId(c))
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	if err = d.manager.Replay(c.Request.Context(), *letter); err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	d.audit(c, models.AuditActionTrigger, models.AuditResourceDeadLetter, letter.DeadLetterID, letter, nil)
+
+	httputil.NewResponse(c, *letter, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get, Status: http.StatusAccepted})
+}
+
+// DeleteDeadLetter godoc
+// @Summary Delete a dead letter
+// @Description removes a dead-lettered job message without replaying it. Requires the admin scope.
+// @Tags dead-letters
+// @Security ApiKey
+// @Param id path string true "dead letter id"
+// @Success 200 {object} httputil.HTTPResponse[string]
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /dead-letters/:id [delete]
+func (d *DeadLetterController) DeleteDeadLetter(c *gin.Context) {
+	id := httputil.GetId(c)
+
+	before, err := d.repo.WithContext(c.Request.Context()).GetDeadLetter(id)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	if err = d.repo.WithContext(c.Request.Context()).DeleteDeadLetter(id); err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	d.audit(c, models.AuditActionDelete, models.AuditResourceDeadLetter, id, before, nil)
+
+	httputil.NewResponse(c, id, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Delete})
+}
+
+// PurgeDeadLetters godoc
+// @Summary Purge dead letters
+// @Description removes every dead letter, or those of a single job, without replaying them. Requires the admin scope.
+// @Tags dead-letters
+// @Security ApiKey
+// @Param job_id query string false "id of job"
+// @Success 200 {object} httputil.HTTPResponse[models.DeadLetterPurge]
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /dead-letters [delete]
+func (d *DeadLetterController) PurgeDeadLetters(c *gin.Context) {
+	var query models.DeadLetterQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	purged, err := d.repo.WithContext(c.Request.Context()).PurgeDeadLetters(query)
+	// dead letters removed before a failure are gone all the same
+	if purged != nil {
+		for _, letter := range *purged {
+			d.audit(c, models.AuditActionDelete, models.AuditResourceDeadLetter, letter.DeadLetterID, letter, nil)
+		}
+	}
+	if err != nil {
+		if purged != nil && len(*purged) > 0 {
+			err = fmt.Errorf("purged %d dead letters before failing: %w", len(*purged), err)
+		}
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	httputil.NewResponse(c, models.DeadLetterPurge{Purged: len(*purged)}, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Delete})
+}
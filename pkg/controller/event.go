@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/events"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+)
+
+// StreamKeepAlive is how often an idle event stream sends a comment to keep proxies from
+// closing the connection.
+const StreamKeepAlive = 15 * time.Second
+
+type EventController struct {
+	Controller
+	repo *repository.EventRepository
+	jobs *repository.JobRepository
+}
+
+func NewEventController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger, bus *events.Bus) *EventController {
+	return &EventController{
+		Controller: Controller{
+			DB:     db,
+			Config: config,
+			Logger: logger,
+			Events: bus,
+		},
+		repo: repository.NewEventRepository(db, logger),
+		jobs: repository.NewJobRepository(db, logger),
+	}
+}
+
+// StreamEvents godoc
+// @Summary Stream job events
+// @Description streams job and execution state transitions as server-sent events for every job the caller can view. Admins receive events for every job.
+// @Description Reconnecting clients resume after the event named by the Last-Event-ID header or last_event_id query param.
+// @Tags events
+// @Security ApiKey
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "id of the last event received"
+// @Param last_event_id query string false "id of the last event received"
+// @Success 200 {object} models.JobEvent
+// @Failure 500 {object} httputil.HTTPError
+// @Router /events [get]
+func (e *EventController) StreamEvents(c *gin.Context) {
+	principal := httputil.GetPrincipal(c)
+
+	lastEventId := c.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.Query("last_event_id")
+	}
+
+	// whether the caller can view each job is checked once per stream; events of jobs already
+	// known to be hidden are dropped before they reach the stream
+	var visible sync.Map
+	sub := e.Events.Subscribe(func(event models.JobEvent) bool {
+		if principal.IsAdmin || event.UserID == principal.UserID {
+			return true
+		}
+		canView, checked := visible.Load(event.JobID)
+		return !checked || canView.(bool)
+	})
+	defer sub.Close()
+
+	var missed []models.JobEvent
+	if lastEventId != "" {
+		res, err := e.repo.WithContext(c.Request.Context()).GetEventsAfter(lastEventId, principal)
+		if err != nil {
+			httputil.NewError(c, http.StatusInternalServerError, err)
+			return
+		}
+		missed = *res
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	replayed := make(map[string]struct{}, len(missed))
+	for _, event := range missed {
+		c.Render(-1, sse.Event{Id: event.EventID, Event: event.Type, Data: event})
+		replayed[event.EventID] = struct{}{}
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(StreamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			if _, ok := replayed[event.EventID]; ok {
+				return true
+			}
+			if !e.canView(c, principal, event, &visible) {
+				return true
+			}
+			c.Render(-1, sse.Event{Id: event.EventID, Event: event.Type, Data: event})
+			return true
+		}
+	})
+}
+
+// canView reports whether the principal can view the job of an event, checking the job's grants
+// the first time one of its events is seen and caching the answer in visible.
+func (e *EventController) canView(c *gin.Context, principal models.Principal, event models.JobEvent, visible *sync.Map) bool {
+	if principal.IsAdmin || event.UserID == principal.UserID {
+		return true
+	}
+	if canView, checked := visible.Load(event.JobID); checked {
+		return canView.(bool)
+	}
+
+	_, err := e.jobs.WithContext(c.Request.Context()).GetJob(event.JobID, principal)
+	switch {
+	case err == nil:
+		visible.Store(event.JobID, true)
+		return true
+	case errors.Is(err, repository.ErrJobNotFound), errors.Is(err, repository.ErrForbidden):
+		visible.Store(event.JobID, false)
+	default:
+		e.Logger.Error(fmt.Sprintf("failed to check whether user %s can view job %s", principal.UserID, event.JobID), &err)
+	}
+
+	return false
+}
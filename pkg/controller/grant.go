@@ -0,0 +1,236 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+)
+
+type GrantController struct {
+	Controller
+	repo *repository.GrantRepository
+}
+
+func NewGrantController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger) *GrantController {
+	return &GrantController{
+		Controller: Controller{
+			DB:     db,
+			Config: config,
+			Logger: logger,
+		},
+		repo: repository.NewGrantRepository(db, logger),
+	}
+}
+
+// GetJobGrants godoc
+// @Summary Get the grants on a job
+// @Description retrieves the roles users and groups have been granted on a job. Requires the admin role on the job.
+// @Tags grants
+// @Security ApiKey
+// @Success 200 {object} httputil.HTTPResponse[[]models.JobGrant]
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /jobs/:id/grants [get]
+func (g *GrantController) GetJobGrants(c *gin.Context) {
+	principal := httputil.GetPrincipal(c)
+	jobId := httputil.GetId(c)
+
+	grants, err := g.repo.WithContext(c.Request.Context()).GetJobGrants(jobId, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to get grants on job %s: %w", jobId, err))
+		return
+	}
+
+	httputil.NewResponse(c, *grants, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
+// SaveJobGrant godoc
+// @Summary Grant a role on a job
+// @Description gives a user or group a role on a job, replacing any role they already had. Requires the admin role on the job.
+// @Tags grants
+// @Security ApiKey
+// @Success 200 {object} httputil.HTTPResponse[models.JobGrant]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /jobs/:id/grants [put]
+func (g *GrantController) SaveJobGrant(c *gin.Context) {
+	principal := httputil.GetPrincipal(c)
+	jobId := httputil.GetId(c)
+
+	var grant models.JobGrant
+	if err := c.ShouldBindJSON(&grant); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	existing, err := g.repo.WithContext(c.Request.Context()).GetJobGrants(jobId, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to grant role on job %s: %w", jobId, err))
+		return
+	}
+
+	res, err := g.repo.WithContext(c.Request.Context()).SaveJobGrant(jobId, grant, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to grant role on job %s: %w", jobId, err))
+		return
+	}
+
+	g.auditSave(c, models.AuditResourceJobGrant, jobId, findGrant(*existing, res.SubjectType, res.SubjectID), res)
+
+	httputil.NewResponse(c, *res, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Put})
+}
+
+// DeleteJobGrant godoc
+// @Summary Revoke a role on a job
+// @Description removes the role a user or group was granted on a job. Requires the admin role on the job.
+// @Tags grants
+// @Security ApiKey
+// @Param subjectType path string true "user or group"
+// @Param subjectId path string true "user or group id"
+// @Success 200 {object} httputil.HTTPResponse[string]
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /jobs/:id/grants/:subjectType/:subjectId [delete]
+func (g *GrantController) DeleteJobGrant(c *gin.Context) {
+	principal := httputil.GetPrincipal(c)
+	jobId := httputil.GetId(c)
+	subjectId := c.Param("subjectId")
+
+	subjectType := c.Param("subjectType")
+
+	existing, err := g.repo.WithContext(c.Request.Context()).GetJobGrants(jobId, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to revoke role on job %s: %w", jobId, err))
+		return
+	}
+
+	if err := g.repo.WithContext(c.Request.Context()).DeleteJobGrant(jobId, subjectType, subjectId, principal); err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to revoke role on job %s: %w", jobId, err))
+		return
+	}
+
+	g.audit(c, models.AuditActionDelete, models.AuditResourceJobGrant, jobId, findGrant(*existing, subjectType, subjectId), nil)
+
+	httputil.NewResponse(c, subjectId, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Delete})
+}
+
+// GetProjectGrants godoc
+// @Summary Get the grants on a project
+// @Description retrieves the roles users and groups have been granted on every job in a project. Requires the admin role on the project.
+// @Tags grants
+// @Security ApiKey
+// @Success 200 {object} httputil.HTTPResponse[[]models.JobGrant]
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /projects/:id/grants [get]
+func (g *GrantController) GetProjectGrants(c *gin.Context) {
+	principal := httputil.GetPrincipal(c)
+	project := httputil.GetId(c)
+
+	grants, err := g.repo.WithContext(c.Request.Context()).GetProjectGrants(project, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to get grants on project %s: %w", project, err))
+		return
+	}
+
+	httputil.NewResponse(c, *grants, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
+// SaveProjectGrant godoc
+// @Summary Grant a role on a project
+// @Description gives a user or group a role on every job in a project, replacing any role they already had. Requires the admin role on the project.
+// @Tags grants
+// @Security ApiKey
+// @Success 200 {object} httputil.HTTPResponse[models.JobGrant]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /projects/:id/grants [put]
+func (g *GrantController) SaveProjectGrant(c *gin.Context) {
+	principal := httputil.GetPrincipal(c)
+	project := httputil.GetId(c)
+
+	var grant models.JobGrant
+	if err := c.ShouldBindJSON(&grant); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	existing, err := g.repo.WithContext(c.Request.Context()).GetProjectGrants(project, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to grant role on project %s: %w", project, err))
+		return
+	}
+
+	res, err := g.repo.WithContext(c.Request.Context()).SaveProjectGrant(project, grant, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to grant role on project %s: %w", project, err))
+		return
+	}
+
+	g.auditSave(c, models.AuditResourceProjectGrant, project, findGrant(*existing, res.SubjectType, res.SubjectID), res)
+
+	httputil.NewResponse(c, *res, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Put})
+}
+
+// DeleteProjectGrant godoc
+// @Summary Revoke a role on a project
+// @Description removes the role a user or group was granted on a project. Requires the admin role on the project.
+// @Tags grants
+// @Security ApiKey
+// @Param subjectType path string true "user or group"
+// @Param subjectId path string true "user or group id"
+// @Success 200 {object} httputil.HTTPResponse[string]
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /projects/:id/grants/:subjectType/:subjectId [delete]
+func (g *GrantController) DeleteProjectGrant(c *gin.Context) {
+	principal := httputil.GetPrincipal(c)
+	project := httputil.GetId(c)
+	subjectId := c.Param("subjectId")
+
+	subjectType := c.Param("subjectType")
+
+	existing, err := g.repo.WithContext(c.Request.Context()).GetProjectGrants(project, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to revoke role on project %s: %w", project, err))
+		return
+	}
+
+	if err := g.repo.WithContext(c.Request.Context()).DeleteProjectGrant(project, subjectType, subjectId, principal); err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to revoke role on project %s: %w", project, err))
+		return
+	}
+
+	g.audit(c, models.AuditActionDelete, models.AuditResourceProjectGrant, project, findGrant(*existing, subjectType, subjectId), nil)
+
+	httputil.NewResponse(c, subjectId, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Delete})
+}
+
+// auditSave records a grant being saved as a create, or as an update when it replaced a grant.
+func (g *GrantController) auditSave(c *gin.Context, resourceType string, resourceId string, before *models.JobGrant, after *models.JobGrant) {
+	if before == nil {
+		g.audit(c, models.AuditActionCreate, resourceType, resourceId, nil, after)
+		return
+	}
+	g.audit(c, models.AuditActionUpdate, resourceType, resourceId, before, after)
+}
+
+// findGrant returns the grant to a subject, or nil if there is none.
+func findGrant(grants []models.JobGrant, subjectType string, subjectId string) *models.JobGrant {
+	for _, grant := range grants {
+		if grant.SubjectType == subjectType && grant.SubjectID == subjectId {
+			return &grant
+		}
+	}
+	return nil
+}
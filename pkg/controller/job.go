@@ -3,50 +3,65 @@ package controller
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/compile"
+	"github.com/julianstephens/distributed-job-manager/pkg/events"
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
 	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
 	"github.com/julianstephens/distributed-job-manager/pkg/models"
 	"github.com/julianstephens/distributed-job-manager/pkg/repository"
 	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/julianstephens/distributed-job-manager/pkg/utils"
 )
 
 type JobController struct {
 	Controller
-	repo *repository.JobRepository
+	repo     *repository.JobRepository
+	quotas   *repository.QuotaRepository
+	compiler compile.Checker
 }
 
-func NewJobController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger) *JobController {
+func NewJobController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger, bus *events.Bus, compiler compile.Checker) *JobController {
 	return &JobController{
 		Controller: Controller{
 			DB:     db,
 			Config: config,
 			Logger: logger,
+			Events: bus,
 		},
-		repo: repository.NewJobRepository(db, logger),
+		repo:     repository.NewJobRepository(db, logger),
+		quotas:   repository.NewQuotaRepository(db, logger, config.Quota),
+		compiler: compiler,
 	}
 }
 
 // GetJobs godoc
 // @Summary Get all jobs
-// @Description retrieves all jobs
+// @Description retrieves a page of the jobs the user owns or has been granted a role on, or all jobs when no limit is given. Pages may hold fewer jobs than the limit.
 // @Tags jobs
 // @Security ApiKey
+// @Param limit query int false "page size"
+// @Param page_token query string false "token from a previous page"
 // @Success 200 {object} httputil.HTTPResponse[[]models.Job]
 // @Failure 500 {object} httputil.HTTPError
 // @Router /jobs [get]
 func (j *JobController) GetJobs(c *gin.Context) {
-	userId := httputil.GetUserId(c)
-	isAdmin := c.GetBool("isAdmin")
+	principal := httputil.GetPrincipal(c)
 
-	jobs, err := j.repo.GetJobs(userId, isAdmin)
+	page, ok := httputil.GetPage(c)
+	if !ok {
+		return
+	}
+
+	jobs, nextToken, err := j.repo.WithContext(c.Request.Context()).GetJobs(principal, page)
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		httputil.NewError(c, errorStatus(err), err)
 		return
 	}
 
-	httputil.NewResponse(c, *jobs, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+	httputil.NewResponse(c, *jobs, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get, NextPageToken: nextToken})
 }
 
 // GetJob godoc
@@ -55,16 +70,16 @@ func (j *JobController) GetJobs(c *gin.Context) {
 // @Tags jobs
 // @Security ApiKey
 // @Success 200 {object} httputil.HTTPResponse[models.Job]
+// @Failure 404 {object} httputil.HTTPError
 // @Failure 500 {object} httputil.HTTPError
 // @Router /jobs/:id [get]
 func (j *JobController) GetJob(c *gin.Context) {
-	userId := httputil.GetUserId(c)
+	principal := httputil.GetPrincipal(c)
 	jobId := httputil.GetId(c)
-	isAdmin := c.GetBool("isAdmin")
 
-	job, err := j.repo.GetJob(jobId, userId, isAdmin)
+	job, err := j.repo.WithContext(c.Request.Context()).GetJob(jobId, principal)
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, fmt.Errorf("unable to get job %s", jobId))
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to get job %s: %w", jobId, err))
 		return
 	}
 
@@ -73,72 +88,203 @@ func (j *JobController) GetJob(c *gin.Context) {
 
 // CreateJob godoc
 // @Summary Create a job
-// @Description creates a new job
+// @Description creates a new job. Creating a job in a project requires the editor role on the project, and users may only own a limited number of active jobs. With dry_run=true the payload is only validated, as by /jobs/validate.
 // @Tags jobs
 // @Security ApiKey
+// @Param dry_run query bool false "validate the payload without creating the job"
 // @Success 201 {object} httputil.HTTPResponse[models.Job]
+// @Success 200 {object} httputil.HTTPResponse[models.PayloadValidation]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 403 {object} httputil.HTTPError
 // @Failure 500 {object} httputil.HTTPError
+// @Failure 503 {object} httputil.HTTPError
 // @Router /jobs [post]
 func (j *JobController) CreateJob(c *gin.Context) {
-	userId := httputil.GetUserId(c)
+	principal := httputil.GetPrincipal(c)
 
 	var job models.Job
 	if err := c.ShouldBindJSON(&job); err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		httputil.HandleFieldError(c, err)
 		return
 	}
 
-	res, err := j.repo.CreateJob(job, userId)
+	if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+		res, err := compile.Validate(c.Request.Context(), j.compiler, job.Payload)
+		j.respondValidation(c, res, err)
+		return
+	}
+
+	if !principal.IsAdmin {
+		if err := j.quotas.WithContext(c.Request.Context()).CheckJobQuota(principal.UserID); err != nil {
+			httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to create job: %w", err))
+			return
+		}
+	}
+
+	res, err := j.repo.WithContext(c.Request.Context()).CreateJob(job, principal)
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, fmt.Errorf("unable to create job: %w", err))
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to create job: %w", err))
 		return
 	}
 
+	j.publishEvent(c.Request.Context(), models.JobEvent{Type: models.EventJobCreated, UserID: res.UserID, JobID: res.JobID, Status: res.Status})
+	j.audit(c, models.AuditActionCreate, models.AuditResourceJob, res.JobID, nil, res)
+
 	httputil.NewResponse(c, *res, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Post})
 }
 
 // UpdateJob godoc
 // @Summary Update a job
-// @Description updates a new job
+// @Description updates a job. Changing only the status requires the operator role on the job, any other change the editor role. With dry_run=true the payload the job would have after the update is only validated, as by /jobs/validate.
 // @Tags jobs
 // @Security ApiKey
+// @Param dry_run query bool false "validate the payload without updating the job"
 // @Success 201 {object} httputil.HTTPResponse[models.Job]
+// @Success 200 {object} httputil.HTTPResponse[models.PayloadValidation]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
 // @Failure 500 {object} httputil.HTTPError
+// @Failure 503 {object} httputil.HTTPError
 // @Router /jobs/:id [patch]
 func (j *JobController) UpdateJob(c *gin.Context) {
-	userId := httputil.GetUserId(c)
+	principal := httputil.GetPrincipal(c)
 	jobId := httputil.GetId(c)
 
 	var jobUpdate models.JobUpdateRequest
 	if err := c.ShouldBindJSON(&jobUpdate); err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	before, err := j.repo.WithContext(c.Request.Context()).GetJob(jobId, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to update job %s: %w", jobId, err))
+		return
+	}
+
+	if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+		j.validateUpdate(c, *before, jobUpdate)
 		return
 	}
 
-	job, err := j.repo.UpdateJob(jobUpdate, jobId, userId)
+	job, err := j.repo.WithContext(c.Request.Context()).UpdateJob(jobUpdate, jobId, principal)
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, fmt.Errorf("unable to update job %s: %w", jobId, err))
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to update job %s: %w", jobId, err))
 		return
 	}
 
+	action := models.AuditActionUpdate
+	if job.Status == models.JobStatusCancelled && before.Status != models.JobStatusCancelled {
+		action = models.AuditActionCancel
+	}
+	j.audit(c, action, models.AuditResourceJob, jobId, before, job)
+
+	if jobUpdate.Status != nil {
+		j.publishEvent(c.Request.Context(), models.JobEvent{Type: models.EventJobStatusChanged, UserID: job.UserID, JobID: job.JobID, Status: job.Status})
+	}
+
 	httputil.NewResponse(c, *job, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Patch})
 }
 
+// validateUpdate responds with the validation of the payload a job would have after an update.
+// The stored payload has already been sanitized, so its code blocks are read as they are.
+func (j *JobController) validateUpdate(c *gin.Context, job models.Job, jobUpdate models.JobUpdateRequest) {
+	if jobUpdate.Payload != nil {
+		res, err := compile.Validate(c.Request.Context(), j.compiler, *jobUpdate.Payload)
+		j.respondValidation(c, res, err)
+		return
+	}
+
+	parser := &utils.Parser{}
+	if err := parser.ParseSanitized(job.Payload); err != nil {
+		j.respondValidation(c, models.PayloadValidation{}, err)
+		return
+	}
+
+	res, err := compile.ValidateBlocks(c.Request.Context(), j.compiler, parser.Result)
+	j.respondValidation(c, res, err)
+}
+
+// ValidatePayload godoc
+// @Summary Validate a job payload
+// @Description parses a job payload, checks that its code blocks are in supported languages and compile-checks each of them on a worker, without saving anything. Diagnostics give the 1-based block and line they refer to.
+// @Tags jobs
+// @Security ApiKey
+// @Param request body models.PayloadValidationRequest true "payload to validate"
+// @Success 200 {object} httputil.HTTPResponse[models.PayloadValidation]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Failure 503 {object} httputil.HTTPError
+// @Router /jobs/validate [post]
+func (j *JobController) ValidatePayload(c *gin.Context) {
+	var req models.PayloadValidationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	res, err := compile.Validate(c.Request.Context(), j.compiler, req.Payload)
+	j.respondValidation(c, res, err)
+}
+
+func (j *JobController) respondValidation(c *gin.Context, res models.PayloadValidation, err error) {
+	if err != nil {
+		j.Logger.WithContext(c.Request.Context()).Error("unable to validate job payload", &err)
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to validate payload: %w", err))
+		return
+	}
+
+	httputil.NewResponse(c, res, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
 // DeleteJob godoc
 // @Summary Delete a job
-// @Description removes an existing job
+// @Description removes an existing job. Requires the admin role on the job.
 // @Tags jobs
 // @Security ApiKey
 // @Success 200 {object} httputil.HTTPResponse[string]
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
 // @Failure 500 {object} httputil.HTTPError
 // @Router /jobs/:id [delete]
 func (j *JobController) DeleteJob(c *gin.Context) {
+	principal := httputil.GetPrincipal(c)
 	jobId := httputil.GetId(c)
 
-	if err := j.repo.DeleteJob(jobId); err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+	job, err := j.repo.WithContext(c.Request.Context()).DeleteJob(jobId, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
 		return
 	}
 
+	j.publishEvent(c.Request.Context(), models.JobEvent{Type: models.EventJobDeleted, UserID: job.UserID, JobID: job.JobID, Status: job.Status})
+	j.audit(c, models.AuditActionDelete, models.AuditResourceJob, jobId, job, nil)
+
 	httputil.NewResponse(c, jobId, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Delete})
 }
+
+// RunJob godoc
+// @Summary Run a job now
+// @Description schedules a job to run at the scheduler's next poll. Requires the operator role on the job.
+// @Tags jobs
+// @Security ApiKey
+// @Success 202 {object} httputil.HTTPResponse[models.JobSchedule]
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /jobs/:id/run [post]
+func (j *JobController) RunJob(c *gin.Context) {
+	principal := httputil.GetPrincipal(c)
+	jobId := httputil.GetId(c)
+
+	schedule, err := j.repo.WithContext(c.Request.Context()).RunJob(jobId, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to run job %s: %w", jobId, err))
+		return
+	}
+
+	j.audit(c, models.AuditActionTrigger, models.AuditResourceJob, jobId, nil, schedule)
+
+	httputil.NewResponse(c, *schedule, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get, Status: http.StatusAccepted})
+}
@@ -1,9 +1,14 @@
 package controller
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/events"
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
 	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
 	"github.com/julianstephens/distributed-job-manager/pkg/models"
@@ -13,32 +18,59 @@ import (
 
 type ExecutionController struct {
 	Controller
-	repo *repository.ExecutionRepository
+	repo    *repository.ExecutionRepository
+	jobRepo *repository.JobRepository
+	quotas  *repository.QuotaRepository
 }
 
-func NewExecutionController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger) *ExecutionController {
+func NewExecutionController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger, bus *events.Bus) *ExecutionController {
 	return &ExecutionController{
 		Controller: Controller{
 			DB:     db,
 			Config: config,
 			Logger: logger,
+			Events: bus,
 		},
-		repo: repository.NewExecutionRepository(db, logger),
+		repo:    repository.NewExecutionRepository(db, logger),
+		jobRepo: repository.NewJobRepository(db, logger),
+		quotas:  repository.NewQuotaRepository(db, logger, config.Quota),
 	}
 }
 
 func (e *ExecutionController) CreateExecution(c *gin.Context) {
 	var req models.JobExecution
 	if err := c.ShouldBindJSON(&req); err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		httputil.HandleFieldError(c, err)
 		return
 	}
 
-	exec, err := e.repo.CreateExecution(req)
+	principal := httputil.GetPrincipal(c)
+
+	// quotas are charged to the job's owner, not to the worker registering the execution
+	job, err := e.jobRepo.WithContext(c.Request.Context()).GetJob(req.JobID, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	if err = e.quotas.WithContext(c.Request.Context()).CheckExecutionQuota(job.UserID); err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	exec, err := e.repo.WithContext(c.Request.Context()).CreateExecution(req, principal)
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		httputil.NewError(c, errorStatus(err), err)
+		return
 	}
 
+	if err = e.quotas.WithContext(c.Request.Context()).RecordExecution(job.UserID); err != nil {
+		e.Logger.Error(fmt.Sprintf("failed to record execution %s against the quota of user %s", exec.ExecutionID, job.UserID), &err)
+	}
+
+	e.publishExecutionEvent(c.Request.Context(), models.EventExecutionCreated, *exec)
+	e.audit(c, models.AuditActionCreate, models.AuditResourceExecution, exec.ExecutionID, nil, exec)
+
 	httputil.NewResponse(c, *exec, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Post})
 }
 
@@ -47,15 +79,255 @@ func (e *ExecutionController) UpdateExecution(c *gin.Context) {
 
 	var req models.JobExecutionUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		httputil.HandleFieldError(c, err)
 		return
 	}
 
-	exec, err := e.repo.UpdateExecution(req, id)
+	principal := httputil.GetPrincipal(c)
+
+	before, err := e.repo.WithContext(c.Request.Context()).GetExecution(id, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	exec, err := e.repo.WithContext(c.Request.Context()).UpdateExecution(req, id, principal)
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		httputil.NewError(c, errorStatus(err), err)
 		return
 	}
 
+	// workers report the end time once, when the execution finishes
+	if req.EndTime != nil && !exec.StartTime.IsZero() && exec.EndTime.After(exec.StartTime) {
+		e.recordSandboxTime(c.Request.Context(), *exec, principal)
+	}
+
+	if req.Status != nil {
+		e.publishExecutionEvent(c.Request.Context(), models.EventExecutionStatusChanged, *exec)
+	}
+	e.audit(c, models.AuditActionUpdate, models.AuditResourceExecution, id, before, exec)
+
+	switch followUp(before.Status, exec.Status) {
+	case followUpRetry:
+		e.retryJob(c.Request.Context(), *exec)
+	case followUpComplete:
+		e.completeJob(c.Request.Context(), *exec)
+	}
+
 	httputil.NewResponse(c, *exec, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Patch})
 }
+
+// jobFollowUp is what an execution's change of status does to its job.
+type jobFollowUp int
+
+const (
+	followUpNone jobFollowUp = iota
+	// followUpRetry retries or fails the job of an execution that failed.
+	followUpRetry
+	// followUpComplete completes the job of an execution that completed, or schedules its next run.
+	followUpComplete
+)
+
+// followUp returns what an execution going from status before to after does to its job.
+func followUp(before string, after string) jobFollowUp {
+	if before == after {
+		return followUpNone
+	}
+
+	switch after {
+	case models.JobStatusFailed:
+		return followUpRetry
+	case models.JobStatusCompleted:
+		return followUpComplete
+	}
+	return followUpNone
+}
+
+func (e *ExecutionController) AppendOutput(c *gin.Context) {
+	id := httputil.GetId(c)
+
+	var chunk models.ExecutionOutputChunk
+	if err := c.ShouldBindJSON(&chunk); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+	chunk.ExecutionID = id
+
+	if err := e.repo.WithContext(c.Request.Context()).AppendOutput(chunk, httputil.GetPrincipal(c)); err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	if err := e.Events.PublishOutput(c.Request.Context(), chunk); err != nil {
+		e.Logger.Error(fmt.Sprintf("failed to publish output chunk %d of execution %s", chunk.Seq, id), &err)
+	}
+
+	httputil.NewResponse(c, chunk, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Post})
+}
+
+// recordSandboxTime counts the wall-time of a finished execution against its job owner's quota.
+// Failures are logged rather than returned since the execution has already been updated.
+func (e *ExecutionController) recordSandboxTime(ctx context.Context, exec models.JobExecution, principal models.Principal) {
+	job, err := e.jobRepo.WithContext(ctx).GetJob(exec.JobID, principal)
+	if err == nil {
+		err = e.quotas.WithContext(ctx).RecordSandboxTime(job.UserID, exec.EndTime.Sub(exec.StartTime))
+	}
+	if err != nil {
+		e.Logger.Error(fmt.Sprintf("failed to record sandbox time of execution %s", exec.ExecutionID), &err)
+	}
+}
+
+// GetJobExecutions godoc
+// @Summary List job executions
+// @Description lists the executions of a job, most recently started first
+// @Tags executions
+// @Security ApiKey
+// @Produce json
+// @Param id path string true "job id"
+// @Success 200 {object} httputil.HTTPResponse[[]models.JobExecution]
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /jobs/:id/executions [get]
+func (e *ExecutionController) GetJobExecutions(c *gin.Context) {
+	id := httputil.GetId(c)
+
+	executions, err := e.repo.WithContext(c.Request.Context()).GetJobExecutions(id, httputil.GetPrincipal(c))
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to get executions of job %s: %w", id, err))
+		return
+	}
+
+	httputil.NewResponse(c, *executions, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
+// GetExecutionLogs godoc
+// @Summary Get execution output
+// @Description writes an execution's output as plain text. With follow=true the response stays open and streams new output until the execution finishes.
+// @Tags executions
+// @Security ApiKey
+// @Produce plain
+// @Param id path string true "execution id"
+// @Param follow query bool false "stream output until the execution finishes"
+// @Success 200 {string} string
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /executions/:id/logs [get]
+func (e *ExecutionController) GetExecutionLogs(c *gin.Context) {
+	principal := httputil.GetPrincipal(c)
+	id := httputil.GetId(c)
+	follow, _ := strconv.ParseBool(c.Query("follow"))
+
+	exec, err := e.repo.WithContext(c.Request.Context()).GetExecution(id, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to get execution %s: %w", id, err))
+		return
+	}
+
+	follow = follow && !models.IsTerminalStatus(exec.Status)
+
+	// subscribe before reading stored output so no chunk published in between is missed
+	var output *events.Subscription[models.ExecutionOutputChunk]
+	var finished *events.Subscription[models.JobEvent]
+	if follow {
+		output = e.Events.SubscribeOutput(id)
+		defer output.Close()
+		finished = e.Events.Subscribe(func(event models.JobEvent) bool {
+			return event.ExecutionID == id && models.IsTerminalStatus(event.Status)
+		})
+		defer finished.Close()
+	}
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	lastSeq := -1
+	writeStored := func(w io.Writer) bool {
+		chunks, err := e.repo.WithContext(c.Request.Context()).GetOutput(id, lastSeq)
+		if err != nil {
+			return false
+		}
+		for _, chunk := range *chunks {
+			if _, err = io.WriteString(w, chunk.Data); err != nil {
+				return false
+			}
+			lastSeq = chunk.Seq
+		}
+		c.Writer.Flush()
+		return true
+	}
+
+	if !writeStored(c.Writer) || !follow {
+		return
+	}
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-finished.C:
+			// output published just before the final status may still be in flight
+			writeStored(w)
+			return false
+		case chunk, ok := <-output.C:
+			if !ok {
+				return false
+			}
+			if chunk.Seq <= lastSeq {
+				return true
+			}
+			if chunk.Seq > lastSeq+1 {
+				return writeStored(w)
+			}
+			if _, err := io.WriteString(w, chunk.Data); err != nil {
+				return false
+			}
+			lastSeq = chunk.Seq
+			return true
+		}
+	})
+}
+
+// retryJob applies the retry policy of the job of a failed execution. Failures are logged rather
+// than returned since the execution has already been updated.
+func (e *ExecutionController) retryJob(ctx context.Context, exec models.JobExecution) {
+	job, changed, err := e.jobRepo.WithContext(ctx).RetryJob(exec.JobID, e.Config.Retry)
+	if err != nil {
+		e.Logger.Error(fmt.Sprintf("failed to apply retry policy of job %s after execution %s failed", exec.JobID, exec.ExecutionID), &err)
+		return
+	}
+	if changed {
+		e.publishEvent(ctx, models.JobEvent{Type: models.EventJobStatusChanged, UserID: job.UserID, JobID: job.JobID, Status: job.Status})
+	}
+}
+
+// completeJob schedules the next run of the job of a completed execution, or completes a one-time
+// job. Failures are logged rather than returned since the execution has already been updated.
+func (e *ExecutionController) completeJob(ctx context.Context, exec models.JobExecution) {
+	job, changed, err := e.jobRepo.WithContext(ctx).CompleteJob(exec.JobID)
+	if err != nil {
+		e.Logger.Error(fmt.Sprintf("failed to move job %s on after execution %s completed", exec.JobID, exec.ExecutionID), &err)
+		return
+	}
+	if changed {
+		e.publishEvent(ctx, models.JobEvent{Type: models.EventJobStatusChanged, UserID: job.UserID, JobID: job.JobID, Status: job.Status})
+	}
+}
+
+// publishExecutionEvent broadcasts an execution transition to the owner of its job.
+func (e *ExecutionController) publishExecutionEvent(ctx context.Context, eventType string, exec models.JobExecution) {
+	job, err := e.jobRepo.WithContext(ctx).GetJob(exec.JobID, models.SystemPrincipal)
+	if err != nil {
+		e.Logger.Error(fmt.Sprintf("unable to resolve owner of execution %s for %s event", exec.ExecutionID, eventType), &err)
+		return
+	}
+
+	e.publishEvent(ctx, models.JobEvent{
+		Type:        eventType,
+		UserID:      job.UserID,
+		JobID:       exec.JobID,
+		ExecutionID: exec.ExecutionID,
+		Status:      exec.Status,
+	})
+}
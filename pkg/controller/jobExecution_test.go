@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+func TestFollowUp(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   jobFollowUp
+	}{
+		{"run failed", models.JobStatusInProgress, models.JobStatusFailed, followUpRetry},
+		{"rejected before running", models.JobStatusScheduled, models.JobStatusFailed, followUpRetry},
+		{"run completed", models.JobStatusInProgress, models.JobStatusCompleted, followUpComplete},
+		{"failure reported again", models.JobStatusFailed, models.JobStatusFailed, followUpNone},
+		{"completion reported again", models.JobStatusCompleted, models.JobStatusCompleted, followUpNone},
+		{"started", models.JobStatusScheduled, models.JobStatusInProgress, followUpNone},
+		{"abandoned", models.JobStatusScheduled, models.JobStatusCancelled, followUpNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := followUp(tt.before, tt.after); got != tt.want {
+				t.Errorf("followUp(%s, %s) = %d, want %d", tt.before, tt.after, got, tt.want)
+			}
+		})
+	}
+}
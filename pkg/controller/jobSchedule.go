@@ -1,8 +1,6 @@
 package controller
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
 	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
@@ -28,23 +26,31 @@ func NewScheduleController(db *store.DBSession, conf *models.Config, log *graylo
 }
 
 func (s *ScheduleController) GetSchedules(c *gin.Context) {
+	page, ok := httputil.GetPage(c)
+	if !ok {
+		return
+	}
+
 	queryParams := c.Request.URL.Query()
+	for _, param := range models.PageQueryParams {
+		queryParams.Del(param)
+	}
 
-	schedules, err := s.repo.GetSchedules(queryParams)
+	schedules, nextToken, err := s.repo.WithContext(c.Request.Context()).GetSchedules(queryParams, page, httputil.GetPrincipal(c))
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		httputil.NewError(c, errorStatus(err), err)
 		return
 	}
 
-	httputil.NewResponse(c, *schedules, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+	httputil.NewResponse(c, *schedules, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get, NextPageToken: nextToken})
 }
 
 func (s *ScheduleController) GetSchedule(c *gin.Context) {
 	id := httputil.GetId(c)
 
-	schedule, err := s.repo.GetSchedule(id)
+	schedule, err := s.repo.WithContext(c.Request.Context()).GetSchedule(id, httputil.GetPrincipal(c))
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		httputil.NewError(c, errorStatus(err), err)
 		return
 	}
 
@@ -54,16 +60,18 @@ func (s *ScheduleController) GetSchedule(c *gin.Context) {
 func (s *ScheduleController) CreateSchedule(c *gin.Context) {
 	var req models.JobSchedule
 	if err := c.ShouldBindJSON(&req); err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		httputil.HandleFieldError(c, err)
 		return
 	}
 
-	schedule, err := s.repo.CreateSchedule(req)
+	schedule, err := s.repo.WithContext(c.Request.Context()).CreateSchedule(req, httputil.GetPrincipal(c))
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		httputil.NewError(c, errorStatus(err), err)
 		return
 	}
 
+	s.audit(c, models.AuditActionCreate, models.AuditResourceSchedule, schedule.JobID, nil, schedule)
+
 	httputil.NewResponse(c, *schedule, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Post})
 }
 
@@ -72,26 +80,46 @@ func (s *ScheduleController) UpdateSchedule(c *gin.Context) {
 
 	var req models.JobScheduleUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	principal := httputil.GetPrincipal(c)
+
+	before, err := s.repo.WithContext(c.Request.Context()).GetSchedule(id, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
 		return
 	}
 
-	schedule, err := s.repo.UpdateSchedule(id, req)
+	schedule, err := s.repo.WithContext(c.Request.Context()).UpdateSchedule(id, req, principal)
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		httputil.NewError(c, errorStatus(err), err)
 		return
 	}
 
+	s.audit(c, models.AuditActionUpdate, models.AuditResourceSchedule, id, before, schedule)
+
 	httputil.NewResponse(c, *schedule, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Patch})
 }
 
 func (s *ScheduleController) DeleteSchedule(c *gin.Context) {
 	id := httputil.GetId(c)
 
-	if err := s.repo.DeleteSchedule(id); err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+	principal := httputil.GetPrincipal(c)
+
+	before, err := s.repo.WithContext(c.Request.Context()).GetSchedule(id, principal)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
 		return
 	}
 
+	if err := s.repo.WithContext(c.Request.Context()).DeleteSchedule(id, principal); err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	s.audit(c, models.AuditActionDelete, models.AuditResourceSchedule, id, before, nil)
+
 	httputil.NewResponse(c, id, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Delete})
 }
@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/events"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
+	"github.com/julianstephens/distributed-job-manager/pkg/manifests"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+)
+
+type ManifestController struct {
+	Controller
+	repo   *repository.JobRepository
+	quotas *repository.QuotaRepository
+}
+
+func NewManifestController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger, bus *events.Bus) *ManifestController {
+	return &ManifestController{
+		Controller: Controller{
+			DB:     db,
+			Config: config,
+			Logger: logger,
+			Events: bus,
+		},
+		repo:   repository.NewJobRepository(db, logger),
+		quotas: repository.NewQuotaRepository(db, logger, config.Quota),
+	}
+}
+
+// ExportManifests godoc
+// @Summary Export job manifests
+// @Description exports the user's jobs as manifests. With format=yaml the manifests are written as a bare YAML document that can be applied as is.
+// @Tags manifests
+// @Security ApiKey
+// @Produce json,yaml
+// @Param format query string false "json or yaml"
+// @Success 200 {object} httputil.HTTPResponse[models.ManifestSet]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /manifests [get]
+func (m *ManifestController) ExportManifests(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "yaml" {
+		httputil.NewError(c, http.StatusBadRequest, fmt.Errorf("unsupported manifest format %q", format))
+		return
+	}
+
+	userId := httputil.GetUserId(c)
+	jobs, err := m.repo.WithContext(c.Request.Context()).GetOwnedJobs(userId)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to export jobs of user %s: %w", userId, err))
+		return
+	}
+
+	set := models.ManifestSet{Manifests: make([]models.JobManifest, len(*jobs))}
+	for i, job := range *jobs {
+		set.Manifests[i] = manifests.FromJob(job)
+	}
+
+	if format == "yaml" {
+		c.YAML(http.StatusOK, set)
+		return
+	}
+
+	httputil.NewResponse(c, set, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
+// ApplyManifests godoc
+// @Summary Apply job manifests
+// @Description makes the user's jobs match a set of manifests, given as JSON or YAML. Jobs are matched to manifests by name: missing jobs are created, differing ones updated and, with prune, jobs no manifest names are deleted. With dry_run=true the changes are only planned. Changes are applied in order and applying stops at the first that fails.
+// @Tags manifests
+// @Security ApiKey
+// @Accept json,yaml
+// @Param dry_run query bool false "plan the changes without applying them"
+// @Param request body models.ManifestApplyRequest true "manifests to apply"
+// @Success 200 {object} httputil.HTTPResponse[models.ManifestPlan]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /manifests/apply [post]
+func (m *ManifestController) ApplyManifests(c *gin.Context) {
+	principal := httputil.GetPrincipal(c)
+	repo := m.repo.WithContext(c.Request.Context())
+
+	var req models.ManifestApplyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	existing, err := repo.GetOwnedJobs(principal.UserID)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to plan manifests: %w", err))
+		return
+	}
+
+	steps, err := manifests.Plan(req.Manifests, *existing, req.Prune)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to plan manifests: %w", err))
+		return
+	}
+
+	if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+		httputil.NewResponse(c, models.ManifestPlan{Changes: manifests.Changes(steps)}, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+		return
+	}
+
+	current := make(map[string]models.Job, len(*existing))
+	for _, job := range *existing {
+		current[job.JobID] = job
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		if err := m.applyStep(c, step, current[step.Change.JobID]); err != nil {
+			httputil.NewError(c, errorStatus(err), fmt.Errorf("applied %d of %d manifest changes, unable to %s %q: %w", i, len(steps), step.Change.Action, step.Change.Name, err))
+			return
+		}
+	}
+
+	httputil.NewResponse(c, models.ManifestPlan{Applied: true, Changes: manifests.Changes(steps)}, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
+// applyStep makes a planned change, recording the ID of a created job in its change.
+func (m *ManifestController) applyStep(c *gin.Context, step *manifests.Step, before models.Job) error {
+	principal := httputil.GetPrincipal(c)
+	repo := m.repo.WithContext(c.Request.Context())
+
+	switch step.Change.Action {
+	case models.ManifestActionCreate:
+		if !principal.IsAdmin {
+			if err := m.quotas.WithContext(c.Request.Context()).CheckJobQuota(principal.UserID); err != nil {
+				return err
+			}
+		}
+
+		job, err := repo.CreateJob(step.Job, principal)
+		if err != nil {
+			return err
+		}
+		step.Change.JobID = job.JobID
+
+		m.publishEvent(c.Request.Context(), models.JobEvent{Type: models.EventJobCreated, UserID: job.UserID, JobID: job.JobID, Status: job.Status})
+		m.audit(c, models.AuditActionCreate, models.AuditResourceJob, job.JobID, nil, job)
+	case models.ManifestActionUpdate:
+		job, err := repo.UpdateJob(step.Update, step.Change.JobID, principal)
+		if err != nil {
+			return err
+		}
+
+		m.audit(c, models.AuditActionUpdate, models.AuditResourceJob, job.JobID, before, job)
+	case models.ManifestActionDelete:
+		job, err := repo.DeleteJob(step.Change.JobID, principal)
+		if err != nil {
+			return err
+		}
+
+		m.publishEvent(c.Request.Context(), models.JobEvent{Type: models.EventJobDeleted, UserID: job.UserID, JobID: job.JobID, Status: job.Status})
+		m.audit(c, models.AuditActionDelete, models.AuditResourceJob, job.JobID, job, nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+)
+
+type UsageController struct {
+	Controller
+	repo *repository.QuotaRepository
+}
+
+func NewUsageController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger) *UsageController {
+	return &UsageController{
+		Controller: Controller{
+			DB:     db,
+			Config: config,
+			Logger: logger,
+		},
+		repo: repository.NewQuotaRepository(db, logger, config.Quota),
+	}
+}
+
+// GetUsage godoc
+// @Summary Get quota usage
+// @Description retrieves the user's current usage against each of their quotas. Admins may get the usage of another user with user_id.
+// @Tags usage
+// @Security ApiKey
+// @Param user_id query string false "user to get the usage of"
+// @Success 200 {object} httputil.HTTPResponse[models.Usage]
+// @Failure 403 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /usage [get]
+func (u *UsageController) GetUsage(c *gin.Context) {
+	userId := httputil.GetUserId(c)
+
+	if requested := c.Query("user_id"); requested != "" && requested != userId {
+		if !c.GetBool("isAdmin") {
+			httputil.NewError(c, http.StatusForbidden, errors.New("only admins can get the usage of other users"))
+			return
+		}
+		userId = requested
+	}
+
+	usage, err := u.repo.WithContext(c.Request.Context()).GetUsage(userId)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to get usage of user %s: %w", userId, err))
+		return
+	}
+
+	httputil.NewResponse(c, *usage, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
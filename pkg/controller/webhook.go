@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/julianstephens/distributed-job-manager/pkg/utils"
+	"github.com/julianstephens/distributed-job-manager/pkg/webhooks"
+)
+
+type WebhookController struct {
+	Controller
+	repo       *repository.WebhookRepository
+	jobRepo    *repository.JobRepository
+	dispatcher *webhooks.Dispatcher
+}
+
+func NewWebhookController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger, dispatcher *webhooks.Dispatcher) *WebhookController {
+	return &WebhookController{
+		Controller: Controller{
+			DB:     db,
+			Config: config,
+			Logger: logger,
+		},
+		repo:       repository.NewWebhookRepository(db, logger),
+		jobRepo:    repository.NewJobRepository(db, logger),
+		dispatcher: dispatcher,
+	}
+}
+
+// GetWebhooks godoc
+// @Summary Get all webhooks
+// @Description retrieves the user's webhooks, or every webhook for admins. Signing secrets are omitted.
+// @Tags webhooks
+// @Security ApiKey
+// @Success 200 {object} httputil.HTTPResponse[[]models.Webhook]
+// @Failure 500 {object} httputil.HTTPError
+// @Router /webhooks [get]
+func (w *WebhookController) GetWebhooks(c *gin.Context) {
+	userId := httputil.GetUserId(c)
+	isAdmin := c.GetBool("isAdmin")
+
+	hooks, err := w.repo.WithContext(c.Request.Context()).GetWebhooks(userId, isAdmin)
+	if err != nil {
+		httputil.NewError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	for i := range *hooks {
+		(*hooks)[i].Secret = ""
+	}
+
+	httputil.NewResponse(c, *hooks, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
+// GetWebhook godoc
+// @Summary Get a specific webhook
+// @Description retrieves a single webhook. The signing secret is omitted.
+// @Tags webhooks
+// @Security ApiKey
+// @Success 200 {object} httputil.HTTPResponse[models.Webhook]
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /webhooks/:id [get]
+func (w *WebhookController) GetWebhook(c *gin.Context) {
+	hook, ok := w.getWebhook(c)
+	if !ok {
+		return
+	}
+
+	hook.Secret = ""
+
+	httputil.NewResponse(c, *hook, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
+// CreateWebhook godoc
+// @Summary Create a webhook
+// @Description subscribes a URL to the events of a job, of the jobs matching a label selector, or of all the user's jobs.
+// @Description The URL must resolve to public addresses, and redirects are not followed.
+// @Description The response contains the secret used to sign deliveries, which is not returned again.
+// @Tags webhooks
+// @Security ApiKey
+// @Success 201 {object} httputil.HTTPResponse[models.Webhook]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /webhooks [post]
+func (w *WebhookController) CreateWebhook(c *gin.Context) {
+	userId := httputil.GetUserId(c)
+	isAdmin := c.GetBool("isAdmin")
+
+	var hook models.Webhook
+	if err := c.ShouldBindJSON(&hook); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	if err := w.dispatcher.CheckURL(c.Request.Context(), hook.URL); err != nil {
+		httputil.NewError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// events are only delivered to the webhooks of a job's owner, so an admin's webhook on another
+	// user's job belongs to that user
+	owner := userId
+	if hook.JobID != "" {
+		job, err := w.jobRepo.WithContext(c.Request.Context()).GetJob(hook.JobID, httputil.GetPrincipal(c))
+		if err != nil || (job.UserID != userId && !isAdmin) {
+			httputil.NewError(c, http.StatusBadRequest, fmt.Errorf("unable to get job %s", hook.JobID))
+			return
+		}
+		owner = job.UserID
+	}
+
+	res, err := w.repo.WithContext(c.Request.Context()).CreateWebhook(hook, owner)
+	if err != nil {
+		httputil.NewError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.audit(c, models.AuditActionCreate, models.AuditResourceWebhook, res.WebhookID, nil, withoutSecret(*res))
+
+	httputil.NewResponse(c, *res, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Post})
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description removes an existing webhook
+// @Tags webhooks
+// @Security ApiKey
+// @Success 200 {object} httputil.HTTPResponse[string]
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /webhooks/:id [delete]
+func (w *WebhookController) DeleteWebhook(c *gin.Context) {
+	userId := httputil.GetUserId(c)
+	webhookId := httputil.GetId(c)
+	isAdmin := c.GetBool("isAdmin")
+
+	before, ok := w.getWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := w.repo.WithContext(c.Request.Context()).DeleteWebhook(webhookId, userId, isAdmin); err != nil {
+		httputil.NewError(c, utils.If(errors.Is(err, repository.ErrWebhookNotFound), http.StatusNotFound, http.StatusInternalServerError), err)
+		return
+	}
+
+	w.audit(c, models.AuditActionDelete, models.AuditResourceWebhook, webhookId, withoutSecret(*before), nil)
+
+	httputil.NewResponse(c, webhookId, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Delete})
+}
+
+// GetDeliveries godoc
+// @Summary Get webhook deliveries
+// @Description retrieves a page of a webhook's delivery log, newest first
+// @Tags webhooks
+// @Security ApiKey
+// @Param limit query int false "page size"
+// @Param page_token query string false "token from a previous page"
+// @Success 200 {object} httputil.HTTPResponse[[]models.WebhookDelivery]
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /webhooks/:id/deliveries [get]
+func (w *WebhookController) GetDeliveries(c *gin.Context) {
+	hook, ok := w.getWebhook(c)
+	if !ok {
+		return
+	}
+
+	page, ok := httputil.GetPage(c)
+	if !ok {
+		return
+	}
+
+	deliveries, nextToken, err := w.repo.WithContext(c.Request.Context()).GetDeliveries(hook.WebhookID, page)
+	if err != nil {
+		httputil.NewError(c, utils.If(errors.Is(err, repository.ErrInvalidPageToken), http.StatusBadRequest, http.StatusInternalServerError), err)
+		return
+	}
+
+	httputil.NewResponse(c, *deliveries, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get, NextPageToken: nextToken})
+}
+
+// Redeliver godoc
+// @Summary Redeliver a webhook event
+// @Description sends the payload of a previous delivery again as a new delivery, signed with the webhook's current secret
+// @Tags webhooks
+// @Security ApiKey
+// @Success 202 {object} httputil.HTTPResponse[models.WebhookDelivery]
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Failure 503 {object} httputil.HTTPError
+// @Router /webhooks/:id/deliveries/:deliveryId/redeliver [post]
+func (w *WebhookController) Redeliver(c *gin.Context) {
+	hook, ok := w.getWebhook(c)
+	if !ok {
+		return
+	}
+
+	deliveryId := c.Param("deliveryId")
+
+	previous, err := w.repo.WithContext(c.Request.Context()).GetDelivery(hook.WebhookID, deliveryId)
+	if err != nil {
+		httputil.NewError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// the delivery outlives the request, so it must not be cancelled with it
+	delivery, err := w.dispatcher.Redeliver(context.WithoutCancel(c.Request.Context()), *hook, *previous)
+	if err != nil {
+		httputil.NewError(c, utils.If(errors.Is(err, webhooks.ErrBusy), http.StatusServiceUnavailable, http.StatusInternalServerError), err)
+		return
+	}
+
+	w.audit(c, models.AuditActionTrigger, models.AuditResourceWebhook, hook.WebhookID, nil, delivery)
+
+	httputil.NewResponse(c, delivery, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get, Status: http.StatusAccepted})
+}
+
+// getWebhook loads the webhook named by the 'id' path param, writing the error response if it
+// cannot be found.
+func (w *WebhookController) getWebhook(c *gin.Context) (*models.Webhook, bool) {
+	userId := httputil.GetUserId(c)
+	webhookId := httputil.GetId(c)
+	isAdmin := c.GetBool("isAdmin")
+
+	hook, err := w.repo.WithContext(c.Request.Context()).GetWebhook(webhookId, userId, isAdmin)
+	if err != nil {
+		httputil.NewError(c, utils.If(errors.Is(err, repository.ErrWebhookNotFound), http.StatusNotFound, http.StatusInternalServerError), err)
+		return nil, false
+	}
+
+	return hook, true
+}
+
+// withoutSecret returns a copy of a webhook that is safe to record in the audit log.
+func withoutSecret(hook models.Webhook) models.Webhook {
+	hook.Secret = ""
+	return hook
+}
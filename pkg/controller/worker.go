@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+)
+
+type WorkerController struct {
+	Controller
+	repo *repository.WorkerRepository
+}
+
+func NewWorkerController(db *store.DBSession, config *models.Config, logger *graylogger.GrayLogger) *WorkerController {
+	return &WorkerController{
+		Controller: Controller{
+			DB:     db,
+			Config: config,
+			Logger: logger,
+		},
+		repo: repository.NewWorkerRepository(db, logger),
+	}
+}
+
+// GetWorkers godoc
+// @Summary Get all workers
+// @Description lists registered workers by ID along with their health, which is stale or dead once their heartbeats stop arriving
+// @Tags workers
+// @Security ApiKey
+// @Produce json
+// @Param health query string false "only workers in this health: healthy, stale or dead"
+// @Success 200 {object} httputil.HTTPResponse[[]models.WorkerNode]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /workers [get]
+func (w *WorkerController) GetWorkers(c *gin.Context) {
+	health := c.Query("health")
+	if health != "" && health != models.WorkerHealthHealthy && health != models.WorkerHealthStale && health != models.WorkerHealthDead {
+		httputil.NewError(c, http.StatusBadRequest, fmt.Errorf("unknown worker health %q", health))
+		return
+	}
+
+	workers, err := w.repo.WithContext(c.Request.Context()).GetWorkers()
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+
+	now := time.Now()
+	res := make([]models.WorkerNode, 0, len(*workers))
+	for _, worker := range *workers {
+		worker.Health = w.Config.Worker.WorkerHealth(worker.LastHeartbeat, now)
+		if health == "" || worker.Health == health {
+			res = append(res, worker)
+		}
+	}
+	slices.SortFunc(res, func(a, b models.WorkerNode) int {
+		return cmp.Compare(a.WorkerID, b.WorkerID)
+	})
+
+	httputil.NewResponse(c, res, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
+// GetWorker godoc
+// @Summary Get a worker
+// @Description retrieves a registered worker along with its health
+// @Tags workers
+// @Security ApiKey
+// @Produce json
+// @Param id path string true "worker id"
+// @Success 200 {object} httputil.HTTPResponse[models.WorkerNode]
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /workers/:id [get]
+func (w *WorkerController) GetWorker(c *gin.Context) {
+	id := httputil.GetId(c)
+
+	worker, err := w.repo.WithContext(c.Request.Context()).GetWorker(id)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to get worker %s: %w", id, err))
+		return
+	}
+	worker.Health = w.Config.Worker.WorkerHealth(worker.LastHeartbeat, time.Now())
+
+	httputil.NewResponse(c, *worker, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
+
+// RegisterWorker godoc
+// @Summary Register a worker
+// @Description records a worker that started, replacing the registration of an earlier run with the same ID
+// @Tags workers
+// @Security ApiKey
+// @Accept json
+// @Produce json
+// @Param id path string true "worker id"
+// @Param registration body models.WorkerRegistration true "what the worker runs"
+// @Success 200 {object} httputil.HTTPResponse[models.WorkerNode]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /workers/:id [put]
+func (w *WorkerController) RegisterWorker(c *gin.Context) {
+	id := httputil.GetId(c)
+
+	var req models.WorkerRegistration
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	worker, err := w.repo.WithContext(c.Request.Context()).RegisterWorker(id, req)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), err)
+		return
+	}
+	worker.Health = models.WorkerHealthHealthy
+
+	w.Logger.WithContext(c.Request.Context()).Info(fmt.Sprintf("worker %s registered from %s with %d sandboxes", id, req.Host, req.Capacity), nil)
+
+	httputil.NewResponse(c, *worker, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Put})
+}
+
+// WorkerHeartbeat godoc
+// @Summary Send a worker heartbeat
+// @Description records that a worker is alive and how loaded it is. Unregistered workers get a 404 and should register again.
+// @Tags workers
+// @Security ApiKey
+// @Accept json
+// @Produce json
+// @Param id path string true "worker id"
+// @Param heartbeat body models.WorkerHeartbeat true "current load"
+// @Success 200 {object} httputil.HTTPResponse[models.WorkerNode]
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /workers/:id/heartbeat [post]
+func (w *WorkerController) WorkerHeartbeat(c *gin.Context) {
+	id := httputil.GetId(c)
+
+	var req models.WorkerHeartbeat
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.HandleFieldError(c, err)
+		return
+	}
+
+	worker, err := w.repo.WithContext(c.Request.Context()).Heartbeat(id, req)
+	if err != nil {
+		httputil.NewError(c, errorStatus(err), fmt.Errorf("unable to record heartbeat of worker %s: %w", id, err))
+		return
+	}
+	worker.Health = w.Config.Worker.WorkerHealth(worker.LastHeartbeat, time.Now())
+
+	httputil.NewResponse(c, *worker, httputil.Options{IsCrudHandler: true, HttpMsgMethod: httputil.Get})
+}
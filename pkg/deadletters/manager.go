@@ -0,0 +1,154 @@
+// Package deadletters keeps the job messages the work queue gave up on.
+//
+// Workers send a job message to the dead-letter exchange, with the reason in a header, when it
+// cannot be decoded or was delivered too often without being processed; the broker does the same
+// for messages it drops. Every jobsvc replica consumes the shared dead-letter queue and stores
+// each message in Cassandra, where admins can inspect it and either replay it onto the work
+// queue or purge it.
+package deadletters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/queue"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// storeRetryDelay holds back a dead letter that could not be stored before it is consumed again.
+const storeRetryDelay = 5 * time.Second
+
+type Manager struct {
+	conf   *models.Config
+	conn   *amqp091.Connection
+	ch     *amqp091.Channel
+	pubMu  sync.Mutex
+	repo   *repository.DeadLetterRepository
+	logger *graylogger.GrayLogger
+}
+
+func NewManager(conf *models.Config, conn *amqp091.Connection, repo *repository.DeadLetterRepository, logger *graylogger.GrayLogger) (*Manager, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = queue.DeclareWorkQueue(ch, conf); err != nil {
+		return nil, err
+	}
+
+	return &Manager{
+		conf:   conf,
+		conn:   conn,
+		ch:     ch,
+		repo:   repo,
+		logger: logger,
+	}, nil
+}
+
+// Channel returns the channel dead letters are replayed on.
+func (m *Manager) Channel() *amqp091.Channel {
+	return m.ch
+}
+
+// Close closes the replay channel.
+func (m *Manager) Close() error {
+	return m.ch.Close()
+}
+
+// Run stores every message of the dead-letter queue until ctx is cancelled or the channel closes.
+func (m *Manager) Run(ctx context.Context) error {
+	ch, err := m.conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	msgs, err := ch.ConsumeWithContext(ctx, m.conf.Rabbit.DeadLetterQueue, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("unable to consume dead-letter queue: %w", err)
+	}
+
+	for msg := range msgs {
+		msgCtx, span := tracing.StartProcess(ctx, m.conf.Rabbit.DeadLetterQueue, msg)
+		err := m.store(msgCtx, msg)
+		span.End()
+
+		if err != nil {
+			time.Sleep(storeRetryDelay)
+			err = msg.Nack(false, true)
+		} else {
+			err = msg.Ack(false)
+		}
+		if err != nil {
+			m.logger.Error("failed to settle dead letter", &err)
+		}
+	}
+
+	return nil
+}
+
+// Replay publishes a dead-lettered job message to the work queue again and removes the dead
+// letter. The replayed message starts over with no deliveries.
+func (m *Manager) Replay(ctx context.Context, letter models.DeadLetter) error {
+	pubCtx, span, headers := tracing.StartPublish(ctx, m.conf.Rabbit.Name, nil)
+
+	m.pubMu.Lock()
+	err := m.ch.PublishWithContext(pubCtx, m.conf.Rabbit.Name, "", false, false, amqp091.Publishing{ContentType: "application/json", Headers: headers, Body: []byte(letter.Body)})
+	m.pubMu.Unlock()
+	span.End()
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("failed to replay dead letter %s", letter.DeadLetterID), &err)
+		return fmt.Errorf("unable to replay dead letter %s", letter.DeadLetterID)
+	}
+
+	return m.repo.WithContext(ctx).DeleteDeadLetter(letter.DeadLetterID)
+}
+
+func (m *Manager) store(ctx context.Context, msg amqp091.Delivery) error {
+	letter := models.DeadLetter{
+		Reason:     reason(msg.Headers),
+		Deliveries: queue.Deliveries(msg.Headers),
+		Body:       string(msg.Body),
+	}
+	letter.WorkerID, _ = msg.Headers[queue.HeaderWorkerID].(string)
+
+	// messages dead-lettered because they could not be decoded belong to no job
+	var job models.Job
+	if err := json.Unmarshal(msg.Body, &job); err == nil {
+		letter.JobID = job.JobID
+		letter.UserID = job.UserID
+	}
+
+	if _, err := m.repo.WithContext(ctx).CreateDeadLetter(letter); err != nil {
+		return err
+	}
+
+	m.logger.WithContext(ctx).Info(fmt.Sprintf("dead-lettered job %s: %s", letter.JobID, letter.Reason), nil)
+	return nil
+}
+
+// reason returns why a message was dead-lettered, either as a worker recorded it or as the broker
+// did in the x-death header.
+func reason(headers amqp091.Table) string {
+	if reason, ok := headers[queue.HeaderDeadLetterReason].(string); ok && reason != "" {
+		return reason
+	}
+
+	if deaths, ok := headers["x-death"].([]any); ok && len(deaths) > 0 {
+		if death, ok := deaths[0].(amqp091.Table); ok {
+			if reason, ok := death["reason"].(string); ok {
+				return "dropped by the broker: " + reason
+			}
+		}
+	}
+
+	return "unknown"
+}
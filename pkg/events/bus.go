@@ -0,0 +1,219 @@
+// Package events fans job and execution state transitions, and execution output, out to every
+// jobsvc replica.
+//
+// Publishing a job event stores it in Cassandra, so reconnecting subscribers can resume from a
+// last-event ID, and publishes it to a fanout exchange. Output chunks are stored by the caller
+// and only broadcast. Each replica binds its own exclusive queue to that exchange and hands
+// received messages to its local subscribers.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
+	"github.com/oklog/ulid/v2"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// SubscriptionBuffer is how many messages a subscriber may fall behind before it is dropped.
+const SubscriptionBuffer = 64
+
+// Message types set on everything published to the events exchange.
+const (
+	MessageTypeJobEvent        = "job_event"
+	MessageTypeExecutionOutput = "execution_output"
+)
+
+type Bus struct {
+	conf       *models.Config
+	ch         *amqp091.Channel
+	repo       *repository.EventRepository
+	logger     *graylogger.GrayLogger
+	pubMu      sync.Mutex
+	jobSubs    subscribers[models.JobEvent]
+	outputSubs subscribers[models.ExecutionOutputChunk]
+}
+
+// Subscription receives the messages accepted by its filter. C is closed when the subscription
+// is closed or when the subscriber falls too far behind.
+type Subscription[T any] struct {
+	C      <-chan T
+	c      chan T
+	filter func(T) bool
+	set    *subscribers[T]
+	once   sync.Once
+}
+
+type subscribers[T any] struct {
+	mu   sync.RWMutex
+	subs map[*Subscription[T]]struct{}
+}
+
+func NewBus(conf *models.Config, conn *amqp091.Connection, repo *repository.EventRepository, logger *graylogger.GrayLogger) (*Bus, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ch.ExchangeDeclare(conf.Rabbit.EventsExchange, amqp091.ExchangeFanout, true, false, false, false, nil); err != nil {
+		return nil, fmt.Errorf("unable to declare events exchange: %w", err)
+	}
+
+	return &Bus{
+		conf:   conf,
+		ch:     ch,
+		repo:   repo,
+		logger: logger,
+	}, nil
+}
+
+// Channel returns the channel the bus publishes and consumes on.
+func (b *Bus) Channel() *amqp091.Channel {
+	return b.ch
+}
+
+// Publish stores the event and sends it to every replica.
+func (b *Bus) Publish(ctx context.Context, event models.JobEvent) error {
+	event.EventID = ulid.Make().String()
+	event.CreatedAt = time.Now().UTC()
+
+	if err := b.repo.WithContext(ctx).CreateEvent(event); err != nil {
+		return err
+	}
+
+	return b.publish(ctx, MessageTypeJobEvent, event.Type, event)
+}
+
+// PublishOutput sends an already stored execution output chunk to every replica.
+func (b *Bus) PublishOutput(ctx context.Context, chunk models.ExecutionOutputChunk) error {
+	return b.publish(ctx, MessageTypeExecutionOutput, chunk.ExecutionID, chunk)
+}
+
+func (b *Bus) publish(ctx context.Context, messageType string, routingKey string, msg any) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, span, headers := tracing.StartPublish(ctx, b.conf.Rabbit.EventsExchange, nil)
+	defer span.End()
+
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+
+	return b.ch.PublishWithContext(ctx, b.conf.Rabbit.EventsExchange, routingKey, false, false, amqp091.Publishing{
+		ContentType: "application/json",
+		Type:        messageType,
+		Timestamp:   time.Now().UTC(),
+		Headers:     headers,
+		Body:        body,
+	})
+}
+
+// Listen consumes events published by every replica and dispatches them to local subscribers
+// until ctx is cancelled or the channel closes.
+func (b *Bus) Listen(ctx context.Context) error {
+	q, err := b.ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return fmt.Errorf("unable to declare events queue: %w", err)
+	}
+
+	if err = b.ch.QueueBind(q.Name, "", b.conf.Rabbit.EventsExchange, false, nil); err != nil {
+		return fmt.Errorf("unable to bind events queue: %w", err)
+	}
+
+	msgs, err := b.ch.ConsumeWithContext(ctx, q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("unable to consume events queue: %w", err)
+	}
+
+	for d := range msgs {
+		switch d.Type {
+		case MessageTypeJobEvent:
+			var event models.JobEvent
+			if err := json.Unmarshal(d.Body, &event); err != nil {
+				b.logger.Error("failed to unmarshal job event", &err)
+				continue
+			}
+			b.jobSubs.dispatch(event)
+		case MessageTypeExecutionOutput:
+			var chunk models.ExecutionOutputChunk
+			if err := json.Unmarshal(d.Body, &chunk); err != nil {
+				b.logger.Error("failed to unmarshal execution output", &err)
+				continue
+			}
+			b.outputSubs.dispatch(chunk)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe registers a subscriber for the job events accepted by filter.
+func (b *Bus) Subscribe(filter func(models.JobEvent) bool) *Subscription[models.JobEvent] {
+	return b.jobSubs.add(filter)
+}
+
+// SubscribeOutput registers a subscriber for the output chunks of an execution.
+func (b *Bus) SubscribeOutput(executionId string) *Subscription[models.ExecutionOutputChunk] {
+	return b.outputSubs.add(func(chunk models.ExecutionOutputChunk) bool {
+		return chunk.ExecutionID == executionId
+	})
+}
+
+// Close unregisters the subscription and closes its channel.
+func (s *Subscription[T]) Close() {
+	s.once.Do(func() {
+		s.set.mu.Lock()
+		delete(s.set.subs, s)
+		s.set.mu.Unlock()
+		close(s.c)
+	})
+}
+
+func (s *subscribers[T]) add(filter func(T) bool) *Subscription[T] {
+	c := make(chan T, SubscriptionBuffer)
+	sub := &Subscription[T]{C: c, c: c, filter: filter, set: s}
+
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[*Subscription[T]]struct{})
+	}
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+
+	return sub
+}
+
+func (s *subscribers[T]) dispatch(msg T) {
+	var lagging []*Subscription[T]
+
+	s.mu.RLock()
+	for sub := range s.subs {
+		if !sub.filter(msg) {
+			continue
+		}
+		select {
+		case sub.c <- msg:
+		default:
+			lagging = append(lagging, sub)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, sub := range lagging {
+		sub.Close()
+	}
+}
+
+// Close closes the bus channel, ending Listen.
+func (b *Bus) Close() error {
+	return b.ch.Close()
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/julianstephens/distributed-job-manager/pkg/config"
 	"github.com/julianstephens/distributed-job-manager/pkg/queue"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
 	"github.com/julianstephens/distributed-job-manager/pkg/utils"
 	"github.com/rabbitmq/amqp091-go"
 )
@@ -21,6 +22,8 @@ type GrayLogger struct {
 	LogCh      *amqp091.Channel
 	LogConn    *amqp091.Connection
 	Originator string
+	traceId    string
+	spanId     string
 }
 
 type LogLevel int64
@@ -46,6 +49,8 @@ type Log struct {
 	Level          LogLevel  `json:"level"`
 	Origin         string    `json:"_origin"`
 	AdditionalData *string   `json:"_additional_data"`
+	TraceID        string    `json:"_trace_id,omitempty"`
+	SpanID         string    `json:"_span_id,omitempty"`
 }
 
 type LogOptions struct {
@@ -74,11 +79,19 @@ func NewLogger(originator string) (*GrayLogger, error) {
 	}, nil
 }
 
+// WithContext returns a copy of the logger whose entries carry the trace and span IDs of ctx, so
+// they can be joined to the trace.
+func (l *GrayLogger) WithContext(ctx context.Context) *GrayLogger {
+	res := *l
+	res.traceId, res.spanId = tracing.IDs(ctx)
+	return &res
+}
+
 func (l *GrayLogger) Info(msg string, additionalData *string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	log := formatGELFLog(l.Originator, msg, LogLevelInfo, additionalData, nil)
+	log := l.newLog(msg, LogLevelInfo, additionalData, nil)
 
 	data, err := json.Marshal(&log)
 	if err != nil {
@@ -97,7 +110,7 @@ func (l *GrayLogger) Error(msg string, trace *error) {
 		err := *trace
 		errText = err.Error()
 	}
-	log := formatGELFLog(l.Originator, msg, LogLevelError, utils.If(trace != nil, &errText, nil), nil)
+	log := l.newLog(msg, LogLevelError, utils.If(trace != nil, &errText, nil), nil)
 
 	data, err := json.Marshal(&log)
 	if err != nil {
@@ -126,7 +139,7 @@ func (l *GrayLogger) ErrorWithData(msg string, trace *error, additionalData *map
 		formattedData = utils.StringPtr(string(d))
 	}
 
-	log := formatGELFLog(l.Originator, msg, LogLevelError, utils.If(trace != nil, &errText, nil), formattedData)
+	log := l.newLog(msg, LogLevelError, utils.If(trace != nil, &errText, nil), formattedData)
 
 	data, err := json.Marshal(&log)
 	if err != nil {
@@ -140,7 +153,7 @@ func (l *GrayLogger) Warn(msg string, additionalData *string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	log := formatGELFLog(l.Originator, msg, LogLevelWarning, additionalData, nil)
+	log := l.newLog(msg, LogLevelWarning, additionalData, nil)
 
 	data, err := json.Marshal(&log)
 	if err != nil {
@@ -154,7 +167,7 @@ func (l *GrayLogger) Debug(msg string, additionalData *string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	log := formatGELFLog(l.Originator, msg, LogLevelDebug, additionalData, nil)
+	log := l.newLog(msg, LogLevelDebug, additionalData, nil)
 
 	data, err := json.Marshal(&log)
 	if err != nil {
@@ -165,6 +178,12 @@ func (l *GrayLogger) Debug(msg string, additionalData *string) {
 }
 
 func (l *GrayLogger) doLog(ctx context.Context, body []byte) {
+	// without a connection to the log exchange, as in tests, entries are written to stderr
+	if LogCh == nil {
+		os.Stderr.Write(append(body, '\n'))
+		return
+	}
+
 	err := LogCh.PublishWithContext(
 		ctx,
 		"logs",
@@ -181,6 +200,14 @@ func (l *GrayLogger) doLog(ctx context.Context, body []byte) {
 	}
 }
 
+// newLog formats a GELF log tagged with the logger's trace.
+func (l *GrayLogger) newLog(msg string, level LogLevel, trace *string, additionalData *string) Log {
+	log := formatGELFLog(l.Originator, msg, level, trace, additionalData)
+	log.TraceID = l.traceId
+	log.SpanID = l.spanId
+	return log
+}
+
 func formatGELFLog(origin string, msg string, level LogLevel, trace *string, additionalData *string) Log {
 	hostname, err := os.Hostname()
 	if err != nil {
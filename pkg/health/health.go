@@ -0,0 +1,168 @@
+// Package health serves the liveness and readiness probes of the DJM services.
+//
+// /healthz reports whether the process is up and able to serve requests. /readyz additionally
+// runs the service's dependency checks and fails if any of them does.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// CheckTimeout bounds how long a single readiness check may take.
+const CheckTimeout = 3 * time.Second
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check reports an error if a dependency is unavailable.
+type Check func(ctx context.Context) error
+
+// Report is the body of a probe response. Checks holds StatusOK or the error of each check.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Checker runs a service's readiness checks.
+type Checker struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+func New() *Checker {
+	return &Checker{checks: make(map[string]Check)}
+}
+
+// Add registers a readiness check, replacing any check with the same name.
+func (h *Checker) Add(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// Ready runs every check concurrently.
+func (h *Checker) Ready(ctx context.Context) Report {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]string, len(h.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+
+			result := StatusOK
+			if err := check(ctx); err != nil {
+				result = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result != StatusOK {
+				report.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// Register adds the /healthz and /readyz probes to r.
+func (h *Checker) Register(r gin.IRoutes) {
+	r.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, Report{Status: StatusOK})
+	})
+	r.GET("/readyz", func(c *gin.Context) {
+		report := h.Ready(c.Request.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	})
+}
+
+// Serve runs an HTTP server on addr with the probes and any routes added by register, for
+// services without an API of their own.
+func (h *Checker) Serve(addr string, register ...func(gin.IRoutes)) error {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	h.Register(r)
+	for _, add := range register {
+		add(r)
+	}
+	return r.Run(addr)
+}
+
+// Cassandra checks that the database answers queries.
+func Cassandra(db *store.DBSession) Check {
+	return func(ctx context.Context) error {
+		if db.Client.Session.Closed() {
+			return errors.New("session closed")
+		}
+		return db.Client.Session.Query("SELECT now() FROM system.local").WithContext(ctx).Exec()
+	}
+}
+
+// Rabbit checks that a connection and the channels opened on it are open.
+func Rabbit(conn *amqp091.Connection, channels ...*amqp091.Channel) Check {
+	return func(context.Context) error {
+		if conn == nil || conn.IsClosed() {
+			return errors.New("connection closed")
+		}
+		for _, ch := range channels {
+			if ch == nil || ch.IsClosed() {
+				return errors.New("channel closed")
+			}
+		}
+		return nil
+	}
+}
+
+// Reachable checks that the liveness probe of another service at endpoint responds. Only the
+// scheme and host of endpoint are used.
+func Reachable(endpoint string) Check {
+	return func(ctx context.Context) error {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return err
+		}
+		probe := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/healthz"}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, probe.String(), nil)
+		if err != nil {
+			return err
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s responded %d", probe.String(), res.StatusCode)
+		}
+		return nil
+	}
+}
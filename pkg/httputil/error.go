@@ -11,8 +11,9 @@ import (
 )
 
 type HTTPError struct {
-	Code    int    `json:"code" example:"400"`
-	Message string `json:"message" example:"status bad request"`
+	Code    int      `json:"code" example:"400"`
+	Message string   `json:"message" example:"status bad request"`
+	Errors  []string `json:"errors,omitempty"`
 }
 
 type ValidationError struct {
@@ -45,10 +46,23 @@ func (v ValidationError) NewFieldError() string {
 
 }
 
+// HandleFieldError responds with every field validation error in err. Errors that did not come
+// from the validator (e.g. malformed JSON) are returned as a plain bad request.
 func HandleFieldError(ctx *gin.Context, err error) {
-	for _, fieldErr := range err.(validator.ValidationErrors) {
-		msg := ValidationError{FieldError: fieldErr}.NewFieldError()
-		NewError(ctx, http.StatusBadRequest, errors.New(msg))
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		NewError(ctx, http.StatusBadRequest, err)
 		return
 	}
+
+	msgs := make([]string, 0, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		msgs = append(msgs, ValidationError{FieldError: fieldErr}.NewFieldError())
+	}
+
+	ctx.JSON(http.StatusBadRequest, HTTPError{
+		Code:    http.StatusBadRequest,
+		Message: "request validation failed",
+		Errors:  msgs,
+	})
 }
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
 )
 
 type Item struct {
@@ -25,6 +26,16 @@ func GetUserId(ctx *gin.Context) (userId string) {
 	return
 }
 
+// GetPrincipal builds the authenticated caller from the 'userId', 'groups' and 'isAdmin' keys
+// set on Gin context by the auth middleware
+func GetPrincipal(ctx *gin.Context) models.Principal {
+	return models.Principal{
+		UserID:  GetUserId(ctx),
+		Groups:  ctx.GetStringSlice("groups"),
+		IsAdmin: ctx.GetBool("isAdmin"),
+	}
+}
+
 // GetId parses the 'id' path param from Gin context
 func GetId(ctx *gin.Context) (id string) {
 	id = ctx.Param("id")
@@ -34,3 +45,12 @@ func GetId(ctx *gin.Context) (id string) {
 	}
 	return
 }
+
+// GetPage parses the 'limit' and 'page_token' query params from Gin context
+func GetPage(ctx *gin.Context) (page models.PageRequest, ok bool) {
+	if err := ctx.ShouldBindQuery(&page); err != nil {
+		HandleFieldError(ctx, err)
+		return
+	}
+	return page, true
+}
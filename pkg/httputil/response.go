@@ -8,14 +8,16 @@ import (
 )
 
 type HTTPResponse[T any] struct {
-	Message string `json:"message" `
-	Data    T      `json:"data"`
+	Message       string `json:"message" `
+	Data          T      `json:"data"`
+	NextPageToken string `json:"next_page_token,omitempty"`
 }
 
 type Options struct {
 	IsCrudHandler bool
 	HttpMsgMethod HTTPMethod
 	Status        int
+	NextPageToken string
 }
 
 type HTTPMethod int64
@@ -67,8 +69,9 @@ func NewResponse[T any](ctx *gin.Context, data T, opts Options) {
 	}
 
 	res := HTTPResponse[T]{
-		Message: message,
-		Data:    data,
+		Message:       message,
+		Data:          data,
+		NextPageToken: opts.NextPageToken,
 	}
 	ctx.JSON(status, res)
 }
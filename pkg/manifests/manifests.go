@@ -0,0 +1,217 @@
+// Package manifests converts jobs to and from declarative manifests and plans the changes that
+// applying a set of manifests makes to a user's jobs.
+//
+// A manifest's name is the name of the job it declares, so a user's jobs are matched to their
+// manifests by name rather than by ID. Payloads are exported as stored, already sanitized, and
+// sanitizing them again leaves them unchanged, so exported manifests apply without changes.
+package manifests
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/utils"
+	"github.com/julianstephens/distributed-job-manager/pkg/validation"
+)
+
+// ErrInvalid is returned when a set of manifests cannot be applied.
+var ErrInvalid = errors.New("invalid manifests")
+
+// Step is a planned change along with the job to create or the update to apply for it.
+type Step struct {
+	Change models.ManifestChange
+	Job    models.Job
+	Update models.JobUpdateRequest
+}
+
+// FromJob returns the manifest declaring a job.
+func FromJob(job models.Job) models.JobManifest {
+	return models.JobManifest{
+		Version:     models.ManifestVersion,
+		Name:        job.JobName,
+		Description: job.JobDescription,
+		Project:     job.Project,
+		Labels:      job.Labels,
+		Schedule: models.ManifestSchedule{
+			Frequency: job.Frequency,
+			TimeZone:  job.TimeZone,
+			Start:     job.ExecutionTime,
+		},
+		Limits: models.ManifestLimits{
+			MaxRetries: job.MaxRetries,
+		},
+		Payload: job.Payload,
+	}
+}
+
+// Plan compares manifests with the user's existing jobs and returns the steps that make the jobs
+// match: creating or updating a job for each manifest and, with prune, deleting jobs no manifest
+// names. Deletes come last, ordered by name.
+func Plan(manifests []models.JobManifest, existing []models.Job, prune bool) ([]Step, error) {
+	byName := make(map[string]models.Job, len(existing))
+	shared := make(map[string]int)
+	for _, job := range existing {
+		if _, ok := byName[job.JobName]; ok {
+			shared[job.JobName]++
+		}
+		byName[job.JobName] = job
+	}
+
+	named := make(map[string]bool, len(manifests))
+	steps := make([]Step, 0, len(manifests))
+	for _, m := range manifests {
+		if named[m.Name] {
+			return nil, fmt.Errorf("%w: %q is declared more than once", ErrInvalid, m.Name)
+		}
+		named[m.Name] = true
+
+		if n := shared[m.Name]; n > 0 {
+			return nil, fmt.Errorf("%w: %d of your jobs are named %q, rename all but one to manage it with a manifest", ErrInvalid, n+1, m.Name)
+		}
+
+		payload, err := sanitize(m)
+		if err != nil {
+			return nil, err
+		}
+
+		job, ok := byName[m.Name]
+		if !ok {
+			if err := checkStart(m); err != nil {
+				return nil, err
+			}
+			steps = append(steps, Step{
+				Change: models.ManifestChange{Action: models.ManifestActionCreate, Name: m.Name},
+				Job:    toJob(m),
+			})
+			continue
+		}
+
+		fields, update := diff(job, m, payload)
+		if update.ExecutionTime != nil {
+			if err := checkStart(m); err != nil {
+				return nil, err
+			}
+		}
+
+		action := utils.If(len(fields) > 0, models.ManifestActionUpdate, models.ManifestActionUnchanged)
+		steps = append(steps, Step{
+			Change: models.ManifestChange{Action: action, Name: m.Name, JobID: job.JobID, Fields: fields},
+			Update: update,
+		})
+	}
+
+	if prune {
+		var deletes []Step
+		for _, job := range existing {
+			if !named[job.JobName] {
+				deletes = append(deletes, Step{
+					Change: models.ManifestChange{Action: models.ManifestActionDelete, Name: job.JobName, JobID: job.JobID},
+				})
+			}
+		}
+		slices.SortFunc(deletes, func(a, b Step) int {
+			return cmp.Or(cmp.Compare(a.Change.Name, b.Change.Name), cmp.Compare(a.Change.JobID, b.Change.JobID))
+		})
+		steps = append(steps, deletes...)
+	}
+
+	return steps, nil
+}
+
+// Changes returns the changes of steps.
+func Changes(steps []Step) []models.ManifestChange {
+	changes := make([]models.ManifestChange, len(steps))
+	for i, step := range steps {
+		changes[i] = step.Change
+	}
+	return changes
+}
+
+func toJob(m models.JobManifest) models.Job {
+	return models.Job{
+		JobName:        m.Name,
+		JobDescription: m.Description,
+		Frequency:      m.Schedule.Frequency,
+		TimeZone:       m.Schedule.TimeZone,
+		Labels:         m.Labels,
+		Project:        m.Project,
+		Payload:        m.Payload,
+		MaxRetries:     m.Limits.MaxRetries,
+		ExecutionTime:  m.Schedule.Start,
+	}
+}
+
+// sanitize returns the payload of a manifest as it would be stored, checking its languages as
+// creating the job would.
+func sanitize(m models.JobManifest) (string, error) {
+	parser := &utils.Parser{}
+	if err := parser.Parse(m.Payload); err != nil {
+		return "", fmt.Errorf("%w: unable to parse payload of %q: %v", ErrInvalid, m.Name, err)
+	}
+
+	supported := utils.GetSupportedLanguages()
+	for _, block := range parser.Result {
+		if supported[block.Language] == "" {
+			return "", fmt.Errorf("%w: payload of %q: %s is not a supported code language", ErrInvalid, m.Name, block.Language)
+		}
+	}
+
+	return parser.SanitizedInput, nil
+}
+
+// checkStart rejects a start time that has passed, which jobs may only keep, not be given.
+func checkStart(m models.JobManifest) error {
+	if m.Schedule.Start.Before(time.Now().Add(-validation.ClockSkew)) {
+		return fmt.Errorf("%w: start of %q is in the past", ErrInvalid, m.Name)
+	}
+	return nil
+}
+
+// diff returns the fields in which a job differs from its manifest and the update that applies
+// them. payload is the manifest's payload as it would be stored.
+func diff(job models.Job, m models.JobManifest, payload string) ([]models.ManifestFieldChange, models.JobUpdateRequest) {
+	var fields []models.ManifestFieldChange
+	var update models.JobUpdateRequest
+
+	if job.JobDescription != m.Description {
+		fields = append(fields, models.ManifestFieldChange{Field: "description", Before: job.JobDescription, After: m.Description})
+		update.JobDescription = &m.Description
+	}
+	if job.Project != m.Project {
+		fields = append(fields, models.ManifestFieldChange{Field: "project", Before: job.Project, After: m.Project})
+		update.Project = &m.Project
+	}
+	if !maps.Equal(job.Labels, m.Labels) {
+		labels := utils.If(m.Labels != nil, m.Labels, map[string]string{})
+		fields = append(fields, models.ManifestFieldChange{Field: "labels", Before: job.Labels, After: labels})
+		update.Labels = &labels
+	}
+	if job.Frequency != m.Schedule.Frequency {
+		fields = append(fields, models.ManifestFieldChange{Field: "schedule.frequency", Before: job.Frequency, After: m.Schedule.Frequency})
+		update.Frequency = &m.Schedule.Frequency
+	}
+	if job.TimeZone != m.Schedule.TimeZone {
+		fields = append(fields, models.ManifestFieldChange{Field: "schedule.time_zone", Before: job.TimeZone, After: m.Schedule.TimeZone})
+		update.TimeZone = &m.Schedule.TimeZone
+	}
+	// Cassandra keeps timestamps to the millisecond
+	if start := m.Schedule.Start.Truncate(time.Millisecond); !job.ExecutionTime.Equal(start) {
+		fields = append(fields, models.ManifestFieldChange{Field: "schedule.start", Before: job.ExecutionTime, After: start})
+		update.ExecutionTime = &start
+	}
+	if job.MaxRetries != m.Limits.MaxRetries {
+		fields = append(fields, models.ManifestFieldChange{Field: "limits.max_retries", Before: job.MaxRetries, After: m.Limits.MaxRetries})
+		update.MaxRetries = &m.Limits.MaxRetries
+	}
+	if job.Payload != payload {
+		fields = append(fields, models.ManifestFieldChange{Field: "payload", Before: job.Payload, After: payload})
+		update.Payload = &m.Payload
+	}
+
+	return fields, update
+}
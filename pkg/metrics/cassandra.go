@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"context"
+	"runtime"
+	"strings"
+
+	"github.com/gocql/gocql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var cassandraQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: Namespace,
+	Subsystem: "cassandra",
+	Name:      "query_duration_seconds",
+	Help:      "Latency of Cassandra queries, by the method that issued them and outcome.",
+	Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"method", "outcome"})
+
+const (
+	modulePath = "github.com/julianstephens/distributed-job-manager/"
+	// maxQueryFrames bounds how far up the stack QueryObserver looks for the issuing method.
+	maxQueryFrames = 32
+)
+
+// QueryObserver records the latency of every query attempt. Queries are labelled with the
+// outermost method of the package that issued them, e.g. 'repository.(*JobRepository).GetJobs',
+// so helpers shared between repository methods are attributed to their callers.
+type QueryObserver struct{}
+
+func (QueryObserver) ObserveQuery(_ context.Context, q gocql.ObservedQuery) {
+	outcome := "success"
+	if q.Err != nil {
+		outcome = "error"
+	}
+	cassandraQueryDuration.WithLabelValues(queryMethod(), outcome).Observe(q.End.Sub(q.Start).Seconds())
+}
+
+// queryMethod finds the outermost frame of the first package of this module on the stack that is
+// not the database plumbing itself. gocql calls observers synchronously, so the stack is the
+// issuing goroutine's.
+func queryMethod() string {
+	pcs := make([]uintptr, maxQueryFrames)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+
+	method, pkg := "unknown", ""
+	for {
+		frame, more := frames.Next()
+		name, ok := strings.CutPrefix(frame.Function, modulePath+"pkg/")
+		if ok && !strings.HasPrefix(name, "metrics.") && !strings.HasPrefix(name, "store.") {
+			framePkg, _, _ := strings.Cut(name, ".")
+			if pkg != "" && framePkg != pkg {
+				break
+			}
+			method, pkg = name, framePkg
+		} else if pkg != "" {
+			break
+		}
+		if !more {
+			break
+		}
+	}
+
+	return method
+}
@@ -0,0 +1,18 @@
+// Package metrics defines the Prometheus metrics of the DJM services and serves them on /metrics.
+//
+// Metrics are registered with the default registry when the package is loaded, so each service
+// only exposes the metrics of the code it runs.
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every DJM metric.
+const Namespace = "djm"
+
+// Register adds the /metrics endpoint to r.
+func Register(r gin.IRoutes) {
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+}
@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of an execution reaped because its worker died.
+const (
+	ReapedRequeued = "requeued"
+	ReapedFailed   = "failed"
+)
+
+// ReapedExecutions counts executions failed because their worker died, by whether their job was
+// requeued or had no retries left.
+var ReapedExecutions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: Namespace,
+	Subsystem: "reaper",
+	Name:      "executions_total",
+	Help:      "Executions failed because their worker died, by what became of their job.",
+}, []string{"outcome"})
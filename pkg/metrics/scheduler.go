@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of a schedule picked up by a scheduler poll.
+const (
+	ScheduleFound      = "found"
+	ScheduleDispatched = "dispatched"
+	ScheduleSkipped    = "skipped"
+	ScheduleFailed     = "failed"
+)
+
+var (
+	schedulerPolls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "scheduler",
+		Name:      "polls_total",
+		Help:      "Polls of the schedule table, by whether they completed.",
+	}, []string{"result"})
+
+	schedulerSchedules = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "scheduler",
+		Name:      "schedules_total",
+		Help:      "Schedules found by polls and what became of them.",
+	}, []string{"outcome"})
+
+	schedulerLastPoll = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "scheduler",
+		Name:      "last_poll_schedules",
+		Help:      "Schedules found by the most recent poll and what became of them.",
+	}, []string{"outcome"})
+)
+
+// Poll counts the schedules of a single scheduler poll.
+type Poll struct {
+	counts map[string]int
+}
+
+func NewPoll() *Poll {
+	return &Poll{counts: map[string]int{}}
+}
+
+// Add counts n schedules with an outcome.
+func (p *Poll) Add(outcome string, n int) {
+	p.counts[outcome] += n
+}
+
+// Done records the poll, which completed unless err is set.
+func (p *Poll) Done(err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	schedulerPolls.WithLabelValues(result).Inc()
+
+	for _, outcome := range []string{ScheduleFound, ScheduleDispatched, ScheduleSkipped, ScheduleFailed} {
+		schedulerSchedules.WithLabelValues(outcome).Add(float64(p.counts[outcome]))
+		schedulerLastPoll.WithLabelValues(outcome).Set(float64(p.counts[outcome]))
+	}
+}
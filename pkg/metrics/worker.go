@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of an execution run by a worker.
+const (
+	ExecutionCompleted = "completed"
+	ExecutionFailed    = "failed"
+)
+
+var (
+	// MessagesConsumed counts the job messages a worker has taken off the queue.
+	MessagesConsumed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "worker",
+		Name:      "messages_consumed_total",
+		Help:      "Job messages consumed from the queue.",
+	})
+
+	// ExecutionDuration records how long executions ran in a sandbox, by language and outcome.
+	ExecutionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "worker",
+		Name:      "execution_duration_seconds",
+		Help:      "Wall-time of executions in a sandbox, by language and outcome.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"language", "outcome"})
+)
+
+// RegisterSandboxPool exposes the size and utilization of a worker's sandbox pool. usage is called
+// on every scrape.
+func RegisterSandboxPool(size int, usage func() (available int, reserved int)) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "worker",
+		Name:      "sandboxes",
+		Help:      "Sandboxes the worker was configured with.",
+	}, func() float64 { return float64(size) })
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "worker",
+		Name:      "sandboxes_available",
+		Help:      "Initialized sandboxes not reserved by a job.",
+	}, func() float64 {
+		available, _ := usage()
+		return float64(available)
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "worker",
+		Name:      "sandboxes_reserved",
+		Help:      "Sandboxes reserved by a running job.",
+	}, func() float64 {
+		_, reserved := usage()
+		return float64(reserved)
+	})
+}
@@ -6,97 +6,79 @@ import (
 	"net/http"
 	"slices"
 	"strings"
+	"time"
 
-	"github.com/MicahParks/keyfunc/v3"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/julianstephens/distributed-job-manager/pkg/config"
 	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
-	"github.com/julianstephens/distributed-job-manager/pkg/logger"
 	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
 )
 
-var (
-	ErrInvalidToken = errors.New("authorization token is invalid")
+// Values of the 'authSource' context key set by Guard.
+const (
+	AuthSourceJWT    = "jwt"
+	AuthSourceAPIKey = "api_key"
 )
 
-type JWTManager struct {
-	conf *models.Config
-}
-
-func NewJWTManager() *JWTManager {
-	conf := config.GetConfig()
-
-	return &JWTManager{
-		conf: conf,
-	}
-}
+// APIKeyTouchInterval is how stale an API key's last use may get before Guard updates it.
+const APIKeyTouchInterval = time.Minute
 
-func (j *JWTManager) extractToken(c *gin.Context) string {
+// extractToken reads the bearer token from the Authorization header. API keys may also be sent
+// without the 'Bearer' scheme.
+func extractToken(c *gin.Context) string {
 	bearerToken := c.Request.Header.Get("Authorization")
-	if len(strings.Split(bearerToken, " ")) == 2 {
-		return strings.Split(bearerToken, " ")[1]
+	parts := strings.Split(bearerToken, " ")
+	if len(parts) == 2 {
+		return parts[1]
+	}
+	if len(parts) == 1 && strings.HasPrefix(parts[0], models.APIKeyPrefix) {
+		return parts[0]
 	}
 
 	return ""
 }
 
-func (j *JWTManager) Validate(c *gin.Context) (string, error) {
-	tokenString := j.extractToken(c)
-	if tokenString == "" {
-		logger.Errorf("received malformated token")
-		return "", ErrInvalidToken
-	}
-
-	k, err := keyfunc.NewDefaultCtx(c.Request.Context(), []string{j.conf.Auth0.JWKSUrl})
-	if err != nil {
-		return "", fmt.Errorf("failed to create a keyfunc from the jwks URL: %v", err)
-	}
+// Guard authenticates requests bearing either an API key or a JWT, setting the 'userId',
+// 'scopes', 'groups' and 'authSource' context keys. Requests bearing an API key also get the
+// 'apiKeyId' key, and 'apiKeyExpiresAt' if the key expires.
+func Guard(tokens *JWTManager, keys *repository.APIKeyRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := extractToken(ctx)
 
-	parsed, err := jwt.Parse(tokenString, k.Keyfunc)
-	if err != nil {
-		switch {
-		case errors.Is(err, jwt.ErrTokenMalformed):
-			return "", ErrInvalidToken
-		case errors.Is(err, jwt.ErrTokenSignatureInvalid):
-			return "", fmt.Errorf("invalid token signature")
-		case errors.Is(err, jwt.ErrTokenExpired):
-			return "", fmt.Errorf("token expired")
-		default:
-			return "", fmt.Errorf("failed to parse jwt: %v", err)
-		}
-	}
+		if strings.HasPrefix(token, models.APIKeyPrefix) {
+			key, err := keys.WithContext(ctx.Request.Context()).Authenticate(token)
+			if err != nil {
+				httputil.NewError(ctx, http.StatusUnauthorized, errors.New("unauthorized request"))
+				ctx.Abort()
+				return
+			}
 
-	if claims, ok := parsed.Claims.(jwt.MapClaims); ok {
-		iss, err := claims.GetIssuer()
-		if err != nil || iss != fmt.Sprintf("https://%s/", j.conf.Auth0.Domain) {
-			return "", errors.New("invalid token issuer")
-		}
+			// last use is only a hint, so avoid a write on every request
+			if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > APIKeyTouchInterval {
+				go keys.TouchAPIKey(*key)
+			}
 
-		aud, err := claims.GetAudience()
-		if err != nil || !slices.Contains(aud, j.conf.Auth0.Audience) {
-			return "", errors.New("invalid token audience")
+			ctx.Set("userId", key.Subject)
+			ctx.Set("scopes", key.Scopes)
+			ctx.Set("authSource", AuthSourceAPIKey)
+			ctx.Set("apiKeyId", key.KeyID)
+			if key.ExpiresAt != nil {
+				ctx.Set("apiKeyExpiresAt", *key.ExpiresAt)
+			}
+			ctx.Next()
+			return
 		}
 
-		parsedScopes := strings.Split(claims["scope"].(string), " ")
-		c.Set("scopes", parsedScopes)
-
-		return claims.GetSubject()
-	} else {
-		return "", errors.New("failed to parse token claims")
-	}
-}
-
-func Guard() gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		jM := NewJWTManager()
-		uid, err := jM.Validate(ctx)
+		identity, err := tokens.Validate(token)
 		if err != nil {
 			httputil.NewError(ctx, http.StatusUnauthorized, errors.New("unauthorized request"))
 			ctx.Abort()
 			return
 		}
-		ctx.Set("userId", uid)
+		ctx.Set("userId", identity.Subject)
+		ctx.Set("scopes", identity.Scopes)
+		ctx.Set("groups", identity.Groups)
+		ctx.Set("authSource", AuthSourceJWT)
 		ctx.Next()
 	}
 }
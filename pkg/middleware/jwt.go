@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/julianstephens/distributed-job-manager/pkg/logger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+var (
+	ErrInvalidToken   = errors.New("authorization token is invalid")
+	ErrMissingSubject = errors.New("authorization token has no subject")
+)
+
+// JWTManager verifies bearer JWTs. It is meant to be created once per process: in OIDC mode it
+// keeps a JWKS cache that is refreshed in the background and whenever a token names an unknown
+// key, until the context passed to NewJWTManager is cancelled.
+type JWTManager struct {
+	issuer      string
+	audience    string
+	scopeClaim  string
+	groupsClaim string
+	methods     []string
+	keyfunc     jwt.Keyfunc
+}
+
+func NewJWTManager(ctx context.Context, conf *models.Config) (*JWTManager, error) {
+	j := &JWTManager{
+		issuer:      conf.Auth.Issuer,
+		audience:    conf.Auth.Audience,
+		scopeClaim:  conf.Auth.ScopeClaim,
+		groupsClaim: conf.Auth.GroupsClaim,
+	}
+
+	switch conf.Auth.Mode {
+	case models.AuthModeLocal:
+		if conf.JWTSecretKey == "" {
+			return nil, errors.New("local auth mode requires JWT_SECRET_KEY")
+		}
+		secret := []byte(conf.JWTSecretKey)
+		j.issuer = conf.Auth.LocalIssuer()
+		j.methods = []string{jwt.SigningMethodHS256.Alg()}
+		j.keyfunc = func(*jwt.Token) (any, error) {
+			return secret, nil
+		}
+	case models.AuthModeOIDC:
+		if j.issuer == "" && conf.Auth0.Domain != "" {
+			j.issuer = fmt.Sprintf("https://%s/", conf.Auth0.Domain)
+		}
+		if j.audience == "" {
+			j.audience = conf.Auth0.Audience
+		}
+		jwksUrl := conf.Auth.JWKSUrl
+		if jwksUrl == "" {
+			jwksUrl = conf.Auth0.JWKSUrl
+		}
+		if j.issuer == "" || j.audience == "" || jwksUrl == "" {
+			return nil, errors.New("oidc auth mode requires an issuer, audience and jwks url")
+		}
+
+		k, err := keyfunc.NewDefaultOverrideCtx(ctx, []string{jwksUrl}, keyfunc.Override{
+			RefreshInterval: conf.Auth.RefreshInterval,
+			RefreshErrorHandlerFunc: func(url string) func(context.Context, error) {
+				return func(_ context.Context, err error) {
+					logger.Errorf("failed to refresh jwks from %s: %v", url, err)
+				}
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a keyfunc from the jwks URL: %v", err)
+		}
+		j.keyfunc = k.Keyfunc
+		// the JWKS alone would admit any algorithm its key types allow
+		j.methods = conf.Auth.Algorithms
+		if len(j.methods) == 0 {
+			j.methods = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}
+		}
+	default:
+		return nil, fmt.Errorf("unknown auth mode: %s", conf.Auth.Mode)
+	}
+
+	if j.scopeClaim == "" {
+		j.scopeClaim = "scope"
+	}
+	if j.groupsClaim == "" {
+		j.groupsClaim = "groups"
+	}
+
+	return j, nil
+}
+
+// Identity is the caller described by a verified token.
+type Identity struct {
+	Subject string
+	Scopes  []string
+	Groups  []string
+}
+
+// Validate verifies a token's signature algorithm, signature, issuer and audience, returning the
+// identity it carries. Tokens without a subject are rejected.
+func (j *JWTManager) Validate(tokenString string) (identity *Identity, err error) {
+	if tokenString == "" {
+		logger.Errorf("received malformated token")
+		return nil, ErrInvalidToken
+	}
+
+	opts := []jwt.ParserOption{jwt.WithIssuer(j.issuer), jwt.WithExpirationRequired(), jwt.WithValidMethods(j.methods)}
+	if j.audience != "" {
+		opts = append(opts, jwt.WithAudience(j.audience))
+	}
+
+	parsed, err := jwt.Parse(tokenString, j.keyfunc, opts...)
+	if err != nil {
+		switch {
+		case errors.Is(err, jwt.ErrTokenMalformed):
+			return nil, ErrInvalidToken
+		case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+			return nil, fmt.Errorf("invalid token signature")
+		case errors.Is(err, jwt.ErrTokenExpired):
+			return nil, fmt.Errorf("token expired")
+		case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+			return nil, errors.New("invalid token issuer")
+		case errors.Is(err, jwt.ErrTokenInvalidAudience):
+			return nil, errors.New("invalid token audience")
+		default:
+			return nil, fmt.Errorf("failed to parse jwt: %v", err)
+		}
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("failed to parse token claims")
+	}
+
+	// every caller is identified by its subject, so a token without one identifies no one
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(subject) == "" {
+		return nil, ErrMissingSubject
+	}
+
+	return &Identity{
+		Subject: subject,
+		Scopes:  stringsClaim(claims[j.scopeClaim]),
+		Groups:  stringsClaim(claims[j.groupsClaim]),
+	}, nil
+}
+
+// stringsClaim reads a claim that providers encode either as a space-separated string ('scope')
+// or as a list ('scp', 'permissions', 'groups').
+func stringsClaim(claim any) (values []string) {
+	switch v := claim.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []any:
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				values = append(values, str)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+const testSecret = "test-secret"
+
+func newLocalManager(t *testing.T) *JWTManager {
+	t.Helper()
+	conf := &models.Config{JWTSecretKey: testSecret, Auth: models.AuthConfig{Mode: models.AuthModeLocal}}
+	j, err := NewJWTManager(context.Background(), conf)
+	if err != nil {
+		t.Fatalf("NewJWTManager() error = %v", err)
+	}
+	return j
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return token
+}
+
+func TestValidate(t *testing.T) {
+	j := newLocalManager(t)
+	claims := func(sub any) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":   models.LocalAuthIssuer,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": "read:jobs",
+		}
+		if sub != nil {
+			c["sub"] = sub
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid token", signToken(t, jwt.SigningMethodHS256, claims("alice")), false},
+		{"algorithm other than HS256", signToken(t, jwt.SigningMethodHS384, claims("alice")), true},
+		{"missing subject", signToken(t, jwt.SigningMethodHS256, claims(nil)), true},
+		{"empty subject", signToken(t, jwt.SigningMethodHS256, claims("")), true},
+		{"blank subject", signToken(t, jwt.SigningMethodHS256, claims("  ")), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := j.Validate(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Validate() identity = %+v, want error", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if identity.Subject != "alice" {
+				t.Errorf("Validate() subject = %q, want %q", identity.Subject, "alice")
+			}
+		})
+	}
+}
+
+func TestValidateMissingSubject(t *testing.T) {
+	j := newLocalManager(t)
+	token := signToken(t, jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": models.LocalAuthIssuer,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	if _, err := j.Validate(token); !errors.Is(err, ErrMissingSubject) {
+		t.Errorf("Validate() error = %v, want %v", err, ErrMissingSubject)
+	}
+}
+
+func TestGuardRejectsTokenWithoutSubject(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	j := newLocalManager(t)
+
+	router := gin.New()
+	router.GET("/", Guard(j, nil), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	token := signToken(t, jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": models.LocalAuthIssuer,
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"sub": "",
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", res.Code, http.StatusUnauthorized)
+	}
+}
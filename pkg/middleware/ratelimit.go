@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/ratelimit"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+)
+
+// NewRateLimitStore returns the bucket store selected by the rate limit config.
+func NewRateLimitStore(conf models.RateLimitConfig, db *store.DBSession) (ratelimit.Store, error) {
+	switch conf.Store {
+	case models.RateLimitStoreMemory:
+		return ratelimit.NewMemoryStore(), nil
+	case models.RateLimitStoreCassandra:
+		return ratelimit.NewCassandraStore(db), nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limit store %q", conf.Store)
+	}
+}
+
+// RateLimit limits each caller to the read or write budget of the rate limit config, keyed by
+// the API key the request was made with or else its 'userId'. It must run after Guard. Callers
+// with the admin scope are not limited, and requests are let through if the store fails.
+func RateLimit(conf models.RateLimitConfig, buckets ratelimit.Store, logger *graylogger.GrayLogger) gin.HandlerFunc {
+	read := ratelimit.Limit{Rate: conf.ReadRate, Burst: conf.ReadBurst}
+	write := ratelimit.Limit{Rate: conf.WriteRate, Burst: conf.WriteBurst}
+
+	return func(c *gin.Context) {
+		if !conf.Enabled || hasScope(c, "admin") {
+			c.Next()
+			return
+		}
+
+		key := "user:" + c.GetString("userId")
+		if keyId := c.GetString("apiKeyId"); keyId != "" {
+			key = "apikey:" + keyId
+		}
+
+		limit, class := write, "write"
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			limit, class = read, "read"
+		}
+
+		res, err := buckets.Take(c.Request.Context(), key+":"+class, limit)
+		if err != nil {
+			logger.Error(fmt.Sprintf("unable to take %s rate limit token for %s", class, key), &err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset.Seconds())))
+
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter.Seconds())))
+			httputil.NewError(c, http.StatusTooManyRequests, errors.New("rate limit exceeded"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// hasScope reports whether the scopes Guard set on the context include scope.
+func hasScope(c *gin.Context, scope string) bool {
+	switch v := c.Value("scopes").(type) {
+	case string:
+		return slices.Contains(strings.Fields(v), scope)
+	case []string:
+		return slices.Contains(v, scope)
+	default:
+		return false
+	}
+}
+
+func ceilSeconds(s float64) int {
+	return int(math.Ceil(s))
+}
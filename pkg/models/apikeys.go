@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// APIKeyPrefix starts every API key so Guard can tell keys apart from JWTs.
+const APIKeyPrefix = "djm_"
+
+// ServiceAccountPrefix starts the subject of keys issued to a service account rather than to
+// the user who created them. The subject is namespaced by its creator, as in 'svc:<user>/<name>',
+// so two users naming a service account alike do not share it.
+const ServiceAccountPrefix = "svc:"
+
+// ServiceAccountSubject returns the subject of the service account called name created by userId.
+func ServiceAccountSubject(userId string, name string) string {
+	return ServiceAccountPrefix + userId + "/" + name
+}
+
+// Scopes are the OAuth scopes checked by RequireScopes, and so the scopes an API key may hold.
+var Scopes = []string{
+	"read:jobs",
+	"write:jobs",
+	"read:executions",
+	"write:executions",
+	"read:schedules",
+	"write:schedules",
+	"read:workers",
+	"write:workers",
+	"admin",
+}
+
+// APIKey is a long-lived credential that authenticates as Subject with a fixed set of scopes.
+// Only the SHA-256 hash of the key is stored.
+type APIKey struct {
+	KeyID      string     `json:"key_id"`
+	UserID     string     `json:"user_id"`
+	Subject    string     `json:"subject"`
+	Name       string     `json:"name"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the key may still be used to authenticate.
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+type APIKeyCreateRequest struct {
+	Name           string     `binding:"required,max=128" json:"name"`
+	ServiceAccount string     `binding:"omitempty,max=64,slug" json:"service_account,omitempty"`
+	Scopes         []string   `binding:"required,min=1,dive,scope" json:"scopes"`
+	ExpiresAt      *time.Time `binding:"omitempty,notpast" json:"expires_at,omitempty"`
+}
+
+// APIKeyCreateResponse is the only time the plaintext key is returned.
+type APIKeyCreateResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Resources recorded in the audit log.
+const (
+	AuditResourceJob          = "job"
+	AuditResourceSchedule     = "schedule"
+	AuditResourceExecution    = "execution"
+	AuditResourceJobGrant     = "job_grant"
+	AuditResourceProjectGrant = "project_grant"
+	AuditResourceWebhook      = "webhook"
+	AuditResourceAPIKey       = "api_key"
+	AuditResourceDeadLetter   = "dead_letter"
+)
+
+// Actions recorded in the audit log. Cancel is an update that moves a job to the cancelled status,
+// trigger one that runs a job, redelivers a webhook or replays a dead letter outside its normal
+// flow.
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionCancel  = "cancel"
+	AuditActionTrigger = "trigger"
+)
+
+// Sources of the actor of an audit entry.
+const (
+	AuditSourceUser      = "user"
+	AuditSourceAPIKey    = "api_key"
+	AuditSourceScheduler = "scheduler"
+	AuditSourceWorker    = "worker"
+)
+
+// SchedulerSubject and WorkerSubjectPrefix start the subjects of the tokens the scheduler and
+// workers mint for themselves in local auth mode.
+const (
+	SchedulerSubject    = "schedulingsvc"
+	WorkerSubjectPrefix = "workersvc:"
+)
+
+// AuditEntry records a single mutation made through the job service. Before and After are JSON
+// snapshots of the resource, absent for creates and deletes respectively.
+type AuditEntry struct {
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	CreatedAt    time.Time       `json:"created_at"`
+	EntryID      string          `json:"entry_id"`
+	Action       string          `json:"action"`
+	ActorID      string          `json:"actor_id"`
+	ActorSource  string          `json:"actor_source"`
+	APIKeyID     string          `json:"api_key_id,omitempty"`
+	RequestID    string          `json:"request_id"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditQuery filters the audit log. From is inclusive and To exclusive.
+type AuditQuery struct {
+	ResourceType string     `form:"resource_type" binding:"required_with=ResourceID"`
+	ResourceID   string     `form:"resource_id"`
+	ActorID      string     `form:"actor_id"`
+	From         *time.Time `form:"from"`
+	To           *time.Time `form:"to"`
+}
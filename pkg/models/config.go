@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type DatabaseConfig struct {
 	Host     string `env:"DB_HOST"`
 	Port     string `env:"DB_PORT"`
@@ -14,6 +16,8 @@ type JobServiceConfig struct {
 }
 
 type ScheduleServiceConfig struct {
+	Host              string `env:"SCHEDULING_SERVICE_HOST"`
+	Port              string `env:"SCHEDULING_SERVICE_PORT"`
 	Auth0ClientID     string `env:"SCHEDULING_AUTH0_CLIENT_ID"`
 	Auth0ClientSecret string `env:"SCHEDULING_AUTH0_CLIENT_SECRET"`
 }
@@ -24,14 +28,25 @@ type CassandraConfig struct {
 	Keyspace string `env:"CASS_KEYSPACE"`
 }
 
+// RabbitConfig sets the broker and the queues jobs move through. Jobs are published to the Name
+// exchange and consumed from the Name queue; a job is dead-lettered to DeadLetterExchange once it
+// was delivered MaxDeliveries times without being processed. Jobs sent back to the queue wait in
+// DelayQueue first. ManagementURL is the broker's management API, through which the job service
+// sets the work queue's dead-letter policy; it defaults to port 15672 of Host.
 type RabbitConfig struct {
-	Host            string `env:"RABBIT_HOST"`
-	Port            string `env:"RABBIT_PORT"`
-	Username        string `env:"RABBIT_USERNAME"`
-	Password        string `env:"RABBIT_PASSWORD"`
-	LoggingUsername string `env:"RABBIT_LOGGING_USERNAME"`
-	LoggingPassword string `env:"RABBIT_LOGGING_PASSWORD"`
-	Name            string `env:"RABBIT_QUEUE_NAME"`
+	Host               string `env:"RABBIT_HOST"`
+	Port               string `env:"RABBIT_PORT"`
+	Username           string `env:"RABBIT_USERNAME"`
+	Password           string `env:"RABBIT_PASSWORD"`
+	LoggingUsername    string `env:"RABBIT_LOGGING_USERNAME"`
+	LoggingPassword    string `env:"RABBIT_LOGGING_PASSWORD"`
+	Name               string `env:"RABBIT_QUEUE_NAME"`
+	EventsExchange     string `env:"RABBIT_EVENTS_EXCHANGE" envDefault:"djm.events"`
+	DeadLetterExchange string `env:"RABBIT_DEAD_LETTER_EXCHANGE" envDefault:"djm.dead-letter"`
+	DeadLetterQueue    string `env:"RABBIT_DEAD_LETTER_QUEUE" envDefault:"djm.dead-letter"`
+	DelayQueue         string `env:"RABBIT_DELAY_QUEUE" envDefault:"djm.delay"`
+	MaxDeliveries      int    `env:"RABBIT_MAX_DELIVERIES" envDefault:"5"`
+	ManagementURL      string `env:"RABBIT_MANAGEMENT_URL"`
 }
 
 type Auth0Config struct {
@@ -40,13 +55,119 @@ type Auth0Config struct {
 	JWKSUrl  string `env:"JWKS_URL"`
 }
 
+// AuthConfig selects how Guard verifies JWTs. In 'oidc' mode tokens are checked against the
+// provider's JWKS, falling back to the Auth0 settings for any value left unset, and must be
+// signed with one of Algorithms. In 'local' mode tokens are HS256-signed with JWTSecretKey, so
+// the stack can run without an identity provider.
+type AuthConfig struct {
+	Mode            string        `env:"AUTH_MODE" envDefault:"oidc"`
+	Issuer          string        `env:"OIDC_ISSUER"`
+	Audience        string        `env:"OIDC_AUDIENCE"`
+	JWKSUrl         string        `env:"OIDC_JWKS_URL"`
+	TokenURL        string        `env:"OIDC_TOKEN_URL"`
+	ScopeClaim      string        `env:"OIDC_SCOPE_CLAIM" envDefault:"scope"`
+	GroupsClaim     string        `env:"OIDC_GROUPS_CLAIM" envDefault:"groups"`
+	RefreshInterval time.Duration `env:"OIDC_JWKS_REFRESH_INTERVAL" envDefault:"1h"`
+	Algorithms      []string      `env:"OIDC_ALGORITHMS" envSeparator:"," envDefault:"RS256,ES256"`
+}
+
+const (
+	AuthModeOIDC  = "oidc"
+	AuthModeLocal = "local"
+
+	// LocalAuthIssuer is the issuer of local mode tokens when OIDC_ISSUER is unset.
+	LocalAuthIssuer = "djm-local"
+)
+
+// LocalIssuer returns the issuer of local mode tokens.
+func (a AuthConfig) LocalIssuer() string {
+	if a.Issuer != "" {
+		return a.Issuer
+	}
+	return LocalAuthIssuer
+}
+
 type Auth0WorkerConfig struct {
 	ClientId     string `env:"WORKER_AUTH0_CLIENT_ID"`
 	ClientSecret string `env:"WORKER_AUTH0_CLIENT_SECRET"`
 }
 
+// WorkerConfig configures a worker and how the job service judges its heartbeats. Workers send a
+// heartbeat every HeartbeatInterval; one that has not been heard from for StaleAfter is stale and
+// one silent for DeadAfter is considered dead. MaxSandboxesPerUser caps how many of a worker's
+// sandboxes a single user's jobs may hold at once, 0 for no cap.
 type WorkerConfig struct {
-	ID string `env:"WORKER_ID"`
+	ID                  string        `env:"WORKER_ID"`
+	Host                string        `env:"WORKER_SERVICE_HOST"`
+	Port                string        `env:"WORKER_SERVICE_PORT"`
+	HeartbeatInterval   time.Duration `env:"WORKER_HEARTBEAT_INTERVAL" envDefault:"15s"`
+	StaleAfter          time.Duration `env:"WORKER_STALE_AFTER" envDefault:"45s"`
+	DeadAfter           time.Duration `env:"WORKER_DEAD_AFTER" envDefault:"2m"`
+	MaxSandboxesPerUser int           `env:"WORKER_MAX_SANDBOXES_PER_USER" envDefault:"0"`
+}
+
+// ReaperConfig sets how often the job service looks for executions whose worker died.
+type ReaperConfig struct {
+	Interval time.Duration `env:"REAPER_INTERVAL" envDefault:"30s"`
+}
+
+// RetryConfig sets how long a job whose execution failed waits before it runs again: BaseDelay
+// doubled for every earlier retry, capped at MaxDelay and jittered by up to half.
+type RetryConfig struct {
+	BaseDelay time.Duration `env:"RETRY_BASE_DELAY" envDefault:"3m"`
+	MaxDelay  time.Duration `env:"RETRY_MAX_DELAY" envDefault:"1h"`
+}
+
+// WebhookConfig configures webhook deliveries. Every ResumeInterval the job service resumes the
+// pending deliveries that no replica has attempted for a while, e.g. because it restarted. Each
+// replica works on at most MaxConcurrent deliveries at once. Webhooks may only point at public
+// addresses unless AllowPrivateAddresses is set, e.g. for local development.
+type WebhookConfig struct {
+	QueueName             string        `env:"WEBHOOK_QUEUE_NAME" envDefault:"djm.webhooks"`
+	MaxAttempts           int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"5"`
+	Timeout               time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+	ResumeInterval        time.Duration `env:"WEBHOOK_RESUME_INTERVAL" envDefault:"1m"`
+	MaxConcurrent         int           `env:"WEBHOOK_MAX_CONCURRENT" envDefault:"64"`
+	AllowPrivateAddresses bool          `env:"WEBHOOK_ALLOW_PRIVATE_ADDRESSES" envDefault:"false"`
+}
+
+// RateLimitConfig sets the token bucket budgets of the job service API. Rates are in requests per
+// second. Store is 'memory' to limit each replica on its own or 'cassandra' to share one budget
+// across replicas.
+type RateLimitConfig struct {
+	Enabled    bool    `env:"RATE_LIMIT_ENABLED" envDefault:"true"`
+	Store      string  `env:"RATE_LIMIT_STORE" envDefault:"memory"`
+	ReadRate   float64 `env:"RATE_LIMIT_READ_RATE" envDefault:"20"`
+	ReadBurst  int     `env:"RATE_LIMIT_READ_BURST" envDefault:"100"`
+	WriteRate  float64 `env:"RATE_LIMIT_WRITE_RATE" envDefault:"5"`
+	WriteBurst int     `env:"RATE_LIMIT_WRITE_BURST" envDefault:"20"`
+}
+
+const (
+	RateLimitStoreMemory    = "memory"
+	RateLimitStoreCassandra = "cassandra"
+)
+
+// QuotaConfig limits how much each user's jobs may consume. Active jobs are those not in a
+// terminal status. A zero limit disables the quota.
+type QuotaConfig struct {
+	MaxActiveJobs       int           `env:"QUOTA_MAX_ACTIVE_JOBS" envDefault:"100"`
+	MaxDailyExecutions  int           `env:"QUOTA_MAX_DAILY_EXECUTIONS" envDefault:"1000"`
+	MaxDailySandboxTime time.Duration `env:"QUOTA_MAX_DAILY_SANDBOX_TIME" envDefault:"4h"`
+}
+
+// CompileConfig sets how the job service asks workers to compile-check payloads. Checks that no
+// worker answers within Timeout fail.
+type CompileConfig struct {
+	QueueName string        `env:"COMPILE_QUEUE_NAME" envDefault:"djm.compile"`
+	Timeout   time.Duration `env:"COMPILE_TIMEOUT" envDefault:"30s"`
+}
+
+// TracingConfig enables exporting traces. The OTLP endpoint is set with the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_TRACES_ENDPOINT variables.
+type TracingConfig struct {
+	Enabled     bool    `env:"TRACING_ENABLED" envDefault:"false"`
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
 }
 
 type Config struct {
@@ -59,6 +180,7 @@ type Config struct {
 	SandboxCount     int    `env:"SANDBOX_COUNT"`
 	TempDir          string `env:"TEMP_DIR"`
 	WorkerID         string `env:"WORKER_ID"`
+	Auth             AuthConfig
 	Auth0            Auth0Config
 	Auth0Worker      Auth0WorkerConfig
 	Database         DatabaseConfig
@@ -67,4 +189,11 @@ type Config struct {
 	Rabbit           RabbitConfig
 	Schedule         ScheduleServiceConfig
 	Worker           WorkerConfig
+	Reaper           ReaperConfig
+	Retry            RetryConfig
+	Webhook          WebhookConfig
+	RateLimit        RateLimitConfig
+	Quota            QuotaConfig
+	Tracing          TracingConfig
+	Compile          CompileConfig
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// DeadLetter is a job message the work queue gave up on, kept until an admin replays or purges it.
+// Body is the message as it was published; JobID and UserID are empty if it could not be decoded.
+// Deliveries counts how often workers received the message before it was dead-lettered.
+type DeadLetter struct {
+	DeadLetterID   string    `json:"dead_letter_id"`
+	JobID          string    `json:"job_id"`
+	UserID         string    `json:"user_id"`
+	WorkerID       string    `json:"worker_id"`
+	Reason         string    `json:"reason"`
+	Deliveries     int       `json:"deliveries"`
+	Body           string    `json:"body"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+// DeadLetterQuery filters the dead letters of a single job.
+type DeadLetterQuery struct {
+	JobID string `form:"job_id"`
+}
+
+// DeadLetterPurge reports how many dead letters a purge removed.
+type DeadLetterPurge struct {
+	Purged int `json:"purged"`
+}
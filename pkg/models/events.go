@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// JobEvent records a job or execution state transition. Event IDs are ULIDs, so they sort in
+// the order the events were published.
+type JobEvent struct {
+	EventID     string    `json:"event_id"`
+	Type        string    `json:"type"`
+	UserID      string    `json:"user_id"`
+	JobID       string    `json:"job_id"`
+	ExecutionID string    `json:"execution_id,omitempty"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+const (
+	EventJobCreated             = "job.created"
+	EventJobStatusChanged       = "job.status_changed"
+	EventJobDeleted             = "job.deleted"
+	EventExecutionCreated       = "execution.created"
+	EventExecutionStatusChanged = "execution.status_changed"
+)
+
+var EventTypes = []string{
+	EventJobCreated,
+	EventJobStatusChanged,
+	EventJobDeleted,
+	EventExecutionCreated,
+	EventExecutionStatusChanged,
+}
@@ -1,28 +1,82 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
 
 type Job struct {
-	JobID          string    `binding:"-" json:"job_id"`
-	UserID         string    `binding:"-" json:"user_id"`
-	JobName        string    `json:"job_name"`
-	JobDescription string    `json:"job_description"`
-	JobMetadata    string    `binding:"-" json:"job_metadata"`
-	Frequency      string    `json:"frequency"`
-	Status         string    `binding:"-" json:"status"`
-	Payload        string    `json:"payload"`
-	RetryCount     int       `binding:"-" json:"retry_count"`
-	MaxRetries     int       `json:"max_retries"`
-	ExecutionTime  time.Time `json:"execution_time"`
-	CreatedAt      time.Time `binding:"-" json:"created_at"`
-	UpdatedAt      time.Time `binding:"-" json:"updated_at"`
+	JobID          string            `binding:"-" json:"job_id"`
+	UserID         string            `binding:"-" json:"user_id"`
+	JobName        string            `binding:"required,max=128" json:"job_name"`
+	JobDescription string            `binding:"max=1024" json:"job_description"`
+	JobMetadata    string            `binding:"-" json:"job_metadata"`
+	Frequency      string            `binding:"required,frequency|cron" json:"frequency"`
+	TimeZone       string            `binding:"omitempty,timezone" json:"time_zone"`
+	Labels         map[string]string `binding:"omitempty,max=32,dive,keys,min=1,max=63,endkeys,max=63" json:"labels"`
+	Project        string            `binding:"omitempty,max=64,slug" json:"project,omitempty"`
+	Status         string            `binding:"-" json:"status"`
+	Payload        string            `binding:"required" json:"payload"`
+	RetryCount     int               `binding:"-" json:"retry_count"`
+	MaxRetries     int               `binding:"gte=0,lte=10" json:"max_retries"`
+	ExecutionTime  time.Time         `binding:"required,notpast" json:"execution_time"`
+	CreatedAt      time.Time         `binding:"-" json:"created_at"`
+	UpdatedAt      time.Time         `binding:"-" json:"updated_at"`
 }
 
+// NextRun returns when a recurring job runs next after the given time, evaluating its frequency
+// in its time zone (UTC if unset) so runs keep their wall-clock time across DST changes. Named
+// frequencies repeat from the job's execution time; one-time jobs have no next run and report
+// false.
+func (j *Job) NextRun(after time.Time) (next time.Time, ok bool, err error) {
+	loc := time.UTC
+	if j.TimeZone != "" {
+		if loc, err = time.LoadLocation(j.TimeZone); err != nil {
+			return
+		}
+	}
+
+	var step func(time.Time) time.Time
+	switch j.Frequency {
+	case JobFrequencyOnce:
+		return
+	case JobFrequencyHourly:
+		step = func(t time.Time) time.Time { return t.Add(time.Hour) }
+	case JobFrequencyDaily:
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case JobFrequencyWeekly:
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	case JobFrequencyMonthly:
+		step = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	default:
+		schedule, cronErr := ParseCron(j.Frequency)
+		if cronErr != nil {
+			err = cronErr
+			return
+		}
+		return schedule.Next(after.In(loc)).UTC(), true, nil
+	}
+
+	next = j.ExecutionTime.In(loc)
+	for !next.After(after) {
+		next = step(next)
+	}
+	return next.UTC(), true, nil
+}
+
+// ParseCron parses a standard five-field cron expression or descriptor such as '@daily'.
+func ParseCron(expr string) (cron.Schedule, error) {
+	return cronParser.Parse(expr)
+}
+
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
 func (j *Job) GetJobFrequencyIntervalSeconds() int {
 	switch j.Frequency {
-	case "hourly":
+	case JobFrequencyHourly:
 		return int(time.Hour.Seconds())
-	case "daily":
+	case JobFrequencyDaily:
 		return int(time.Hour.Seconds() * 24)
 	}
 	return -1
@@ -38,26 +92,59 @@ const (
 	JobStatusFailed     = "failed"
 )
 
+// IsTerminalStatus reports whether a job or execution in status will not change again on its own.
+func IsTerminalStatus(status string) bool {
+	return status == JobStatusCompleted || status == JobStatusFailed || status == JobStatusCancelled
+}
+
+var JobStatuses = []string{
+	JobStatusReady,
+	JobStatusPending,
+	JobStatusScheduled,
+	JobStatusInProgress,
+	JobStatusCompleted,
+	JobStatusCancelled,
+	JobStatusFailed,
+}
+
 const (
 	JobFrequencyOnce    = "one-time"
+	JobFrequencyHourly  = "hourly"
 	JobFrequencyDaily   = "daily"
 	JobFrequencyWeekly  = "weekly"
 	JobFrequencyMonthly = "monthly"
 )
 
+var JobFrequencies = []string{
+	JobFrequencyOnce,
+	JobFrequencyHourly,
+	JobFrequencyDaily,
+	JobFrequencyWeekly,
+	JobFrequencyMonthly,
+}
+
 type JobUpdateRequest struct {
-	JobName        *string    `json:"job_name"`
-	JobDescription *string    `json:"job_description"`
-	Frequency      *string    `json:"frequency"`
-	Status         *string    `json:"status"`
-	Payload        *string    `json:"payload"`
-	MaxRetries     *int       `json:"max_retries"`
-	ExecutionTime  *time.Time `json:"execution_time"`
+	JobName        *string            `binding:"omitempty,min=1,max=128" json:"job_name"`
+	JobDescription *string            `binding:"omitempty,max=1024" json:"job_description"`
+	Frequency      *string            `binding:"omitempty,frequency|cron" json:"frequency"`
+	TimeZone       *string            `binding:"omitempty,timezone" json:"time_zone"`
+	Labels         *map[string]string `binding:"omitempty,max=32,dive,keys,min=1,max=63,endkeys,max=63" json:"labels"`
+	Project        *string            `binding:"omitempty,max=64,slug" json:"project"`
+	Status         *string            `binding:"omitempty,jobstatus" json:"status"`
+	Payload        *string            `binding:"omitempty,min=1" json:"payload"`
+	MaxRetries     *int               `binding:"omitempty,gte=0,lte=10" json:"max_retries"`
+	ExecutionTime  *time.Time         `binding:"omitempty,notpast" json:"execution_time"`
+}
+
+// OnlyStatus reports whether the update changes nothing but the job's status.
+func (u JobUpdateRequest) OnlyStatus() bool {
+	return u.Status != nil && u.JobName == nil && u.JobDescription == nil && u.Frequency == nil && u.TimeZone == nil &&
+		u.Labels == nil && u.Project == nil && u.Payload == nil && u.MaxRetries == nil && u.ExecutionTime == nil
 }
 
 type JobSchedule struct {
-	JobID       string    `json:"job_id"`
-	NextRunTime time.Time `json:"next_run_time"`
+	JobID       string    `binding:"required" json:"job_id"`
+	NextRunTime time.Time `binding:"required" json:"next_run_time"`
 	LastRunTime time.Time `json:"last_run_time"`
 }
 
@@ -66,9 +153,12 @@ type JobScheduleUpdateRequest struct {
 	LastRunTime *time.Time `json:"last_run_time"`
 }
 
+// JobExecution is a single run of a job. Attempt numbers the runs of the job: the first run is
+// attempt 1 and every retry of a failed run adds one.
 type JobExecution struct {
 	ExecutionID  string    `binding:"-" json:"execution_id"`
-	JobID        string    `json:"job_id"`
+	JobID        string    `binding:"required" json:"job_id"`
+	Attempt      int       `binding:"-" json:"attempt"`
 	WorkerID     string    `json:"worker_id"`
 	StartTime    time.Time `json:"start_time"`
 	EndTime      time.Time `json:"end_time"`
@@ -77,19 +167,23 @@ type JobExecution struct {
 	ErrorMessage string    `json:"error_message"`
 }
 
+// ExecutionErrorWorkerLost is the error message of executions failed because their worker stopped
+// sending heartbeats before reporting a result.
+const ExecutionErrorWorkerLost = "worker lost"
+
+// ExecutionOutputChunk is a piece of an execution's output, streamed while the job runs. Seq
+// orders the chunks of an execution.
+type ExecutionOutputChunk struct {
+	ExecutionID string    `binding:"-" json:"execution_id"`
+	Seq         int       `binding:"gte=0" json:"seq"`
+	Data        string    `binding:"required" json:"data"`
+	CreatedAt   time.Time `binding:"-" json:"created_at"`
+}
+
 type JobExecutionUpdateRequest struct {
 	StartTime    *time.Time `json:"start_time"`
 	EndTime      *time.Time `json:"end_time"`
-	Status       *string    `json:"status"`
+	Status       *string    `binding:"omitempty,jobstatus" json:"status"`
 	Output       *string    `json:"output"`
 	ErrorMessage *string    `json:"error_message"`
 }
-
-// type WorkerNode struct {
-// 	WorkerID      string
-// 	IPAddress     string
-// 	Status        string
-// 	LastHeartbeat string
-// 	Capacity      int
-// 	CurrentLoad   int
-// }
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// ManifestVersion is the version of the job manifest format.
+const ManifestVersion = "djm/v1"
+
+// JobManifest declares a job so it can be kept in version control. Name identifies the job among
+// its owner's jobs and is its job name, so applying the same manifests again changes nothing.
+type JobManifest struct {
+	Version     string            `binding:"omitempty,eq=djm/v1" json:"version,omitempty" yaml:"version,omitempty"`
+	Name        string            `binding:"required,max=128" json:"name" yaml:"name"`
+	Description string            `binding:"max=1024" json:"description,omitempty" yaml:"description,omitempty"`
+	Project     string            `binding:"omitempty,max=64,slug" json:"project,omitempty" yaml:"project,omitempty"`
+	Labels      map[string]string `binding:"omitempty,max=32,dive,keys,min=1,max=63,endkeys,max=63" json:"labels,omitempty" yaml:"labels,omitempty"`
+	Schedule    ManifestSchedule  `json:"schedule" yaml:"schedule"`
+	Limits      ManifestLimits    `json:"limits" yaml:"limits"`
+	Payload     string            `binding:"required" json:"payload" yaml:"payload"`
+}
+
+// ManifestSchedule is when a job runs. Start is the job's execution time; it may be in the past
+// for jobs that already exist, but not for ones the manifest creates.
+type ManifestSchedule struct {
+	Frequency string    `binding:"required,frequency|cron" json:"frequency" yaml:"frequency"`
+	TimeZone  string    `binding:"omitempty,timezone" json:"time_zone,omitempty" yaml:"time_zone,omitempty"`
+	Start     time.Time `binding:"required" json:"start" yaml:"start"`
+}
+
+type ManifestLimits struct {
+	MaxRetries int `binding:"gte=0,lte=10" json:"max_retries" yaml:"max_retries"`
+}
+
+// ManifestSet is a user's job manifests, as exported and applied.
+type ManifestSet struct {
+	Manifests []JobManifest `binding:"dive" json:"manifests" yaml:"manifests"`
+}
+
+// ManifestApplyRequest applies a set of manifests. With Prune, jobs of the caller that no
+// manifest names are deleted.
+type ManifestApplyRequest struct {
+	Manifests []JobManifest `binding:"dive" json:"manifests" yaml:"manifests"`
+	Prune     bool          `json:"prune" yaml:"prune"`
+}
+
+const (
+	ManifestActionCreate    = "create"
+	ManifestActionUpdate    = "update"
+	ManifestActionDelete    = "delete"
+	ManifestActionUnchanged = "unchanged"
+)
+
+// ManifestFieldChange is a manifest field whose value differs from the job's.
+type ManifestFieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// ManifestChange is what applying a manifest does to a job. JobID is empty for creates until
+// they are applied.
+type ManifestChange struct {
+	Action string                `json:"action"`
+	Name   string                `json:"name"`
+	JobID  string                `json:"job_id,omitempty"`
+	Fields []ManifestFieldChange `json:"fields,omitempty"`
+}
+
+// ManifestPlan lists the changes applying a set of manifests makes, and whether they were made.
+type ManifestPlan struct {
+	Applied bool             `json:"applied"`
+	Changes []ManifestChange `json:"changes"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// PageRequest selects a single page of a list endpoint. A zero Limit returns every row.
+type PageRequest struct {
+	Limit int    `form:"limit" binding:"omitempty,gte=1,lte=500" json:"limit"`
+	Token string `form:"page_token" json:"page_token"`
+}
+
+// PageQueryParams are the query parameters consumed by PageRequest.
+var PageQueryParams = []string{"limit", "page_token"}
@@ -0,0 +1,40 @@
+package models
+
+// PayloadDiagnostic is a problem found in a job payload. Block and Line are 1-based; a zero
+// Block refers to the payload as a whole and a zero Line to the whole block.
+type PayloadDiagnostic struct {
+	Block    int    `json:"block,omitempty"`
+	Language string `json:"language,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+// PayloadValidation is the result of checking a job payload without saving it.
+type PayloadValidation struct {
+	Valid       bool                `json:"valid"`
+	Blocks      int                 `json:"blocks"`
+	Diagnostics []PayloadDiagnostic `json:"diagnostics"`
+}
+
+type PayloadValidationRequest struct {
+	Payload string `binding:"required" json:"payload"`
+}
+
+// CompileBlock is a code block sent to a worker to be compile-checked.
+type CompileBlock struct {
+	Language string `json:"language"`
+	Content  string `json:"content"`
+}
+
+// CompileRequest asks a worker to compile-check code blocks.
+type CompileRequest struct {
+	Blocks []CompileBlock `json:"blocks"`
+}
+
+// CompileResponse carries the compiler diagnostics of a CompileRequest, or the error that kept
+// the worker from checking it.
+type CompileResponse struct {
+	Diagnostics []PayloadDiagnostic `json:"diagnostics"`
+	Error       string              `json:"error,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// UsageDayFormat formats the UTC day a usage counter covers.
+const UsageDayFormat = "2006-01-02"
+
+// UsageCounter is the consumption of a user's jobs on one UTC day.
+type UsageCounter struct {
+	UserID     string
+	Day        string
+	Executions int64
+	SandboxMs  int64
+}
+
+// QuotaUsage is how much of a quota has been used. A zero Limit means the quota is unlimited.
+type QuotaUsage struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit"`
+}
+
+// Exhausted reports whether nothing more may be used under the quota.
+func (q QuotaUsage) Exhausted() bool {
+	return q.Limit > 0 && q.Used >= q.Limit
+}
+
+// Usage is a user's current usage against each of their quotas. Daily quotas reset at midnight UTC.
+type Usage struct {
+	UserID              string     `json:"user_id"`
+	Day                 string     `json:"day"`
+	ActiveJobs          QuotaUsage `json:"active_jobs"`
+	DailyExecutions     QuotaUsage `json:"daily_executions"`
+	DailySandboxSeconds QuotaUsage `json:"daily_sandbox_seconds"`
+}
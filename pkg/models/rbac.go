@@ -0,0 +1,80 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Roles a grant may give on a job or project, ordered from least to most privileged. Each role
+// includes the permissions of the ones before it:
+//   - viewer reads the job, its schedule, executions and logs
+//   - operator also runs, pauses and resumes the job
+//   - editor also changes the job's definition and schedule
+//   - admin also deletes the job and manages its grants
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleEditor   = "editor"
+	RoleAdmin    = "admin"
+)
+
+var Roles = []string{
+	RoleViewer,
+	RoleOperator,
+	RoleEditor,
+	RoleAdmin,
+}
+
+// RoleAtLeast reports whether role carries the permissions of required.
+func RoleAtLeast(role string, required string) bool {
+	have := slices.Index(Roles, role)
+	return have >= 0 && have >= slices.Index(Roles, required)
+}
+
+// HigherRole returns the more privileged of two roles, either of which may be empty.
+func HigherRole(a string, b string) string {
+	if slices.Index(Roles, b) > slices.Index(Roles, a) {
+		return b
+	}
+	return a
+}
+
+const (
+	GrantResourceJob     = "job"
+	GrantResourceProject = "project"
+
+	GrantSubjectUser  = "user"
+	GrantSubjectGroup = "group"
+)
+
+// JobGrant gives a user or group a role on a single job, or on every job in a project.
+type JobGrant struct {
+	ResourceType string    `binding:"-" json:"resource_type"`
+	ResourceID   string    `binding:"-" json:"resource_id"`
+	SubjectType  string    `binding:"required,oneof=user group" json:"subject_type"`
+	SubjectID    string    `binding:"required,max=256" json:"subject_id"`
+	Role         string    `binding:"required,role" json:"role"`
+	CreatedBy    string    `binding:"-" json:"created_by"`
+	CreatedAt    time.Time `binding:"-" json:"created_at"`
+}
+
+// Principal is the caller a repository authorizes reads and mutations for.
+type Principal struct {
+	UserID  string
+	Groups  []string
+	IsAdmin bool
+}
+
+// SystemPrincipal is used by the job service itself for lookups made on no caller's behalf.
+var SystemPrincipal = Principal{IsAdmin: true}
+
+// Matches reports whether a grant was given to the principal or one of its groups.
+func (p Principal) Matches(grant JobGrant) bool {
+	switch grant.SubjectType {
+	case GrantSubjectUser:
+		return grant.SubjectID == p.UserID
+	case GrantSubjectGroup:
+		return slices.Contains(p.Groups, grant.SubjectID)
+	}
+	return false
+}
@@ -12,6 +12,9 @@ var (
 			"job_description",
 			"job_metadata",
 			"frequency",
+			"time_zone",
+			"labels",
+			"project",
 			"status",
 			"payload",
 			"retry_count",
@@ -45,10 +48,36 @@ var (
 	})
 
 	JobExecutions = table.New(table.Metadata{
+		Name: "job_execution_attempts",
+		Columns: []string{
+			"execution_id",
+			"job_id",
+			"attempt",
+			"worker_id",
+			"start_time",
+			"end_time",
+			"status",
+			"output",
+			"error_message",
+		},
+		PartKey: []string{
+			"job_id",
+		},
+		SortKey: []string{
+			"worker_id",
+			"status",
+			"execution_id",
+		},
+	})
+
+	// LegacyJobExecutions is where executions were stored before JobExecutions, keeping only the
+	// latest of a job's executions on a worker in each status. jobsvc copies them over on start.
+	LegacyJobExecutions = table.New(table.Metadata{
 		Name: "job_executions",
 		Columns: []string{
 			"execution_id",
 			"job_id",
+			"attempt",
 			"worker_id",
 			"start_time",
 			"end_time",
@@ -64,4 +93,207 @@ var (
 			"status",
 		},
 	})
+
+	JobEvents = table.New(table.Metadata{
+		Name: "job_events",
+		Columns: []string{
+			"user_id",
+			"event_id",
+			"type",
+			"job_id",
+			"execution_id",
+			"status",
+			"created_at",
+		},
+		PartKey: []string{
+			"user_id",
+		},
+		SortKey: []string{
+			"event_id",
+		},
+	})
+
+	ExecutionOutput = table.New(table.Metadata{
+		Name: "execution_output",
+		Columns: []string{
+			"execution_id",
+			"seq",
+			"data",
+			"created_at",
+		},
+		PartKey: []string{
+			"execution_id",
+		},
+		SortKey: []string{
+			"seq",
+		},
+	})
+
+	Webhooks = table.New(table.Metadata{
+		Name: "webhooks",
+		Columns: []string{
+			"user_id",
+			"webhook_id",
+			"url",
+			"job_id",
+			"label_selector",
+			"event_types",
+			"secret",
+			"created_at",
+		},
+		PartKey: []string{
+			"user_id",
+		},
+		SortKey: []string{
+			"webhook_id",
+		},
+	})
+
+	WebhookDeliveries = table.New(table.Metadata{
+		Name: "webhook_deliveries",
+		Columns: []string{
+			"webhook_id",
+			"delivery_id",
+			"event_id",
+			"event_type",
+			"payload",
+			"status",
+			"attempts",
+			"status_code",
+			"error_message",
+			"created_at",
+			"updated_at",
+		},
+		PartKey: []string{
+			"webhook_id",
+		},
+		SortKey: []string{
+			"delivery_id",
+		},
+	})
+
+	APIKeys = table.New(table.Metadata{
+		Name: "api_keys",
+		Columns: []string{
+			"key_id",
+			"user_id",
+			"subject",
+			"name",
+			"key_hash",
+			"scopes",
+			"expires_at",
+			"last_used_at",
+			"revoked_at",
+			"created_at",
+		},
+		PartKey: []string{
+			"key_id",
+		},
+	})
+
+	JobGrants = table.New(table.Metadata{
+		Name: "job_grants",
+		Columns: []string{
+			"resource_type",
+			"resource_id",
+			"subject_type",
+			"subject_id",
+			"role",
+			"created_by",
+			"created_at",
+		},
+		PartKey: []string{
+			"resource_type",
+			"resource_id",
+		},
+		SortKey: []string{
+			"subject_type",
+			"subject_id",
+		},
+	})
+
+	RateLimits = table.New(table.Metadata{
+		Name: "rate_limits",
+		Columns: []string{
+			"bucket_key",
+			"tokens",
+			"updated_at",
+		},
+		PartKey: []string{
+			"bucket_key",
+		},
+	})
+
+	UsageCounters = table.New(table.Metadata{
+		Name: "usage_counters",
+		Columns: []string{
+			"user_id",
+			"day",
+			"executions",
+			"sandbox_ms",
+		},
+		PartKey: []string{
+			"user_id",
+			"day",
+		},
+	})
+
+	AuditLog = table.New(table.Metadata{
+		Name: "audit_log",
+		Columns: []string{
+			"resource_type",
+			"resource_id",
+			"created_at",
+			"entry_id",
+			"action",
+			"actor_id",
+			"actor_source",
+			"api_key_id",
+			"request_id",
+			"before",
+			"after",
+		},
+		PartKey: []string{
+			"resource_type",
+			"resource_id",
+		},
+		SortKey: []string{
+			"created_at",
+			"entry_id",
+		},
+	})
+
+	Workers = table.New(table.Metadata{
+		Name: "workers",
+		Columns: []string{
+			"worker_id",
+			"host",
+			"capacity",
+			"languages",
+			"version",
+			"current_load",
+			"registered_at",
+			"last_heartbeat",
+		},
+		PartKey: []string{
+			"worker_id",
+		},
+	})
+
+	DeadLetters = table.New(table.Metadata{
+		Name: "dead_letters",
+		Columns: []string{
+			"dead_letter_id",
+			"job_id",
+			"user_id",
+			"worker_id",
+			"reason",
+			"deliveries",
+			"body",
+			"dead_lettered_at",
+		},
+		PartKey: []string{
+			"dead_letter_id",
+		},
+	})
 )
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Webhook subscribes a URL to the events of a single job, of the jobs matching a label
+// selector, or of all of a user's jobs when neither is set.
+type Webhook struct {
+	WebhookID     string    `binding:"-" json:"webhook_id"`
+	UserID        string    `binding:"-" json:"user_id"`
+	URL           string    `binding:"required,http_url" json:"url"`
+	JobID         string    `binding:"excluded_with=LabelSelector" json:"job_id,omitempty"`
+	LabelSelector string    `binding:"omitempty,labelselector" json:"label_selector,omitempty"`
+	EventTypes    []string  `binding:"required,min=1,dive,eventtype" json:"event_types"`
+	Secret        string    `binding:"-" json:"secret,omitempty"`
+	CreatedAt     time.Time `binding:"-" json:"created_at"`
+}
+
+// WebhookDelivery records the delivery of an event to a webhook, including every retry.
+type WebhookDelivery struct {
+	WebhookID    string    `json:"webhook_id"`
+	DeliveryID   string    `json:"delivery_id"`
+	EventID      string    `json:"event_id"`
+	EventType    string    `json:"event_type"`
+	Payload      string    `json:"payload"`
+	Status       string    `json:"status"`
+	Attempts     int       `json:"attempts"`
+	StatusCode   int       `json:"status_code"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusFailed    = "failed"
+)
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// WorkerNode is a worker registered with the job service. Workers register on startup and then
+// report their load with periodic heartbeats; Health is derived from how long ago the last one
+// arrived and is not stored.
+type WorkerNode struct {
+	WorkerID      string    `json:"worker_id"`
+	Host          string    `json:"host"`
+	Capacity      int       `json:"capacity"`
+	Languages     []string  `json:"languages"`
+	Version       string    `json:"version"`
+	CurrentLoad   int       `json:"current_load"`
+	RegisteredAt  time.Time `json:"registered_at"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	Health        string    `json:"health"`
+}
+
+// WorkerRegistration is what a worker reports about itself when it starts. Capacity is the
+// number of sandboxes it runs jobs in.
+type WorkerRegistration struct {
+	Host      string   `binding:"required,max=255" json:"host"`
+	Capacity  int      `binding:"gte=0" json:"capacity"`
+	Languages []string `binding:"dive,required" json:"languages"`
+	Version   string   `binding:"max=64" json:"version"`
+}
+
+// WorkerHeartbeat reports that a worker is alive and how many of its sandboxes are in use.
+type WorkerHeartbeat struct {
+	CurrentLoad int `binding:"gte=0" json:"current_load"`
+}
+
+const (
+	WorkerHealthHealthy = "healthy"
+	WorkerHealthStale   = "stale"
+	WorkerHealthDead    = "dead"
+)
+
+// WorkerHealth returns the health of a worker whose last heartbeat arrived at lastHeartbeat: stale
+// once it is older than StaleAfter and dead once it is older than DeadAfter.
+func (c WorkerConfig) WorkerHealth(lastHeartbeat time.Time, now time.Time) string {
+	age := now.Sub(lastHeartbeat)
+	switch {
+	case age > c.DeadAfter:
+		return WorkerHealthDead
+	case age > c.StaleAfter:
+		return WorkerHealthStale
+	}
+	return WorkerHealthHealthy
+}
@@ -0,0 +1,144 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Headers set on job messages by the worker that gives up on them.
+const (
+	// HeaderDeliveries counts how often a job message was delivered and sent back to the queue.
+	HeaderDeliveries = "x-djm-deliveries"
+	// HeaderDeadLetterReason says why a job message was dead-lettered.
+	HeaderDeadLetterReason = "x-djm-dead-letter-reason"
+	// HeaderWorkerID names the worker that dead-lettered a job message.
+	HeaderWorkerID = "x-djm-worker-id"
+)
+
+// RequeueDelay is how long a job sent back to the work queue waits in the delay queue, so that a
+// failing dependency is not retried in a tight loop.
+const RequeueDelay = 5 * time.Second
+
+// DeadLetterPolicy names the broker policy that dead-letters messages dropped from the work queue.
+// The broker applies only one policy to a queue, so an operator policy that matches the work queue
+// with a higher priority must set the dead-letter exchange itself.
+const DeadLetterPolicy = "djm-dead-letter"
+
+// policyTimeout bounds the management API request that sets DeadLetterPolicy.
+const policyTimeout = 10 * time.Second
+
+// DeclareWorkQueue declares the exchange jobs are published to and the queue workers consume them
+// from, along with the dead-letter exchange and queue and the delay queue. Messages the broker
+// drops from the work queue, such as ones rejected without requeueing, are routed to the
+// dead-letter exchange too. Messages in the delay queue move on to the work exchange once they
+// have waited RequeueDelay.
+//
+// The dead-letter exchange is set through DeadLetterPolicy rather than a queue argument: the
+// arguments of an existing queue cannot change, and declaring it with different ones fails, so
+// work queues declared before dead-lettering existed would otherwise have to be deleted. Only the
+// job service applies the policy, with ApplyDeadLetterPolicy, so no other service needs access to
+// the management API.
+func DeclareWorkQueue(ch *amqp091.Channel, conf *models.Config) error {
+	rabbit := conf.Rabbit
+
+	if err := ch.ExchangeDeclare(rabbit.DeadLetterExchange, amqp091.ExchangeFanout, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("unable to declare dead-letter exchange: %w", err)
+	}
+	if _, err := ch.QueueDeclare(rabbit.DeadLetterQueue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("unable to declare dead-letter queue: %w", err)
+	}
+	if err := ch.QueueBind(rabbit.DeadLetterQueue, "", rabbit.DeadLetterExchange, false, nil); err != nil {
+		return fmt.Errorf("unable to bind dead-letter queue: %w", err)
+	}
+
+	if err := ch.ExchangeDeclare(rabbit.Name, amqp091.ExchangeFanout, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("unable to declare work exchange: %w", err)
+	}
+	if _, err := ch.QueueDeclare(rabbit.Name, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("unable to declare work queue: %w", err)
+	}
+	if err := ch.QueueBind(rabbit.Name, "", rabbit.Name, false, nil); err != nil {
+		return fmt.Errorf("unable to bind work queue: %w", err)
+	}
+
+	delayArgs := amqp091.Table{
+		"x-message-ttl":          RequeueDelay.Milliseconds(),
+		"x-dead-letter-exchange": rabbit.Name,
+	}
+	if _, err := ch.QueueDeclare(rabbit.DelayQueue, true, false, false, false, delayArgs); err != nil {
+		return fmt.Errorf("unable to declare delay queue: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyDeadLetterPolicy creates or updates DeadLetterPolicy through the broker's management API.
+// Deployments that manage broker policies themselves can define the same policy instead.
+func ApplyDeadLetterPolicy(conf *models.Config) error {
+	rabbit := conf.Rabbit
+
+	endpoint := rabbit.ManagementURL
+	if endpoint == "" {
+		endpoint = "http://" + net.JoinHostPort(rabbit.Host, "15672")
+	}
+	// services connect to the default vhost
+	endpoint = strings.TrimSuffix(endpoint, "/") + "/api/policies/%2F/" + DeadLetterPolicy
+
+	body, err := json.Marshal(map[string]any{
+		"pattern":    "^" + regexp.QuoteMeta(rabbit.Name) + "$",
+		"apply-to":   "queues",
+		"definition": map[string]any{"dead-letter-exchange": rabbit.DeadLetterExchange},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to encode dead-letter policy: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), policyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create dead-letter policy request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(rabbit.Username, rabbit.Password)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to set dead-letter policy: %w", err)
+	}
+	defer res.Body.Close()
+
+	// 201 when the policy is created, 204 when it already existed
+	if res.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unable to set dead-letter policy: %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// Deliveries returns how often a job message was delivered before, as counted in its
+// HeaderDeliveries header.
+func Deliveries(headers amqp091.Table) int {
+	switch n := headers[HeaderDeliveries].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
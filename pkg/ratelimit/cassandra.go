@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/scylladb/gocqlx/v3/qb"
+)
+
+// casAttempts is how many times CassandraStore retries a bucket update that lost a race with
+// another replica before rejecting the request.
+const casAttempts = 3
+
+// CassandraStore keeps buckets in the rate_limits table so every replica shares one budget.
+// Buckets are updated with lightweight transactions and expire once they would have refilled.
+type CassandraStore struct {
+	db *store.DBSession
+}
+
+func NewCassandraStore(db *store.DBSession) *CassandraStore {
+	return &CassandraStore{db: db}
+}
+
+func (s *CassandraStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	ttl := fullAfter(limit) + time.Second
+	selectStmt, selectNames := qb.Select(models.RateLimits.Name()).Columns("tokens", "updated_at").Where(qb.Eq("bucket_key")).ToCql()
+	insertStmt, insertNames := qb.Insert(models.RateLimits.Name()).Columns("bucket_key", "tokens", "updated_at").Unique().TTL(ttl).ToCql()
+	updateStmt, updateNames := qb.Update(models.RateLimits.Name()).Set("tokens", "updated_at").Where(qb.Eq("bucket_key")).If(qb.EqNamed("updated_at", "previous_updated_at")).TTL(ttl).ToCql()
+
+	var res Result
+	for range casAttempts {
+		var rows []bucket
+		if err := s.db.Client.Query(selectStmt, selectNames).WithContext(ctx).Bind(key).SelectRelease(&rows); err != nil {
+			return Result{}, err
+		}
+
+		var b, previous bucket
+		if len(rows) > 0 {
+			b = rows[0]
+			previous = b
+		}
+
+		// Cassandra stores timestamps with millisecond precision
+		res = b.take(time.Now().UTC().Truncate(time.Millisecond), limit)
+
+		var applied bool
+		var err error
+		if previous.UpdatedAt.IsZero() {
+			applied, err = s.db.Client.Query(insertStmt, insertNames).WithContext(ctx).Bind(key, b.Tokens, b.UpdatedAt).ExecCASRelease()
+		} else {
+			applied, err = s.db.Client.Query(updateStmt, updateNames).WithContext(ctx).BindMap(map[string]any{
+				"tokens":              b.Tokens,
+				"updated_at":          b.UpdatedAt,
+				"bucket_key":          key,
+				"previous_updated_at": previous.UpdatedAt,
+			}).ExecCASRelease()
+		}
+		if err != nil {
+			return Result{}, err
+		}
+		if applied {
+			return res, nil
+		}
+	}
+
+	// a bucket this contended is being drained by concurrent requests, which is what the limit is
+	// for, so the request is rejected rather than let through as if the store had failed
+	return Result{Remaining: res.Remaining, RetryAfter: secondsToDuration(1 / limit.Rate), Reset: res.Reset}, nil
+}
@@ -0,0 +1,74 @@
+// Package ratelimit implements token bucket rate limiting with pluggable bucket storage.
+//
+// MemoryStore keeps buckets in-process, so each replica enforces its own budget. CassandraStore
+// keeps them in a shared table updated with lightweight transactions, so every replica draws from
+// the same budget.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second up to Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result describes the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// RetryAfter is how long until a token is available when the request was not allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store takes tokens from the buckets it holds. Take only returns an error if the store itself
+// fails; a request that cannot get a token, for whatever reason, is a Result that is not Allowed.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket is the persisted state of a token bucket.
+type bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// take refills b for the time elapsed since it was last updated and tries to take a token.
+func (b *bucket) take(now time.Time, limit Limit) Result {
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = float64(limit.Burst)
+	} else if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed*limit.Rate)
+	}
+	b.UpdatedAt = now
+
+	var res Result
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - b.Tokens) / limit.Rate)
+	}
+
+	res.Remaining = int(b.Tokens)
+	res.Reset = secondsToDuration((float64(limit.Burst) - b.Tokens) / limit.Rate)
+
+	return res
+}
+
+// fullAfter is how long an untouched bucket takes to refill completely, after which its state
+// no longer matters and it can be forgotten.
+func fullAfter(limit Limit) time.Duration {
+	return secondsToDuration(float64(limit.Burst) / limit.Rate)
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
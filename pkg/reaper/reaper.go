@@ -0,0 +1,141 @@
+// Package reaper fails executions whose worker died before reporting a result.
+//
+// Workers send heartbeats while they run. Every interval the reaper looks for registered workers
+// that have been silent for longer than the dead-after period, fails their unfinished executions
+// with a 'worker lost' reason and applies each job's retry policy as for any failed execution.
+// Executions are claimed with a lightweight transaction, so every jobsvc replica can run a reaper
+// without reaping an execution twice.
+package reaper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/events"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/metrics"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
+	"github.com/julianstephens/distributed-job-manager/pkg/utils"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type Reaper struct {
+	conf       *models.Config
+	workers    *repository.WorkerRepository
+	executions *repository.ExecutionRepository
+	jobs       *repository.JobRepository
+	bus        *events.Bus
+	logger     *graylogger.GrayLogger
+}
+
+func NewReaper(conf *models.Config, workers *repository.WorkerRepository, executions *repository.ExecutionRepository, jobs *repository.JobRepository, bus *events.Bus, logger *graylogger.GrayLogger) *Reaper {
+	return &Reaper{
+		conf:       conf,
+		workers:    workers,
+		executions: executions,
+		jobs:       jobs,
+		bus:        bus,
+		logger:     logger,
+	}
+}
+
+// Run reaps the executions of dead workers every interval until ctx is cancelled. Failed passes
+// are logged and retried on the next tick.
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.conf.Reaper.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_ = r.Reap(ctx)
+	}
+}
+
+// Reap fails the unfinished executions of every dead worker and retries their jobs.
+func (r *Reaper) Reap(ctx context.Context) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "reaper.reap")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	workers, err := r.workers.WithContext(ctx).GetWorkers()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, worker := range *workers {
+		if r.conf.Worker.WorkerHealth(worker.LastHeartbeat, now) != models.WorkerHealthDead {
+			continue
+		}
+
+		executions, err := r.executions.WithContext(ctx).GetUnfinishedWorkerExecutions(worker.WorkerID)
+		if err != nil {
+			return err
+		}
+
+		for _, exec := range *executions {
+			r.reapExecution(ctx, exec, worker.LastHeartbeat)
+		}
+	}
+
+	return nil
+}
+
+// reapExecution fails an execution of a dead worker and applies its job's retry policy. Failures
+// are logged so that one bad execution does not hold up the rest.
+func (r *Reaper) reapExecution(ctx context.Context, exec models.JobExecution, lastHeartbeat time.Time) {
+	log := r.logger.WithContext(ctx)
+
+	failed, err := r.executions.WithContext(ctx).FailOrphanedExecution(exec, models.ExecutionErrorWorkerLost)
+	if errors.Is(err, repository.ErrExecutionChanged) {
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to reap execution %s of worker %s", exec.ExecutionID, exec.WorkerID), &err)
+		return
+	}
+
+	log.Info(fmt.Sprintf("failed execution %s of job %s: worker %s last sent a heartbeat at %s", exec.ExecutionID, exec.JobID, exec.WorkerID, lastHeartbeat.Format(time.RFC3339)), nil)
+
+	job, err := r.jobs.WithContext(ctx).GetJob(exec.JobID, models.SystemPrincipal)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to get job %s of reaped execution %s", exec.JobID, exec.ExecutionID), &err)
+		return
+	}
+
+	r.publish(ctx, models.JobEvent{Type: models.EventExecutionStatusChanged, UserID: job.UserID, JobID: job.JobID, ExecutionID: failed.ExecutionID, Status: failed.Status})
+
+	job, changed, err := r.jobs.WithContext(ctx).RetryJob(job.JobID, r.conf.Retry)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to apply retry policy of job %s after losing execution %s", exec.JobID, exec.ExecutionID), &err)
+		return
+	}
+	// the job was cancelled, or moved on, while the execution was running
+	if !changed {
+		return
+	}
+
+	metrics.ReapedExecutions.WithLabelValues(utils.If(job.Status == models.JobStatusFailed, metrics.ReapedFailed, metrics.ReapedRequeued)).Inc()
+	r.publish(ctx, models.JobEvent{Type: models.EventJobStatusChanged, UserID: job.UserID, JobID: job.JobID, Status: job.Status})
+}
+
+// publish broadcasts a transition made by the reaper, logging failures.
+func (r *Reaper) publish(ctx context.Context, event models.JobEvent) {
+	if err := r.bus.Publish(ctx, event); err != nil {
+		r.logger.Error(fmt.Sprintf("failed to publish %s event for job %s", event.Type, event.JobID), &err)
+	}
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/oklog/ulid/v2"
+	"github.com/scylladb/gocqlx/v3/qb"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidAPIKey  = errors.New("api key is invalid")
+)
+
+type APIKeyRepository struct {
+	Repository
+}
+
+func NewAPIKeyRepository(db *store.DBSession, logger *graylogger.GrayLogger) *APIKeyRepository {
+	return &APIKeyRepository{
+		Repository{
+			DB:     db,
+			Logger: logger,
+		},
+	}
+}
+
+// WithContext returns a copy of the repository whose queries and logs are part of the trace in ctx.
+func (r *APIKeyRepository) WithContext(ctx context.Context) *APIKeyRepository {
+	return &APIKeyRepository{r.Repository.withContext(ctx)}
+}
+
+// GetAPIKeys retrieves the API keys created by a user, or by every user if the user is an admin.
+func (r *APIKeyRepository) GetAPIKeys(userId string, isAdmin bool) (keys *[]models.APIKey, err error) {
+	q := qb.Select(models.APIKeys.Name())
+	if !isAdmin {
+		q.Where(qb.Eq("user_id")).AllowFiltering()
+	}
+
+	stmt, names := q.ToCql()
+	transaction := r.query(stmt, names)
+	if !isAdmin {
+		transaction.Bind(userId)
+	}
+
+	var res []models.APIKey
+	if err = transaction.SelectRelease(&res); err != nil {
+		r.Logger.ErrorWithData("unable to get api keys", &err, &map[string]any{
+			"userId":  userId,
+			"isAdmin": isAdmin,
+		})
+		err = errors.New("unable to get api keys")
+		return
+	}
+
+	keys = &res
+
+	return
+}
+
+// GetAPIKey retrieves an API key by its ID if it was created by the user or the user is an admin.
+func (r *APIKeyRepository) GetAPIKey(keyId string, userId string, isAdmin bool) (key *models.APIKey, err error) {
+	var res []models.APIKey
+	if err = r.query(models.APIKeys.Select()).Bind(keyId).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get api key %s", keyId), &err)
+		err = fmt.Errorf("unable to get api key %s", keyId)
+		return
+	}
+
+	if len(res) == 0 || (!isAdmin && res[0].UserID != userId) {
+		err = ErrAPIKeyNotFound
+		return
+	}
+
+	key = &res[0]
+
+	return
+}
+
+// CreateAPIKey generates a new API key for a user, or for one of the user's service accounts.
+// The plaintext key is only available on the returned response.
+func (r *APIKeyRepository) CreateAPIKey(keyData models.APIKeyCreateRequest, userId string) (key *models.APIKeyCreateResponse, err error) {
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		r.Logger.Error("unable to generate api key", &err)
+		err = errors.New("unable to generate api key")
+		return
+	}
+
+	res := models.APIKeyCreateResponse{
+		APIKey: models.APIKey{
+			KeyID:     ulid.Make().String(),
+			UserID:    userId,
+			Subject:   userId,
+			Name:      keyData.Name,
+			Scopes:    keyData.Scopes,
+			ExpiresAt: keyData.ExpiresAt,
+			CreatedAt: time.Now().UTC(),
+		},
+	}
+	if keyData.ServiceAccount != "" {
+		res.Subject = models.ServiceAccountSubject(userId, keyData.ServiceAccount)
+	}
+
+	res.Key = models.APIKeyPrefix + res.KeyID + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	res.KeyHash = hashAPIKey(res.Key)
+
+	if err = r.query(models.APIKeys.Insert()).BindStruct(&res.APIKey).ExecRelease(); err != nil {
+		r.Logger.Error("unable to create api key", &err)
+		err = errors.New("unable to create api key")
+		return
+	}
+
+	key = &res
+
+	return
+}
+
+// RevokeAPIKey marks an API key as revoked. Revoked keys are kept so they still show up when
+// listing a user's keys.
+func (r *APIKeyRepository) RevokeAPIKey(keyId string, userId string, isAdmin bool) (key *models.APIKey, err error) {
+	key, err = r.GetAPIKey(keyId, userId, isAdmin)
+	if err != nil {
+		return
+	}
+
+	if key.RevokedAt != nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	key.RevokedAt = &now
+
+	stmt, names := models.APIKeys.Update("revoked_at")
+	if err = r.query(stmt, names).BindStruct(key).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to revoke api key %s", keyId), &err)
+		err = fmt.Errorf("unable to revoke api key %s", keyId)
+	}
+
+	return
+}
+
+// Authenticate resolves a plaintext API key to its stored record, failing with
+// ErrInvalidAPIKey if the key is unknown, revoked or expired.
+func (r *APIKeyRepository) Authenticate(rawKey string) (key *models.APIKey, err error) {
+	keyId, _, ok := strings.Cut(strings.TrimPrefix(rawKey, models.APIKeyPrefix), "_")
+	if !ok || !strings.HasPrefix(rawKey, models.APIKeyPrefix) {
+		err = ErrInvalidAPIKey
+		return
+	}
+
+	var res []models.APIKey
+	if err = r.query(models.APIKeys.Select()).Bind(keyId).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get api key %s", keyId), &err)
+		err = fmt.Errorf("unable to get api key %s", keyId)
+		return
+	}
+
+	if len(res) == 0 || subtle.ConstantTimeCompare([]byte(res[0].KeyHash), []byte(hashAPIKey(rawKey))) != 1 || !res[0].IsActive(time.Now()) {
+		err = ErrInvalidAPIKey
+		return
+	}
+
+	key = &res[0]
+
+	return
+}
+
+// TouchAPIKey records that an API key was just used.
+func (r *APIKeyRepository) TouchAPIKey(key models.APIKey) (err error) {
+	now := time.Now().UTC()
+	key.LastUsedAt = &now
+
+	stmt, names := models.APIKeys.Update("last_used_at")
+	if err = r.query(stmt, names).BindStruct(&key).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to update last use of api key %s", key.KeyID), &err)
+		err = errors.New("unable to update api key")
+	}
+
+	return
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/oklog/ulid/v2"
+	"github.com/scylladb/gocqlx/v3/qb"
+)
+
+// AuditRepository appends to and queries the audit log. Entries are never updated or deleted.
+type AuditRepository struct {
+	Repository
+}
+
+func NewAuditRepository(db *store.DBSession, logger *graylogger.GrayLogger) *AuditRepository {
+	return &AuditRepository{
+		Repository{
+			DB:     db,
+			Logger: logger,
+		},
+	}
+}
+
+// WithContext returns a copy of the repository whose queries and logs are part of the trace in ctx.
+func (r *AuditRepository) WithContext(ctx context.Context) *AuditRepository {
+	return &AuditRepository{r.Repository.withContext(ctx)}
+}
+
+// RecordEntry appends an entry to the audit log.
+func (r *AuditRepository) RecordEntry(entry models.AuditEntry) (err error) {
+	entry.CreatedAt = time.Now().UTC()
+	entry.EntryID = ulid.Make().String()
+
+	if err = r.query(models.AuditLog.Insert()).BindStruct(&entry).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to record %s of %s %s", entry.Action, entry.ResourceType, entry.ResourceID), &err)
+		err = errors.New("unable to record audit entry")
+	}
+
+	return
+}
+
+// GetEntries retrieves a page of the audit entries matching query. Entries for a single resource
+// are returned newest first; entries across resources are not ordered.
+func (r *AuditRepository) GetEntries(query models.AuditQuery, page models.PageRequest) (entries *[]models.AuditEntry, nextToken string, err error) {
+	q := qb.Select(models.AuditLog.Name())
+	var values []any
+
+	if query.ResourceType != "" {
+		q.Where(qb.Eq("resource_type"))
+		values = append(values, query.ResourceType)
+	}
+	if query.ResourceID != "" {
+		q.Where(qb.Eq("resource_id"))
+		values = append(values, query.ResourceID)
+	}
+	if query.ActorID != "" {
+		q.Where(qb.Eq("actor_id"))
+		values = append(values, query.ActorID)
+	}
+	if query.From != nil {
+		q.Where(qb.GtOrEq("created_at"))
+		values = append(values, *query.From)
+	}
+	if query.To != nil {
+		q.Where(qb.Lt("created_at"))
+		values = append(values, *query.To)
+	}
+	if len(values) > 0 {
+		q.AllowFiltering()
+	}
+
+	stmt, names := q.ToCql()
+
+	var res []models.AuditEntry
+	if nextToken, err = selectPage(r.query(stmt, names).Bind(values...), page, &res); err != nil {
+		r.Logger.Error("unable to get audit entries", &err)
+		if !errors.Is(err, ErrInvalidPageToken) {
+			err = errors.New("unable to get audit entries")
+		}
+		return
+	}
+
+	entries = &res
+
+	return
+}
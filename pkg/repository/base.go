@@ -1,12 +1,16 @@
 package repository
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"slices"
 	"strings"
 	"time"
 
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
 	"github.com/julianstephens/distributed-job-manager/pkg/store"
 	"github.com/scylladb/gocqlx/v3"
 	"github.com/scylladb/gocqlx/v3/qb"
@@ -15,6 +19,24 @@ import (
 type Repository struct {
 	DB     *store.DBSession
 	Logger *graylogger.GrayLogger
+	ctx    context.Context
+}
+
+// withContext returns a copy of r whose queries run in ctx, so they are traced as part of the
+// operation in ctx, and whose logs carry its trace.
+func (r Repository) withContext(ctx context.Context) Repository {
+	r.ctx = ctx
+	r.Logger = r.Logger.WithContext(ctx)
+	return r
+}
+
+// query prepares a statement to run in the repository's context.
+func (r *Repository) query(stmt string, names []string) *gocqlx.Queryx {
+	q := r.DB.Client.Query(stmt, names)
+	if r.ctx != nil {
+		q = q.WithContext(r.ctx)
+	}
+	return q
 }
 
 const (
@@ -27,6 +49,36 @@ const (
 	ContainsSymbol         = "[contains]"
 )
 
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// selectPage reads a single page of q into dest when page has a limit, or every row otherwise.
+// The returned token is empty once there are no more rows.
+func selectPage(q *gocqlx.Queryx, page models.PageRequest, dest any) (nextToken string, err error) {
+	if page.Limit <= 0 {
+		err = q.SelectRelease(dest)
+		return
+	}
+
+	defer q.Release()
+
+	state, err := base64.RawURLEncoding.DecodeString(page.Token)
+	if err != nil {
+		err = ErrInvalidPageToken
+		return
+	}
+
+	iter := q.PageSize(page.Limit).PageState(state).Iter()
+	if err = iter.Select(dest); err != nil {
+		return
+	}
+
+	if next := iter.PageState(); len(next) > 0 {
+		nextToken = base64.RawURLEncoding.EncodeToString(next)
+	}
+
+	return
+}
+
 func cleanValue(value any) any {
 	if s, ok := value.(string); ok {
 		if res, err := time.Parse(time.RFC3339, s); err == nil {
@@ -94,5 +146,5 @@ func (r *Repository) getFilteredQuery(query map[string][]string, tableName strin
 	}
 
 	stmt, names := q.AllowFiltering().ToCql()
-	return r.DB.Client.Query(stmt, names).Bind(values...), nil
+	return r.query(stmt, names).Bind(values...), nil
 }
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/oklog/ulid/v2"
+	"github.com/scylladb/gocqlx/v3/qb"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+type DeadLetterRepository struct {
+	Repository
+}
+
+func NewDeadLetterRepository(db *store.DBSession, logger *graylogger.GrayLogger) *DeadLetterRepository {
+	return &DeadLetterRepository{
+		Repository{
+			DB:     db,
+			Logger: logger,
+		},
+	}
+}
+
+// WithContext returns a copy of the repository whose queries and logs are part of the trace in ctx.
+func (r *DeadLetterRepository) WithContext(ctx context.Context) *DeadLetterRepository {
+	return &DeadLetterRepository{r.Repository.withContext(ctx)}
+}
+
+// CreateDeadLetter stores a dead-lettered job message.
+func (r *DeadLetterRepository) CreateDeadLetter(letterData models.DeadLetter) (letter *models.DeadLetter, err error) {
+	letterData.DeadLetterID = ulid.Make().String()
+	if letterData.DeadLetteredAt.IsZero() {
+		letterData.DeadLetteredAt = time.Now().UTC()
+	}
+
+	if err = r.query(models.DeadLetters.Insert()).BindStruct(&letterData).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to store dead letter of job %s", letterData.JobID), &err)
+		err = errors.New("unable to store dead letter")
+		return
+	}
+
+	letter = &letterData
+
+	return
+}
+
+// GetDeadLetters retrieves a page of the dead letters matching query. Dead letters are not
+// ordered.
+func (r *DeadLetterRepository) GetDeadLetters(query models.DeadLetterQuery, page models.PageRequest) (letters *[]models.DeadLetter, nextToken string, err error) {
+	q := qb.Select(models.DeadLetters.Name())
+	var values []any
+
+	if query.JobID != "" {
+		q.Where(qb.Eq("job_id")).AllowFiltering()
+		values = append(values, query.JobID)
+	}
+
+	stmt, names := q.ToCql()
+
+	var res []models.DeadLetter
+	if nextToken, err = selectPage(r.query(stmt, names).Bind(values...), page, &res); err != nil {
+		r.Logger.Error("unable to get dead letters", &err)
+		if !errors.Is(err, ErrInvalidPageToken) {
+			err = errors.New("unable to get dead letters")
+		}
+		return
+	}
+
+	letters = &res
+
+	return
+}
+
+// GetDeadLetter retrieves a dead letter by its ID.
+func (r *DeadLetterRepository) GetDeadLetter(letterId string) (letter *models.DeadLetter, err error) {
+	var res []models.DeadLetter
+	if err = r.query(models.DeadLetters.Get()).Bind(letterId).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get dead letter %s", letterId), &err)
+		err = fmt.Errorf("unable to get dead letter %s", letterId)
+		return
+	}
+
+	if len(res) == 0 {
+		err = ErrDeadLetterNotFound
+		return
+	}
+
+	letter = &res[0]
+
+	return
+}
+
+// DeleteDeadLetter removes a dead letter.
+func (r *DeadLetterRepository) DeleteDeadLetter(letterId string) (err error) {
+	if err = r.query(models.DeadLetters.Delete()).Bind(letterId).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to delete dead letter %s", letterId), &err)
+		err = fmt.Errorf("unable to delete dead letter %s", letterId)
+	}
+
+	return
+}
+
+// PurgeDeadLetters removes every dead letter matching query and returns the removed dead letters.
+func (r *DeadLetterRepository) PurgeDeadLetters(query models.DeadLetterQuery) (purged *[]models.DeadLetter, err error) {
+	letters, _, err := r.GetDeadLetters(query, models.PageRequest{})
+	if err != nil {
+		return
+	}
+
+	res := []models.DeadLetter{}
+	for _, letter := range *letters {
+		if err = r.DeleteDeadLetter(letter.DeadLetterID); err != nil {
+			break
+		}
+		res = append(res, letter)
+	}
+
+	purged = &res
+
+	return
+}
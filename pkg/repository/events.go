@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/scylladb/gocqlx/v3/qb"
+)
+
+// MaxEventReplay caps how many missed events are replayed to a resuming subscriber.
+const MaxEventReplay = 1000
+
+type EventRepository struct {
+	Repository
+}
+
+func NewEventRepository(db *store.DBSession, logger *graylogger.GrayLogger) *EventRepository {
+	return &EventRepository{
+		Repository{
+			DB:     db,
+			Logger: logger,
+		},
+	}
+}
+
+// WithContext returns a copy of the repository whose queries and logs are part of the trace in ctx.
+func (r *EventRepository) WithContext(ctx context.Context) *EventRepository {
+	return &EventRepository{r.Repository.withContext(ctx)}
+}
+
+// CreateEvent stores a job event so it can be replayed to subscribers that reconnect.
+func (r *EventRepository) CreateEvent(event models.JobEvent) (err error) {
+	if err = r.query(models.JobEvents.Insert()).BindStruct(&event).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to store event %s", event.EventID), &err)
+		err = errors.New("unable to store event")
+	}
+	return
+}
+
+// GetEventsAfter retrieves the events published after lastEventId that the principal can see,
+// oldest first: every event for admins, otherwise those of the principal's own jobs and of the
+// jobs granted to it directly or through a project.
+func (r *EventRepository) GetEventsAfter(lastEventId string, principal models.Principal) (events *[]models.JobEvent, err error) {
+	var res []models.JobEvent
+	if principal.IsAdmin {
+		stmt, names := qb.Select(models.JobEvents.Name()).Where(qb.Gt("event_id")).AllowFiltering().ToCql()
+		if err = r.query(stmt, names).Bind(lastEventId).SelectRelease(&res); err != nil {
+			r.Logger.ErrorWithData("unable to get events", &err, &map[string]any{
+				"lastEventId": lastEventId,
+				"isAdmin":     true,
+			})
+			err = errors.New("unable to get events")
+			return
+		}
+	} else {
+		granted, grantErr := r.grantedJobOwners(principal)
+		if grantErr != nil {
+			err = grantErr
+			return
+		}
+
+		// events are stored under the owner of their job, so each partition holding a granted
+		// job is read and narrowed to the granted jobs
+		partitions := map[string]map[string]struct{}{principal.UserID: nil}
+		for jobId, owner := range granted {
+			if owner == principal.UserID {
+				continue
+			}
+			if partitions[owner] == nil {
+				partitions[owner] = map[string]struct{}{}
+			}
+			partitions[owner][jobId] = struct{}{}
+		}
+
+		stmt, names := qb.Select(models.JobEvents.Name()).Where(qb.Eq("user_id"), qb.Gt("event_id")).ToCql()
+		for owner, jobIds := range partitions {
+			var rows []models.JobEvent
+			if err = r.query(stmt, names).Bind(owner, lastEventId).SelectRelease(&rows); err != nil {
+				r.Logger.ErrorWithData("unable to get events", &err, &map[string]any{
+					"lastEventId": lastEventId,
+					"userId":      principal.UserID,
+					"owner":       owner,
+				})
+				err = errors.New("unable to get events")
+				return
+			}
+
+			for _, event := range rows {
+				if _, ok := jobIds[event.JobID]; ok || jobIds == nil {
+					res = append(res, event)
+				}
+			}
+		}
+	}
+
+	slices.SortFunc(res, func(a, b models.JobEvent) int {
+		return strings.Compare(a.EventID, b.EventID)
+	})
+	if len(res) > MaxEventReplay {
+		res = res[len(res)-MaxEventReplay:]
+	}
+
+	events = &res
+
+	return
+}
@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jinzhu/copier"
@@ -12,25 +15,131 @@ import (
 	"github.com/scylladb/gocqlx/v3/qb"
 )
 
+// ErrExecutionChanged is returned when an execution is no longer in the status it was read in,
+// e.g. because another replica reaped it first or its worker reported after being reaped.
+var ErrExecutionChanged = errors.New("execution changed")
+
 type ExecutionRepository struct {
 	Repository
+	authorizer
 }
 
 func NewExecutionRepository(db *store.DBSession, logger *graylogger.GrayLogger) *ExecutionRepository {
-	return &ExecutionRepository{
-		Repository{
-			DB:     db,
-			Logger: logger,
-		},
+	repo := Repository{
+		DB:     db,
+		Logger: logger,
+	}
+	return newExecutionRepository(repo, &repo)
+}
+
+// newExecutionRepository returns a repository that authorizes principals against the records loaded
+// by lookup.
+func newExecutionRepository(repo Repository, lookup accessLookup) *ExecutionRepository {
+	return &ExecutionRepository{repo, authorizer{lookup}}
+}
+
+// WithContext returns a copy of the repository whose queries and logs are part of the trace in ctx.
+func (r *ExecutionRepository) WithContext(ctx context.Context) *ExecutionRepository {
+	return newExecutionRepository(r.Repository.withContext(ctx), r.lookup.inContext(ctx))
+}
+
+// GetExecution retrieves a specific job execution by its ID if the principal can view its job.
+func (r *ExecutionRepository) GetExecution(executionId string, principal models.Principal) (jobExecution *models.JobExecution, err error) {
+	return r.authorizeExecution(executionId, principal, models.RoleViewer)
+}
+
+// GetJobExecutions retrieves the executions of a job, most recently started first, if the
+// principal can view the job.
+func (r *ExecutionRepository) GetJobExecutions(jobId string, principal models.Principal) (jobExecutions *[]models.JobExecution, err error) {
+	if _, err = r.authorizeJob(jobId, principal, models.RoleViewer); err != nil {
+		return
+	}
+
+	var res []models.JobExecution
+	stmt, names := qb.Select(models.JobExecutions.Name()).Where(qb.Eq("job_id")).ToCql()
+	if err = r.query(stmt, names).Bind(jobId).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get executions of job %s", jobId), &err)
+		err = fmt.Errorf("unable to get executions of job %s", jobId)
+		return
+	}
+
+	// rows are clustered by worker and status, not by time
+	slices.SortFunc(res, func(a, b models.JobExecution) int {
+		return b.StartTime.Compare(a.StartTime)
+	})
+
+	jobExecutions = &res
+
+	return
+}
+
+// GetUnfinishedWorkerExecutions retrieves the executions a worker took on that have not reached a
+// terminal status.
+func (r *ExecutionRepository) GetUnfinishedWorkerExecutions(workerId string) (jobExecutions *[]models.JobExecution, err error) {
+	var res []models.JobExecution
+	stmt, names := qb.Select(models.JobExecutions.Name()).Where(qb.Eq("worker_id")).AllowFiltering().ToCql()
+	if err = r.query(stmt, names).Bind(workerId).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get executions of worker %s", workerId), &err)
+		err = fmt.Errorf("unable to get executions of worker %s", workerId)
+		return
 	}
+
+	unfinished := slices.DeleteFunc(res, func(exec models.JobExecution) bool {
+		return models.IsTerminalStatus(exec.Status)
+	})
+
+	jobExecutions = &unfinished
+
+	return
 }
 
-// CreateExecution creates a new job execution in the database.
-func (r *ExecutionRepository) CreateExecution(execData models.JobExecution) (jobExecution *models.JobExecution, err error) {
+// FailOrphanedExecution marks an execution whose worker is gone as failed with reason. The
+// execution is only failed if it is still in the status it was read in, so of several replicas
+// reaping it one succeeds and the others get ErrExecutionChanged.
+func (r *ExecutionRepository) FailOrphanedExecution(execution models.JobExecution, reason string) (jobExecution *models.JobExecution, err error) {
+	res := execution
+
+	stmt, names := qb.Delete(models.JobExecutions.Name()).Where(qb.Eq("job_id"), qb.Eq("worker_id"), qb.Eq("status"), qb.Eq("execution_id")).Existing().ToCql()
+	applied, err := r.query(stmt, names).Bind(res.JobID, res.WorkerID, res.Status, res.ExecutionID).ExecCASRelease()
+	if err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to delete orphaned job execution %s", res.ExecutionID), &err)
+		err = fmt.Errorf("unable to fail job execution %s", res.ExecutionID)
+		return
+	}
+
+	if !applied {
+		err = ErrExecutionChanged
+		return
+	}
+
+	res.Status = models.JobStatusFailed
+	res.EndTime = time.Now().UTC()
+	res.ErrorMessage = reason
+
+	if err = r.query(models.JobExecutions.Insert()).BindStruct(&res).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to recreate orphaned job execution %s as failed", res.ExecutionID), &err)
+		err = fmt.Errorf("unable to fail job execution %s", res.ExecutionID)
+		return
+	}
+
+	jobExecution = &res
+
+	return
+}
+
+// CreateExecution creates a new job execution in the database if the principal is an operator of
+// the job.
+func (r *ExecutionRepository) CreateExecution(execData models.JobExecution, principal models.Principal) (jobExecution *models.JobExecution, err error) {
+	job, err := r.authorizeJob(execData.JobID, principal, models.RoleOperator)
+	if err != nil {
+		return
+	}
+
 	execData.ExecutionID = uuid.New().String()
+	execData.Attempt = job.RetryCount + 1
 	execData.Status = models.JobStatusScheduled
 
-	if err = r.DB.Client.Query(models.JobExecutions.Insert()).BindStruct(&execData).ExecRelease(); err != nil {
+	if err = r.query(models.JobExecutions.Insert()).BindStruct(&execData).ExecRelease(); err != nil {
 		r.Logger.Error("unable to create job execution", &err)
 		err = errors.New("unable to create job execution")
 		return
@@ -38,7 +147,7 @@ func (r *ExecutionRepository) CreateExecution(execData models.JobExecution) (job
 
 	var res []models.JobExecution
 	stmt, names := qb.Select(models.JobExecutions.Name()).Where(qb.Eq("execution_id")).AllowFiltering().ToCql()
-	if err = r.DB.Client.Query(stmt, names).Bind(execData.ExecutionID).SelectRelease(&res); err != nil {
+	if err = r.query(stmt, names).Bind(execData.ExecutionID).SelectRelease(&res); err != nil {
 		r.Logger.Error("unable to get created job execution", &err)
 		err = errors.New("unable to get created job execution")
 		return
@@ -56,26 +165,32 @@ func (r *ExecutionRepository) CreateExecution(execData models.JobExecution) (job
 	return
 }
 
-// UpdateExecution updates an existing job execution in the database.
-func (r *ExecutionRepository) UpdateExecution(execUpdates models.JobExecutionUpdateRequest, executionId string) (jobExecution *models.JobExecution, err error) {
+// UpdateExecution updates an existing job execution in the database if the principal is an
+// operator of its job. The execution is only updated if it is still in the status it was read in,
+// otherwise ErrExecutionChanged is returned.
+func (r *ExecutionRepository) UpdateExecution(execUpdates models.JobExecutionUpdateRequest, executionId string, principal models.Principal) (jobExecution *models.JobExecution, err error) {
 	r.Logger.Info(fmt.Sprintf("updating job execution %s", executionId), nil)
 
-	var res models.JobExecution
-	stmt, names := qb.Select(models.JobExecutions.Name()).Where(qb.Eq("execution_id")).AllowFiltering().ToCql()
-	if err = r.DB.Client.Query(stmt, names).Bind(executionId).Get(&res); err != nil {
-		r.Logger.Error(fmt.Sprintf("unable to get job execution %s", executionId), &err)
-		err = fmt.Errorf("unable to get job execution %s", executionId)
+	existing, err := r.authorizeExecution(executionId, principal, models.RoleOperator)
+	if err != nil {
 		return
 	}
+	res := *existing
 
-	stmt, names = qb.Delete(models.JobExecutions.Name()).Where(qb.Eq("execution_id")).ToCql()
-	if err = r.DB.Client.Query(stmt, names).Bind(res.JobID, res.WorkerID, res.Status).ExecRelease(); err != nil {
+	stmt, names := qb.Delete(models.JobExecutions.Name()).Where(qb.Eq("job_id"), qb.Eq("worker_id"), qb.Eq("status"), qb.Eq("execution_id")).Existing().ToCql()
+	applied, err := r.query(stmt, names).Bind(res.JobID, res.WorkerID, res.Status, res.ExecutionID).ExecCASRelease()
+	if err != nil {
 		msg := fmt.Sprintf("unable to delete job execution %s for job %s", executionId, res.JobID)
 		r.Logger.Error(msg, &err)
 		err = errors.New(msg)
 		return
 	}
 
+	if !applied {
+		err = ErrExecutionChanged
+		return
+	}
+
 	err = copier.Copy(&res, &execUpdates)
 	if err != nil {
 		r.Logger.Error("unable to copy updates to job execution", &err)
@@ -83,7 +198,7 @@ func (r *ExecutionRepository) UpdateExecution(execUpdates models.JobExecutionUpd
 		return
 	}
 
-	if err = r.DB.Client.Query(models.JobExecutions.Insert()).BindStruct(&res).ExecRelease(); err != nil {
+	if err = r.query(models.JobExecutions.Insert()).BindStruct(&res).ExecRelease(); err != nil {
 		r.Logger.Error(fmt.Sprintf("unable to recreate job execution %s with updates", executionId), &err)
 		err = errors.New("unable to update job execution")
 		return
@@ -93,3 +208,79 @@ func (r *ExecutionRepository) UpdateExecution(execUpdates models.JobExecutionUpd
 
 	return
 }
+
+// AppendOutput stores a chunk of a running execution's output if the principal is an operator of
+// its job.
+func (r *ExecutionRepository) AppendOutput(chunk models.ExecutionOutputChunk, principal models.Principal) (err error) {
+	if _, err = r.authorizeExecution(chunk.ExecutionID, principal, models.RoleOperator); err != nil {
+		return
+	}
+
+	chunk.CreatedAt = time.Now().UTC()
+
+	if err = r.query(models.ExecutionOutput.Insert()).BindStruct(&chunk).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to store output chunk %d of job execution %s", chunk.Seq, chunk.ExecutionID), &err)
+		err = errors.New("unable to store job execution output")
+	}
+
+	return
+}
+
+// GetOutput retrieves the output chunks of an execution with a sequence number greater than afterSeq, in order.
+// Callers must have authorized the execution with GetExecution first.
+func (r *ExecutionRepository) GetOutput(executionId string, afterSeq int) (chunks *[]models.ExecutionOutputChunk, err error) {
+	var res []models.ExecutionOutputChunk
+	stmt, names := qb.Select(models.ExecutionOutput.Name()).Where(qb.Eq("execution_id"), qb.Gt("seq")).ToCql()
+	if err = r.query(stmt, names).Bind(executionId, afterSeq).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get output of job execution %s", executionId), &err)
+		err = fmt.Errorf("unable to get output of job execution %s", executionId)
+		return
+	}
+
+	chunks = &res
+
+	return
+}
+
+// CopyLegacyExecutions copies the executions in LegacyJobExecutions that JobExecutions does not
+// have yet. Executions found in JobExecutions in any status are skipped, since they were copied or
+// have moved on since, so it is safe to run on every start.
+func (r *ExecutionRepository) CopyLegacyExecutions() (copied int, err error) {
+	var legacy []models.JobExecution
+	stmt, names := qb.Select(models.LegacyJobExecutions.Name()).ToCql()
+	if err = r.query(stmt, names).SelectRelease(&legacy); err != nil {
+		r.Logger.Error("unable to get legacy job executions", &err)
+		err = errors.New("unable to get legacy job executions")
+		return
+	}
+
+	byJob := make(map[string][]models.JobExecution)
+	for _, exec := range legacy {
+		byJob[exec.JobID] = append(byJob[exec.JobID], exec)
+	}
+
+	for jobId, execs := range byJob {
+		var existing []models.JobExecution
+		stmt, names := qb.Select(models.JobExecutions.Name()).Columns("execution_id").Where(qb.Eq("job_id")).ToCql()
+		if err = r.query(stmt, names).Bind(jobId).SelectRelease(&existing); err != nil {
+			r.Logger.Error(fmt.Sprintf("unable to get executions of job %s", jobId), &err)
+			err = fmt.Errorf("unable to copy executions of job %s", jobId)
+			return
+		}
+
+		for _, exec := range execs {
+			if slices.ContainsFunc(existing, func(e models.JobExecution) bool { return e.ExecutionID == exec.ExecutionID }) {
+				continue
+			}
+
+			if err = r.query(models.JobExecutions.Insert()).BindStruct(&exec).ExecRelease(); err != nil {
+				r.Logger.Error(fmt.Sprintf("unable to copy job execution %s", exec.ExecutionID), &err)
+				err = fmt.Errorf("unable to copy job execution %s", exec.ExecutionID)
+				return
+			}
+			copied++
+		}
+	}
+
+	return
+}
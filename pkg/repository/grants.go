@@ -0,0 +1,400 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/scylladb/gocqlx/v3/qb"
+)
+
+var (
+	// ErrJobNotFound is returned both for jobs that do not exist and for jobs the caller has no
+	// role on, so callers cannot probe for other tenants' jobs.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrExecutionNotFound is returned both for executions that do not exist and for executions
+	// of jobs the caller has no role on.
+	ErrExecutionNotFound = errors.New("execution not found")
+	// ErrForbidden is returned when the caller can see a job but their role does not allow the
+	// operation.
+	ErrForbidden = errors.New("insufficient role for this operation")
+)
+
+type GrantRepository struct {
+	Repository
+	authorizer
+}
+
+func NewGrantRepository(db *store.DBSession, logger *graylogger.GrayLogger) *GrantRepository {
+	repo := Repository{
+		DB:     db,
+		Logger: logger,
+	}
+	return newGrantRepository(repo, &repo)
+}
+
+// newGrantRepository returns a repository that authorizes principals against the records loaded
+// by lookup.
+func newGrantRepository(repo Repository, lookup accessLookup) *GrantRepository {
+	return &GrantRepository{repo, authorizer{lookup}}
+}
+
+// WithContext returns a copy of the repository whose queries and logs are part of the trace in ctx.
+func (r *GrantRepository) WithContext(ctx context.Context) *GrantRepository {
+	return newGrantRepository(r.Repository.withContext(ctx), r.lookup.inContext(ctx))
+}
+
+// GetJobGrants retrieves the grants on a job if the principal is an admin of it.
+func (r *GrantRepository) GetJobGrants(jobId string, principal models.Principal) (grants *[]models.JobGrant, err error) {
+	if _, err = r.authorizeJob(jobId, principal, models.RoleAdmin); err != nil {
+		return
+	}
+	return r.getGrants(models.GrantResourceJob, jobId)
+}
+
+// SaveJobGrant creates or replaces a grant on a job if the principal is an admin of it.
+func (r *GrantRepository) SaveJobGrant(jobId string, grant models.JobGrant, principal models.Principal) (res *models.JobGrant, err error) {
+	if _, err = r.authorizeJob(jobId, principal, models.RoleAdmin); err != nil {
+		return
+	}
+	return r.saveGrant(models.GrantResourceJob, jobId, grant, principal)
+}
+
+// DeleteJobGrant removes a grant on a job if the principal is an admin of it.
+func (r *GrantRepository) DeleteJobGrant(jobId string, subjectType string, subjectId string, principal models.Principal) (err error) {
+	if _, err = r.authorizeJob(jobId, principal, models.RoleAdmin); err != nil {
+		return
+	}
+	return r.deleteGrant(models.GrantResourceJob, jobId, subjectType, subjectId)
+}
+
+// GetProjectGrants retrieves the grants on a project if the principal is an admin of it.
+func (r *GrantRepository) GetProjectGrants(project string, principal models.Principal) (grants *[]models.JobGrant, err error) {
+	if err = r.authorizeProject(project, principal, models.RoleAdmin); err != nil {
+		return
+	}
+	return r.getGrants(models.GrantResourceProject, project)
+}
+
+// SaveProjectGrant creates or replaces a grant on a project if the principal is an admin of it.
+func (r *GrantRepository) SaveProjectGrant(project string, grant models.JobGrant, principal models.Principal) (res *models.JobGrant, err error) {
+	if err = r.authorizeProject(project, principal, models.RoleAdmin); err != nil {
+		return
+	}
+	return r.saveGrant(models.GrantResourceProject, project, grant, principal)
+}
+
+// DeleteProjectGrant removes a grant on a project if the principal is an admin of it.
+func (r *GrantRepository) DeleteProjectGrant(project string, subjectType string, subjectId string, principal models.Principal) (err error) {
+	if err = r.authorizeProject(project, principal, models.RoleAdmin); err != nil {
+		return
+	}
+	return r.deleteGrant(models.GrantResourceProject, project, subjectType, subjectId)
+}
+
+func (r *Repository) getGrants(resourceType string, resourceId string) (grants *[]models.JobGrant, err error) {
+	var res []models.JobGrant
+	if err = r.query(models.JobGrants.Select()).Bind(resourceType, resourceId).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get grants on %s %s", resourceType, resourceId), &err)
+		err = fmt.Errorf("unable to get grants on %s %s", resourceType, resourceId)
+		return
+	}
+
+	grants = &res
+
+	return
+}
+
+func (r *Repository) saveGrant(resourceType string, resourceId string, grant models.JobGrant, principal models.Principal) (res *models.JobGrant, err error) {
+	grant.ResourceType = resourceType
+	grant.ResourceID = resourceId
+	grant.CreatedBy = principal.UserID
+	grant.CreatedAt = time.Now().UTC()
+
+	if err = r.query(models.JobGrants.Insert()).BindStruct(&grant).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to save grant on %s %s", resourceType, resourceId), &err)
+		err = errors.New("unable to save grant")
+		return
+	}
+
+	res = &grant
+
+	return
+}
+
+func (r *Repository) deleteGrant(resourceType string, resourceId string, subjectType string, subjectId string) (err error) {
+	if err = r.query(models.JobGrants.Delete()).Bind(resourceType, resourceId, subjectType, subjectId).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to delete grant on %s %s", resourceType, resourceId), &err)
+		err = errors.New("unable to delete grant")
+	}
+
+	return
+}
+
+// accessLookup loads the records that authorization decisions are based on.
+type accessLookup interface {
+	findJob(jobId string) (*models.Job, error)
+	findExecution(executionId string) (*models.JobExecution, error)
+	getGrants(resourceType string, resourceId string) (*[]models.JobGrant, error)
+	// inContext returns a lookup whose loads are part of the operation in ctx.
+	inContext(ctx context.Context) accessLookup
+}
+
+// authorizer decides what principals may do with jobs and executions, based on the records its
+// lookup loads. The exported constructors load them from the database.
+type authorizer struct {
+	lookup accessLookup
+}
+
+// inContext returns a copy of the repository that loads authorization records in ctx.
+func (r *Repository) inContext(ctx context.Context) accessLookup {
+	res := r.withContext(ctx)
+	return &res
+}
+
+// roleOn returns the highest role the principal's grants give on a resource, or "" if none.
+func (a authorizer) roleOn(resourceType string, resourceId string, principal models.Principal) (role string, err error) {
+	grants, err := a.lookup.getGrants(resourceType, resourceId)
+	if err != nil {
+		return
+	}
+
+	for _, grant := range *grants {
+		if principal.Matches(grant) {
+			role = models.HigherRole(role, grant.Role)
+		}
+	}
+
+	return
+}
+
+// jobRole returns the principal's role on a job: admin for global admins and the job's owner,
+// otherwise the highest role granted on the job or its project.
+func (a authorizer) jobRole(job models.Job, principal models.Principal) (role string, err error) {
+	if principal.IsAdmin || job.UserID == principal.UserID {
+		return models.RoleAdmin, nil
+	}
+
+	if role, err = a.roleOn(models.GrantResourceJob, job.JobID, principal); err != nil {
+		return
+	}
+
+	if job.Project != "" {
+		var projectRole string
+		if projectRole, err = a.roleOn(models.GrantResourceProject, job.Project, principal); err != nil {
+			return
+		}
+		role = models.HigherRole(role, projectRole)
+	}
+
+	return
+}
+
+// findJob retrieves a job by its ID regardless of owner.
+func (r *Repository) findJob(jobId string) (job *models.Job, err error) {
+	var res []models.Job
+	stmt, names := qb.Select(models.Jobs.Name()).Where(qb.Eq("job_id")).AllowFiltering().ToCql()
+	if err = r.query(stmt, names).Bind(jobId).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get job %s", jobId), &err)
+		err = fmt.Errorf("unable to get job %s", jobId)
+		return
+	}
+
+	if len(res) == 0 {
+		err = ErrJobNotFound
+		return
+	}
+
+	job = &res[0]
+
+	return
+}
+
+// findExecution retrieves an execution by its ID regardless of who may see its job.
+func (r *Repository) findExecution(executionId string) (jobExecution *models.JobExecution, err error) {
+	var res []models.JobExecution
+	stmt, names := qb.Select(models.JobExecutions.Name()).Where(qb.Eq("execution_id")).AllowFiltering().ToCql()
+	if err = r.query(stmt, names).Bind(executionId).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get job execution %s", executionId), &err)
+		err = fmt.Errorf("unable to get job execution %s", executionId)
+		return
+	}
+
+	if len(res) == 0 {
+		err = ErrExecutionNotFound
+		return
+	}
+
+	jobExecution = &res[0]
+
+	return
+}
+
+// authorizeJob retrieves a job if the principal holds at least the required role on it.
+func (a authorizer) authorizeJob(jobId string, principal models.Principal, required string) (job *models.Job, err error) {
+	job, err = a.lookup.findJob(jobId)
+	if err != nil {
+		return
+	}
+
+	role, err := a.jobRole(*job, principal)
+	if err != nil {
+		job = nil
+		return
+	}
+
+	switch {
+	case role == "":
+		job, err = nil, ErrJobNotFound
+	case !models.RoleAtLeast(role, required):
+		job, err = nil, ErrForbidden
+	}
+
+	return
+}
+
+// authorizeProject checks that the principal holds at least the required role on a project.
+func (a authorizer) authorizeProject(project string, principal models.Principal, required string) (err error) {
+	if principal.IsAdmin {
+		return
+	}
+
+	role, err := a.roleOn(models.GrantResourceProject, project, principal)
+	if err != nil {
+		return
+	}
+
+	if !models.RoleAtLeast(role, required) {
+		err = ErrForbidden
+	}
+
+	return
+}
+
+// authorizeExecution retrieves an execution if the principal holds at least the required role on
+// its job.
+func (a authorizer) authorizeExecution(executionId string, principal models.Principal, required string) (jobExecution *models.JobExecution, err error) {
+	exec, err := a.lookup.findExecution(executionId)
+	if err != nil {
+		return
+	}
+
+	if _, err = a.authorizeJob(exec.JobID, principal, required); err != nil {
+		if errors.Is(err, ErrJobNotFound) {
+			err = ErrExecutionNotFound
+		}
+		return
+	}
+
+	jobExecution = exec
+
+	return
+}
+
+// principalGrants retrieves every grant given to the principal or its groups, keyed by the
+// granted job and project.
+func (r *Repository) principalGrants(principal models.Principal) (jobs map[string]string, projects map[string]string, err error) {
+	jobs = map[string]string{}
+	projects = map[string]string{}
+
+	subjects := [][2]string{{models.GrantSubjectUser, principal.UserID}}
+	for _, group := range principal.Groups {
+		subjects = append(subjects, [2]string{models.GrantSubjectGroup, group})
+	}
+
+	stmt, names := qb.Select(models.JobGrants.Name()).Where(qb.Eq("subject_type"), qb.Eq("subject_id")).AllowFiltering().ToCql()
+	for _, subject := range subjects {
+		var res []models.JobGrant
+		if err = r.query(stmt, names).Bind(subject[0], subject[1]).SelectRelease(&res); err != nil {
+			r.Logger.Error(fmt.Sprintf("unable to get grants of %s %s", subject[0], subject[1]), &err)
+			err = errors.New("unable to get grants")
+			return
+		}
+
+		for _, grant := range res {
+			switch grant.ResourceType {
+			case models.GrantResourceJob:
+				jobs[grant.ResourceID] = models.HigherRole(jobs[grant.ResourceID], grant.Role)
+			case models.GrantResourceProject:
+				projects[grant.ResourceID] = models.HigherRole(projects[grant.ResourceID], grant.Role)
+			}
+		}
+	}
+
+	return
+}
+
+// visibleJobs retrieves the IDs of every job the principal has a role on. It must not be called
+// for admins, who can see every job.
+func (r *Repository) visibleJobs(principal models.Principal) (jobIds map[string]struct{}, err error) {
+	grantedJobs, grantedProjects, err := r.principalGrants(principal)
+	if err != nil {
+		return
+	}
+
+	jobIds = make(map[string]struct{}, len(grantedJobs))
+	for jobId := range grantedJobs {
+		jobIds[jobId] = struct{}{}
+	}
+
+	filters := [][2]string{{"user_id", principal.UserID}}
+	for project := range grantedProjects {
+		filters = append(filters, [2]string{"project", project})
+	}
+
+	for _, filter := range filters {
+		var res []models.Job
+		stmt, names := qb.Select(models.Jobs.Name()).Columns("job_id").Where(qb.Eq(filter[0])).AllowFiltering().ToCql()
+		if err = r.query(stmt, names).Bind(filter[1]).SelectRelease(&res); err != nil {
+			r.Logger.Error(fmt.Sprintf("unable to get jobs with %s %s", filter[0], filter[1]), &err)
+			err = errors.New("unable to get jobs")
+			return
+		}
+		for _, job := range res {
+			jobIds[job.JobID] = struct{}{}
+		}
+	}
+
+	return
+}
+
+// grantedJobOwners retrieves the owner of every job the principal's grants give it a role on,
+// directly or through a project, keyed by job ID. Grants on jobs that no longer exist are skipped.
+func (r *Repository) grantedJobOwners(principal models.Principal) (owners map[string]string, err error) {
+	grantedJobs, grantedProjects, err := r.principalGrants(principal)
+	if err != nil {
+		return
+	}
+
+	owners = make(map[string]string, len(grantedJobs))
+	for jobId := range grantedJobs {
+		job, findErr := r.findJob(jobId)
+		if errors.Is(findErr, ErrJobNotFound) {
+			continue
+		}
+		if findErr != nil {
+			err = findErr
+			return
+		}
+		owners[jobId] = job.UserID
+	}
+
+	stmt, names := qb.Select(models.Jobs.Name()).Columns("job_id", "user_id").Where(qb.Eq("project")).AllowFiltering().ToCql()
+	for project := range grantedProjects {
+		var res []models.Job
+		if err = r.query(stmt, names).Bind(project).SelectRelease(&res); err != nil {
+			r.Logger.Error(fmt.Sprintf("unable to get jobs with project %s", project), &err)
+			err = errors.New("unable to get jobs")
+			return
+		}
+		for _, job := range res {
+			owners[job.JobID] = job.UserID
+		}
+	}
+
+	return
+}
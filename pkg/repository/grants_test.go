@@ -0,0 +1,346 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/julianstephens/distributed-job-manager/pkg/utils"
+	"github.com/scylladb/gocqlx/v3"
+)
+
+// fakeAccess serves authorization records from memory.
+type fakeAccess struct {
+	jobs       map[string]models.Job
+	executions map[string]models.JobExecution
+	grants     []models.JobGrant
+	err        error
+}
+
+func (f *fakeAccess) findJob(jobId string) (*models.Job, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	job, ok := f.jobs[jobId]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+func (f *fakeAccess) findExecution(executionId string) (*models.JobExecution, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	exec, ok := f.executions[executionId]
+	if !ok {
+		return nil, ErrExecutionNotFound
+	}
+	return &exec, nil
+}
+
+func (f *fakeAccess) inContext(ctx context.Context) accessLookup {
+	return f
+}
+
+func (f *fakeAccess) getGrants(resourceType string, resourceId string) (*[]models.JobGrant, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	res := []models.JobGrant{}
+	for _, grant := range f.grants {
+		if grant.ResourceType == resourceType && grant.ResourceID == resourceId {
+			res = append(res, grant)
+		}
+	}
+	return &res, nil
+}
+
+var (
+	alice = models.Principal{UserID: "alice"}
+	bob   = models.Principal{UserID: "bob"}
+	carol = models.Principal{UserID: "carol", Groups: []string{"ops"}}
+	admin = models.Principal{UserID: "root", IsAdmin: true}
+)
+
+// newFakeAccess returns alice's job 'job-a' in project 'billing' with execution 'exec-a', and
+// bob's job 'job-b' with execution 'exec-b'. Carol is a viewer of job-a and her group 'ops' is an
+// editor of the billing project. Erin is an operator of job-a.
+func newFakeAccess() *fakeAccess {
+	return &fakeAccess{
+		jobs: map[string]models.Job{
+			"job-a": {JobID: "job-a", UserID: "alice", Project: "billing"},
+			"job-b": {JobID: "job-b", UserID: "bob"},
+		},
+		executions: map[string]models.JobExecution{
+			"exec-a": {ExecutionID: "exec-a", JobID: "job-a"},
+			"exec-b": {ExecutionID: "exec-b", JobID: "job-b"},
+		},
+		grants: []models.JobGrant{
+			{ResourceType: models.GrantResourceJob, ResourceID: "job-a", SubjectType: models.GrantSubjectUser, SubjectID: "carol", Role: models.RoleViewer},
+			{ResourceType: models.GrantResourceProject, ResourceID: "billing", SubjectType: models.GrantSubjectGroup, SubjectID: "ops", Role: models.RoleEditor},
+			{ResourceType: models.GrantResourceJob, ResourceID: "job-a", SubjectType: models.GrantSubjectUser, SubjectID: "erin", Role: models.RoleOperator},
+		},
+	}
+}
+
+func TestAuthorizeJob(t *testing.T) {
+	tests := []struct {
+		name      string
+		jobId     string
+		principal models.Principal
+		required  string
+		wantErr   error
+	}{
+		{"owner", "job-a", alice, models.RoleAdmin, nil},
+		{"global admin", "job-b", admin, models.RoleAdmin, nil},
+		{"other tenant viewing", "job-b", alice, models.RoleViewer, ErrJobNotFound},
+		{"other tenant deleting", "job-a", bob, models.RoleAdmin, ErrJobNotFound},
+		{"missing job", "job-z", alice, models.RoleViewer, ErrJobNotFound},
+		{"missing job for admin", "job-z", admin, models.RoleViewer, ErrJobNotFound},
+		{"job grant", "job-a", models.Principal{UserID: "carol"}, models.RoleViewer, nil},
+		{"job grant too low", "job-a", models.Principal{UserID: "carol"}, models.RoleOperator, ErrForbidden},
+		{"project grant to group", "job-a", carol, models.RoleEditor, nil},
+		{"project grant too low", "job-a", carol, models.RoleAdmin, ErrForbidden},
+		{"grants on another tenant's job", "job-b", carol, models.RoleViewer, ErrJobNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := authorizer{newFakeAccess()}
+
+			job, err := r.authorizeJob(tt.jobId, tt.principal, tt.required)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("authorizeJob() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && job != nil {
+				t.Errorf("authorizeJob() returned job %s along with error %v", job.JobID, err)
+			}
+			if tt.wantErr == nil && (job == nil || job.JobID != tt.jobId) {
+				t.Errorf("authorizeJob() job = %v, want %s", job, tt.jobId)
+			}
+		})
+	}
+}
+
+func TestAuthorizeJobLookupError(t *testing.T) {
+	lookupErr := errors.New("cassandra unavailable")
+	r := authorizer{&fakeAccess{err: lookupErr}}
+
+	if _, err := r.authorizeJob("job-a", alice, models.RoleViewer); !errors.Is(err, lookupErr) {
+		t.Errorf("authorizeJob() error = %v, want %v", err, lookupErr)
+	}
+}
+
+func TestJobRole(t *testing.T) {
+	access := newFakeAccess()
+	jobA := access.jobs["job-a"]
+	jobB := access.jobs["job-b"]
+
+	tests := []struct {
+		name      string
+		job       models.Job
+		principal models.Principal
+		want      string
+	}{
+		{"owner", jobA, alice, models.RoleAdmin},
+		{"global admin", jobB, admin, models.RoleAdmin},
+		{"other tenant", jobB, alice, ""},
+		{"other tenant with no grants", jobA, bob, ""},
+		{"job grant", jobA, models.Principal{UserID: "carol"}, models.RoleViewer},
+		{"highest of job and project grants", jobA, carol, models.RoleEditor},
+		{"group grant of another group", jobA, models.Principal{UserID: "dave", Groups: []string{"sales"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := authorizer{access}
+
+			role, err := r.jobRole(tt.job, tt.principal)
+			if err != nil {
+				t.Fatalf("jobRole() error = %v", err)
+			}
+			if role != tt.want {
+				t.Errorf("jobRole() = %q, want %q", role, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeExecution(t *testing.T) {
+	tests := []struct {
+		name        string
+		executionId string
+		principal   models.Principal
+		required    string
+		wantErr     error
+	}{
+		{"owner", "exec-a", alice, models.RoleOperator, nil},
+		{"global admin", "exec-b", admin, models.RoleOperator, nil},
+		{"other tenant viewing", "exec-b", alice, models.RoleViewer, ErrExecutionNotFound},
+		{"other tenant updating", "exec-a", bob, models.RoleOperator, ErrExecutionNotFound},
+		{"missing execution", "exec-z", alice, models.RoleViewer, ErrExecutionNotFound},
+		{"job grant", "exec-a", models.Principal{UserID: "carol"}, models.RoleViewer, nil},
+		{"job grant too low", "exec-a", models.Principal{UserID: "carol"}, models.RoleOperator, ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := authorizer{newFakeAccess()}
+
+			exec, err := r.authorizeExecution(tt.executionId, tt.principal, tt.required)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("authorizeExecution() error = %v, want %v", err, tt.wantErr)
+			}
+			// the job of another tenant's execution must not be revealed either
+			if errors.Is(err, ErrJobNotFound) {
+				t.Errorf("authorizeExecution() error = %v, want it reported as %v", err, ErrExecutionNotFound)
+			}
+			if tt.wantErr != nil && exec != nil {
+				t.Errorf("authorizeExecution() returned execution %s along with error %v", exec.ExecutionID, err)
+			}
+			if tt.wantErr == nil && (exec == nil || exec.ExecutionID != tt.executionId) {
+				t.Errorf("authorizeExecution() execution = %v, want %s", exec, tt.executionId)
+			}
+		})
+	}
+}
+
+// newClosedRepository returns a repository whose queries all fail, so a mutation that was
+// authorized fails with a database error rather than an authorization error.
+func newClosedRepository() Repository {
+	s := &gocql.Session{}
+	s.Close()
+	session := gocqlx.NewSession(s)
+
+	return Repository{
+		DB:     &store.DBSession{Client: &session},
+		Logger: &graylogger.GrayLogger{Originator: "repository-test"},
+	}
+}
+
+func TestMutationRoles(t *testing.T) {
+	var (
+		viewer   = models.Principal{UserID: "carol"}
+		operator = models.Principal{UserID: "erin"}
+		editor   = carol
+		// allowed stands for any error other than an authorization error
+		allowed = errors.New("allowed")
+	)
+
+	jobs := newJobRepository(newClosedRepository(), newFakeAccess())
+	schedules := newScheduleRepository(newClosedRepository(), newFakeAccess())
+	executions := newExecutionRepository(newClosedRepository(), newFakeAccess())
+
+	statusOnly := func(p models.Principal) error {
+		_, err := jobs.UpdateJob(models.JobUpdateRequest{Status: utils.StringPtr(models.JobStatusCancelled)}, "job-a", p)
+		return err
+	}
+	rename := func(p models.Principal) error {
+		_, err := jobs.UpdateJob(models.JobUpdateRequest{JobName: utils.StringPtr("renamed")}, "job-a", p)
+		return err
+	}
+	moveProject := func(p models.Principal) error {
+		_, err := jobs.UpdateJob(models.JobUpdateRequest{Project: utils.StringPtr("payroll")}, "job-a", p)
+		return err
+	}
+	run := func(p models.Principal) error {
+		_, err := jobs.RunJob("job-a", p)
+		return err
+	}
+	deleteJob := func(p models.Principal) error {
+		_, err := jobs.DeleteJob("job-a", p)
+		return err
+	}
+	createSchedule := func(p models.Principal) error {
+		_, err := schedules.CreateSchedule(models.JobSchedule{JobID: "job-a"}, p)
+		return err
+	}
+	updateSchedule := func(p models.Principal) error {
+		_, err := schedules.UpdateSchedule("job-a", models.JobScheduleUpdateRequest{}, p)
+		return err
+	}
+	deleteSchedule := func(p models.Principal) error {
+		return schedules.DeleteSchedule("job-a", p)
+	}
+	deleteOrphanedSchedule := func(p models.Principal) error {
+		return schedules.DeleteSchedule("job-z", p)
+	}
+	createExecution := func(p models.Principal) error {
+		_, err := executions.CreateExecution(models.JobExecution{JobID: "job-a"}, p)
+		return err
+	}
+	updateExecution := func(p models.Principal) error {
+		_, err := executions.UpdateExecution(models.JobExecutionUpdateRequest{}, "exec-a", p)
+		return err
+	}
+	appendOutput := func(p models.Principal) error {
+		return executions.AppendOutput(models.ExecutionOutputChunk{ExecutionID: "exec-a", Seq: 1}, p)
+	}
+
+	tests := []struct {
+		name      string
+		mutate    func(models.Principal) error
+		principal models.Principal
+		want      error
+	}{
+		{"status change by viewer", statusOnly, viewer, ErrForbidden},
+		{"status change by operator", statusOnly, operator, allowed},
+		{"status change by other tenant", statusOnly, bob, ErrJobNotFound},
+		{"rename by operator", rename, operator, ErrForbidden},
+		{"rename by editor", rename, editor, allowed},
+		{"rename by owner", rename, alice, allowed},
+		{"move to project without a role on it", moveProject, editor, ErrForbidden},
+		{"move to project by global admin", moveProject, admin, allowed},
+
+		{"run by viewer", run, viewer, ErrForbidden},
+		{"run by operator", run, operator, allowed},
+		{"run by other tenant", run, bob, ErrJobNotFound},
+
+		{"delete by editor", deleteJob, editor, ErrForbidden},
+		{"delete by owner", deleteJob, alice, allowed},
+		{"delete by global admin", deleteJob, admin, allowed},
+		{"delete by other tenant", deleteJob, bob, ErrJobNotFound},
+
+		{"create schedule by operator", createSchedule, operator, ErrForbidden},
+		{"create schedule by editor", createSchedule, editor, allowed},
+		{"create schedule by other tenant", createSchedule, bob, ErrJobNotFound},
+		{"update schedule by operator", updateSchedule, operator, ErrForbidden},
+		{"update schedule by editor", updateSchedule, editor, allowed},
+		{"delete schedule by operator", deleteSchedule, operator, ErrForbidden},
+		{"delete schedule by editor", deleteSchedule, editor, allowed},
+		{"delete schedule of deleted job by non-admin", deleteOrphanedSchedule, alice, ErrJobNotFound},
+		{"delete schedule of deleted job by global admin", deleteOrphanedSchedule, admin, allowed},
+
+		{"create execution by viewer", createExecution, viewer, ErrForbidden},
+		{"create execution by operator", createExecution, operator, allowed},
+		{"create execution by other tenant", createExecution, bob, ErrJobNotFound},
+		{"update execution by viewer", updateExecution, viewer, ErrForbidden},
+		{"update execution by operator", updateExecution, operator, allowed},
+		{"update execution by other tenant", updateExecution, bob, ErrExecutionNotFound},
+		{"append output by viewer", appendOutput, viewer, ErrForbidden},
+		{"append output by operator", appendOutput, operator, allowed},
+		{"append output by other tenant", appendOutput, bob, ErrExecutionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.mutate(tt.principal)
+
+			authErr := errors.Is(err, ErrForbidden) || errors.Is(err, ErrJobNotFound) || errors.Is(err, ErrExecutionNotFound)
+			if tt.want == allowed {
+				if err == nil || authErr {
+					t.Errorf("error = %v, want the mutation to be authorized and fail at the database", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package repository
 
 import (
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/copier"
@@ -16,41 +20,44 @@ import (
 
 type JobRepository struct {
 	Repository
+	authorizer
 }
 
 func NewJobRepository(db *store.DBSession, logger *graylogger.GrayLogger) *JobRepository {
-	return &JobRepository{
-		Repository{
-			DB:     db,
-			Logger: logger,
-		},
+	repo := Repository{
+		DB:     db,
+		Logger: logger,
 	}
+	return newJobRepository(repo, &repo)
 }
 
-// GetJobs retrieves all jobs for a user or all jobs if the user is an admin.
-func (r *JobRepository) GetJobs(userId string, isAdmin bool) (jobs *[]models.Job, err error) {
-	r.Logger.Debug(fmt.Sprintf("getting jobs for user %s", userId), utils.StringPtr(fmt.Sprintf("isAdmin: %t", isAdmin)))
-
-	q := qb.Select(models.Jobs.Name())
+// newJobRepository returns a repository that authorizes principals against the records loaded
+// by lookup.
+func newJobRepository(repo Repository, lookup accessLookup) *JobRepository {
+	return &JobRepository{repo, authorizer{lookup}}
+}
 
-	if !isAdmin {
-		q.Where(qb.Eq("user_id")).AllowFiltering()
-	}
+// WithContext returns a copy of the repository whose queries and logs are part of the trace in ctx.
+func (r *JobRepository) WithContext(ctx context.Context) *JobRepository {
+	return newJobRepository(r.Repository.withContext(ctx), r.lookup.inContext(ctx))
+}
 
-	stmt, names := q.ToCql()
-	transaction := r.DB.Client.Query(stmt, names)
+// GetJobs retrieves a page of the jobs a principal can view: every job for admins, otherwise the
+// principal's own jobs and those granted to it directly or through a project.
+func (r *JobRepository) GetJobs(principal models.Principal, page models.PageRequest) (jobs *[]models.Job, nextToken string, err error) {
+	r.Logger.Debug(fmt.Sprintf("getting jobs for user %s", principal.UserID), utils.StringPtr(fmt.Sprintf("isAdmin: %t", principal.IsAdmin)))
 
-	if !isAdmin {
-		transaction.Bind(userId)
+	if !principal.IsAdmin {
+		return r.getVisibleJobs(principal, page)
 	}
 
-	var res []models.Job
-	if err = transaction.SelectRelease(&res); err != nil {
-		data := map[string]any{
-			"userId":  userId,
-			"isAdmin": isAdmin,
-		}
-		r.Logger.ErrorWithData("failed to get jobs", &err, &data)
+	res := []models.Job{}
+	stmt, names := qb.Select(models.Jobs.Name()).ToCql()
+	if nextToken, err = selectPage(r.query(stmt, names), page, &res); err != nil {
+		r.Logger.ErrorWithData("failed to get jobs", &err, &map[string]any{
+			"userId":  principal.UserID,
+			"isAdmin": principal.IsAdmin,
+		})
 		return
 	}
 
@@ -59,49 +66,150 @@ func (r *JobRepository) GetJobs(userId string, isAdmin bool) (jobs *[]models.Job
 	return
 }
 
-// GetJob retrieves a specific job by its ID.
-func (r *JobRepository) GetJob(jobId string, userId string, isAdmin bool) (job *models.Job, err error) {
-	r.Logger.Debug(fmt.Sprintf("getting job %s for user %s", jobId, userId), utils.StringPtr(fmt.Sprintf("isAdmin: %t", isAdmin)))
+// getVisibleJobs retrieves a page of the jobs of a principal that is not an admin, in job ID
+// order. Those jobs are spread over the principal's partition, its granted jobs and its granted
+// projects, so each is read by key from after the job ID in the page token and the first jobs of
+// the merged results make up the page. The last page may be empty.
+func (r *JobRepository) getVisibleJobs(principal models.Principal, page models.PageRequest) (jobs *[]models.Job, nextToken string, err error) {
+	after, err := base64.RawURLEncoding.DecodeString(page.Token)
+	if err != nil {
+		err = ErrInvalidPageToken
+		return
+	}
+
+	grantedJobs, grantedProjects, err := r.principalGrants(principal)
+	if err != nil {
+		return
+	}
 
-	q := qb.Select(models.Jobs.Name())
+	found := map[string]models.Job{}
 
-	if !isAdmin {
-		q.Where(qb.Eq("user_id"))
+	// the principal's partition is clustered by job ID, so only its next page of jobs can be on this page
+	q := qb.Select(models.Jobs.Name()).Where(qb.Eq("user_id"), qb.Gt("job_id"))
+	if page.Limit > 0 {
+		q.Limit(uint(page.Limit))
 	}
+	stmt, names := q.ToCql()
 
-	stmt, names := q.Where(qb.Eq("job_id")).AllowFiltering().ToCql()
-	transaction := r.DB.Client.Query(stmt, names)
+	var owned []models.Job
+	if err = r.query(stmt, names).Bind(principal.UserID, string(after)).SelectRelease(&owned); err != nil {
+		r.Logger.Error(fmt.Sprintf("failed to get jobs of user %s", principal.UserID), &err)
+		err = errors.New("failed to get jobs")
+		return
+	}
+	for _, job := range owned {
+		found[job.JobID] = job
+	}
+	more := page.Limit > 0 && len(owned) == page.Limit
+
+	stmt, names = qb.Select(models.Jobs.Name()).Where(qb.Eq("project"), qb.Gt("job_id")).AllowFiltering().ToCql()
+	for project := range grantedProjects {
+		var rows []models.Job
+		if err = r.query(stmt, names).Bind(project, string(after)).SelectRelease(&rows); err != nil {
+			r.Logger.Error(fmt.Sprintf("failed to get jobs of project %s", project), &err)
+			err = errors.New("failed to get jobs")
+			return
+		}
+		for _, job := range rows {
+			found[job.JobID] = job
+		}
+	}
 
-	if isAdmin {
-		transaction.Bind(jobId)
-	} else {
-		transaction.Bind(userId, jobId)
+	// granted jobs are read one by one in ID order until there are enough to fill the page
+	var granted []string
+	for jobId := range grantedJobs {
+		if _, ok := found[jobId]; !ok && jobId > string(after) {
+			granted = append(granted, jobId)
+		}
 	}
+	slices.Sort(granted)
+
+	fetched := 0
+	for _, jobId := range granted {
+		if page.Limit > 0 && fetched == page.Limit {
+			more = true
+			break
+		}
 
-	var res models.Job
-	if err = transaction.Get(&res); err != nil {
-		data := map[string]any{
-			"jobId":   jobId,
-			"userId":  userId,
-			"isAdmin": isAdmin,
+		job, findErr := r.findJob(jobId)
+		if errors.Is(findErr, ErrJobNotFound) {
+			continue
+		}
+		if findErr != nil {
+			err = findErr
+			return
 		}
-		r.Logger.ErrorWithData("failed to get job", &err, &data)
+		found[jobId] = *job
+		fetched++
+	}
+
+	res := make([]models.Job, 0, len(found))
+	for _, job := range found {
+		res = append(res, job)
+	}
+	slices.SortFunc(res, func(a, b models.Job) int {
+		return strings.Compare(a.JobID, b.JobID)
+	})
+
+	if page.Limit > 0 && len(res) > page.Limit {
+		res = res[:page.Limit]
+		more = true
+	}
+	if more && len(res) > 0 {
+		nextToken = base64.RawURLEncoding.EncodeToString([]byte(res[len(res)-1].JobID))
+	}
+
+	jobs = &res
+
+	return
+}
+
+// GetOwnedJobs retrieves every job the user owns.
+func (r *JobRepository) GetOwnedJobs(userId string) (jobs *[]models.Job, err error) {
+	res := []models.Job{}
+	stmt, names := qb.Select(models.Jobs.Name()).Where(qb.Eq("user_id")).AllowFiltering().ToCql()
+	if err = r.query(stmt, names).Bind(userId).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("failed to get jobs of user %s", userId), &err)
+		err = errors.New("failed to get jobs")
 		return
 	}
 
-	job = &res
+	jobs = &res
 
 	return
 }
 
-// CreateJob creates a new job in the database with the provided job data and user ID.
-func (r *JobRepository) CreateJob(jobData models.Job, userId string) (job *models.Job, err error) {
+// GetJob retrieves a specific job by its ID if the principal can view it.
+func (r *JobRepository) GetJob(jobId string, principal models.Principal) (job *models.Job, err error) {
+	r.Logger.Debug(fmt.Sprintf("getting job %s for user %s", jobId, principal.UserID), utils.StringPtr(fmt.Sprintf("isAdmin: %t", principal.IsAdmin)))
+
+	return r.authorizeJob(jobId, principal, models.RoleViewer)
+}
+
+// GetJobRole retrieves a job along with the principal's role on it.
+func (r *JobRepository) GetJobRole(jobId string, principal models.Principal) (job *models.Job, role string, err error) {
+	if job, err = r.authorizeJob(jobId, principal, models.RoleViewer); err != nil {
+		return
+	}
+	role, err = r.jobRole(*job, principal)
+	return
+}
+
+// CreateJob creates a new job owned by the principal. Creating a job in a project requires the
+// editor role on the project.
+func (r *JobRepository) CreateJob(jobData models.Job, principal models.Principal) (job *models.Job, err error) {
+	if jobData.Project != "" {
+		if err = r.authorizeProject(jobData.Project, principal, models.RoleEditor); err != nil {
+			return
+		}
+	}
+
 	now := time.Now().UTC()
 
 	jobData.CreatedAt = now
 	jobData.UpdatedAt = now
 	jobData.JobID = ulid.Make().String()
-	jobData.UserID = userId
+	jobData.UserID = principal.UserID
 	jobData.RetryCount = 0
 	jobData.Status = models.JobStatusPending
 
@@ -122,7 +230,7 @@ func (r *JobRepository) CreateJob(jobData models.Job, userId string) (job *model
 	}
 	jobData.Payload = parser.SanitizedInput
 
-	if err = r.DB.Client.Query(models.Jobs.Insert()).BindStruct(jobData).ExecRelease(); err != nil {
+	if err = r.query(models.Jobs.Insert()).BindStruct(jobData).ExecRelease(); err != nil {
 		r.Logger.Error("failed to insert job into database", &err)
 		err = errors.New("failed to insert job into database")
 		return
@@ -135,7 +243,7 @@ func (r *JobRepository) CreateJob(jobData models.Job, userId string) (job *model
 		NextRunTime: job.ExecutionTime,
 	}
 
-	if err = r.DB.Client.Query(models.JobSchedules.Insert()).BindStruct(jobSchedule).ExecRelease(); err != nil {
+	if err = r.query(models.JobSchedules.Insert()).BindStruct(jobSchedule).ExecRelease(); err != nil {
 		r.Logger.Error(fmt.Sprintf("failed to create job schedule for job %s", job.JobID), &err)
 		err = fmt.Errorf("failed to create job schedule")
 		return
@@ -144,8 +252,23 @@ func (r *JobRepository) CreateJob(jobData models.Job, userId string) (job *model
 	return
 }
 
-// UpdateJob updates an existing job with new data.
-func (r *JobRepository) UpdateJob(jobUpdate models.JobUpdateRequest, jobId string, userId string) (job *models.Job, err error) {
+// UpdateJob updates an existing job with new data. Changing only the status requires the operator
+// role on the job; any other change requires the editor role, and moving the job into a project
+// the editor role on that project too.
+func (r *JobRepository) UpdateJob(jobUpdate models.JobUpdateRequest, jobId string, principal models.Principal) (job *models.Job, err error) {
+	required := utils.If(jobUpdate.OnlyStatus(), models.RoleOperator, models.RoleEditor)
+
+	existing, err := r.authorizeJob(jobId, principal, required)
+	if err != nil {
+		return
+	}
+
+	if jobUpdate.Project != nil && *jobUpdate.Project != "" && *jobUpdate.Project != existing.Project {
+		if err = r.authorizeProject(*jobUpdate.Project, principal, models.RoleEditor); err != nil {
+			return
+		}
+	}
+
 	if jobUpdate.Payload != nil {
 		parser := &utils.Parser{}
 		if err = parser.Parse(*jobUpdate.Payload); err != nil {
@@ -163,18 +286,11 @@ func (r *JobRepository) UpdateJob(jobUpdate models.JobUpdateRequest, jobId strin
 		jobUpdate.Payload = &parser.SanitizedInput
 	}
 
-	r.Logger.Info(fmt.Sprintf("updating job %s for user %s", jobId, userId), nil)
+	r.Logger.Info(fmt.Sprintf("updating job %s for user %s", jobId, principal.UserID), nil)
 
-	var res models.Job
-	stmt, names := qb.Select(models.Jobs.Name()).Where(qb.Eq("job_id")).AllowFiltering().ToCql()
-	if err = r.DB.Client.Query(stmt, names).Bind(jobId).Get(&res); err != nil {
-		r.Logger.Error(fmt.Sprintf("unable to get job %s", jobId), &err)
-		err = errors.New("unable to get job")
-		return
-	}
-
-	stmt, names = qb.Delete(models.Jobs.Name()).Where(qb.Eq("job_id"), qb.Eq("user_id"), qb.Eq("status")).ToCql()
-	if err = r.DB.Client.Query(stmt, names).Bind(res.JobID, res.UserID, res.Status).ExecRelease(); err != nil {
+	res := *existing
+	stmt, names := qb.Delete(models.Jobs.Name()).Where(qb.Eq("job_id"), qb.Eq("user_id"), qb.Eq("status")).ToCql()
+	if err = r.query(stmt, names).Bind(res.JobID, res.UserID, res.Status).ExecRelease(); err != nil {
 		r.Logger.Error(fmt.Sprintf("unable to delete job %s", jobId), &err)
 		err = errors.New("unable to delete job")
 		return
@@ -187,7 +303,7 @@ func (r *JobRepository) UpdateJob(jobUpdate models.JobUpdateRequest, jobId strin
 		return
 	}
 
-	if err = r.DB.Client.Query(models.Jobs.Insert()).BindStruct(res).ExecRelease(); err != nil {
+	if err = r.query(models.Jobs.Insert()).BindStruct(res).ExecRelease(); err != nil {
 		r.Logger.Error(fmt.Sprintf("unable to recreate job %s w/ updates", jobId), &err)
 		err = errors.New("unable to update job")
 		return
@@ -196,58 +312,225 @@ func (r *JobRepository) UpdateJob(jobUpdate models.JobUpdateRequest, jobId strin
 	job = &res
 
 	if jobUpdate.ExecutionTime != nil {
-		var jobSchedule models.JobSchedule
-		updatedSchedule := models.JobSchedule{
-			JobID:       job.JobID,
-			NextRunTime: *jobUpdate.ExecutionTime,
-		}
+		_, err = r.reschedule(job.JobID, *jobUpdate.ExecutionTime)
+	}
+	return
+}
 
-		stmt, names = qb.Select(models.JobSchedules.Name()).Where(qb.Eq("job_id")).AllowFiltering().ToCql()
-		if err = r.DB.Client.Query(stmt, names).Bind(job.JobID).Get(&jobSchedule); err != nil {
-			r.Logger.Error(fmt.Sprintf("unable to get job schedule for job %s", jobId), &err)
-			err = errors.New("unable to update job schedule")
+// RunJob schedules a job to run immediately if the principal is at least an operator of it. A
+// job that is not waiting for its next run already, e.g. because it finished, goes back to
+// pending so the scheduler dispatches it on its next poll.
+func (r *JobRepository) RunJob(jobId string, principal models.Principal) (jobSchedule *models.JobSchedule, err error) {
+	existing, err := r.authorizeJob(jobId, principal, models.RoleOperator)
+	if err != nil {
+		return
+	}
+	res := *existing
+
+	r.Logger.Info(fmt.Sprintf("running job %s for user %s", jobId, principal.UserID), nil)
+
+	if res.Status != models.JobStatusPending && res.Status != models.JobStatusScheduled && res.Status != models.JobStatusInProgress {
+		stmt, names := qb.Delete(models.Jobs.Name()).Where(qb.Eq("job_id"), qb.Eq("user_id"), qb.Eq("status")).ToCql()
+		if err = r.query(stmt, names).Bind(res.JobID, res.UserID, res.Status).ExecRelease(); err != nil {
+			r.Logger.Error(fmt.Sprintf("unable to delete job %s", jobId), &err)
+			err = errors.New("unable to run job")
 			return
 		}
 
-		stmt, names = qb.Delete(models.JobSchedules.Name()).Where(qb.Eq("job_id"), qb.Eq("next_run_time")).ToCql()
-		if err = r.DB.Client.Query(stmt, names).Bind(job.JobID, jobSchedule.NextRunTime).ExecRelease(); err != nil {
-			r.Logger.Error(fmt.Sprintf("unable to delete job schedule for job %s", jobId), &err)
-			err = errors.New("unable to update job schedule")
+		res.Status = models.JobStatusPending
+		res.RetryCount = 0
+		res.UpdatedAt = time.Now().UTC()
+		if err = r.query(models.Jobs.Insert()).BindStruct(res).ExecRelease(); err != nil {
+			r.Logger.Error(fmt.Sprintf("unable to recreate job %s to run it", jobId), &err)
+			err = errors.New("unable to run job")
 			return
 		}
+	}
+
+	return r.reschedule(jobId, time.Now().UTC())
+}
+
+// RetryJob applies a job's retry policy after one of its executions failed. A job with retries
+// left goes back to the scheduler with its retry count raised, to run again after an exponential
+// backoff; a job without is marked failed. A job that is no longer scheduled or running, e.g.
+// because it was cancelled meanwhile, is returned unchanged with changed false.
+func (r *JobRepository) RetryJob(jobId string, policy models.RetryConfig) (job *models.Job, changed bool, err error) {
+	existing, err := r.findJob(jobId)
+	if err != nil {
+		return
+	}
+	res := *existing
+
+	if res.Status != models.JobStatusScheduled && res.Status != models.JobStatusInProgress {
+		job = &res
+		return
+	}
+
+	stmt, names := qb.Delete(models.Jobs.Name()).Where(qb.Eq("job_id"), qb.Eq("user_id"), qb.Eq("status")).ToCql()
+	if err = r.query(stmt, names).Bind(res.JobID, res.UserID, res.Status).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to delete job %s", jobId), &err)
+		err = errors.New("unable to retry job")
+		return
+	}
+
+	var nextRunTime time.Time
+	if res.RetryCount < res.MaxRetries {
+		res.RetryCount++
+		res.Status = models.JobStatusPending
+		nextRunTime = time.Now().UTC().Add(utils.Backoff(res.RetryCount, policy.BaseDelay, policy.MaxDelay))
+		r.Logger.Info(fmt.Sprintf("retrying job %s at %s, retry %d of %d", jobId, nextRunTime.Format(time.RFC3339), res.RetryCount, res.MaxRetries), nil)
+	} else {
+		res.Status = models.JobStatusFailed
+		r.Logger.Info(fmt.Sprintf("failing job %s after %d retries", jobId, res.RetryCount), nil)
+	}
+
+	if err = r.query(models.Jobs.Insert()).BindStruct(res).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to recreate job %s for retry", jobId), &err)
+		err = errors.New("unable to retry job")
+		return
+	}
+
+	job = &res
+	changed = true
+
+	if res.Status == models.JobStatusPending {
+		_, err = r.reschedule(jobId, nextRunTime)
+	}
+	return
+}
+
+// CompleteJob moves a job on after one of its executions completed. A recurring job goes back to
+// the scheduler for its next run, computed from its frequency and time zone, with its retry count
+// reset; a one-time job is marked completed. A job that is no longer scheduled or running is
+// returned unchanged with changed false.
+func (r *JobRepository) CompleteJob(jobId string) (job *models.Job, changed bool, err error) {
+	existing, err := r.findJob(jobId)
+	if err != nil {
+		return
+	}
+	res := *existing
+
+	if res.Status != models.JobStatusScheduled && res.Status != models.JobStatusInProgress {
+		job = &res
+		return
+	}
+
+	now := time.Now().UTC()
+	nextRunTime, recurring, err := res.NextRun(now)
+	if err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to compute next run of job %s", jobId), &err)
+		err = errors.New("unable to complete job")
+		return
+	}
+
+	stmt, names := qb.Delete(models.Jobs.Name()).Where(qb.Eq("job_id"), qb.Eq("user_id"), qb.Eq("status")).ToCql()
+	if err = r.query(stmt, names).Bind(res.JobID, res.UserID, res.Status).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to delete job %s", jobId), &err)
+		err = errors.New("unable to complete job")
+		return
+	}
+
+	if recurring {
+		res.RetryCount = 0
+		res.Status = models.JobStatusPending
+		r.Logger.Info(fmt.Sprintf("job %s runs next at %s", jobId, nextRunTime.Format(time.RFC3339)), nil)
+	} else {
+		res.Status = models.JobStatusCompleted
+	}
+	res.UpdatedAt = now
+
+	if err = r.query(models.Jobs.Insert()).BindStruct(res).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to recreate job %s after completion", jobId), &err)
+		err = errors.New("unable to complete job")
+		return
+	}
+
+	job = &res
+	changed = true
+
+	if recurring {
+		_, err = r.reschedule(jobId, nextRunTime)
+	}
+	return
+}
+
+// reschedule replaces a job's schedule with one whose next run is at nextRunTime, creating it if
+// the scheduler pruned it after the job finished.
+func (r *JobRepository) reschedule(jobId string, nextRunTime time.Time) (jobSchedule *models.JobSchedule, err error) {
+	var existing []models.JobSchedule
+	updatedSchedule := models.JobSchedule{
+		JobID:       jobId,
+		NextRunTime: nextRunTime,
+	}
 
-		if err = r.DB.Client.Query(models.JobSchedules.Insert()).BindStruct(updatedSchedule).ExecRelease(); err != nil {
-			r.Logger.Error(fmt.Sprintf("unable to recreate job schedule for job %s", job.JobID), &err)
+	stmt, names := qb.Select(models.JobSchedules.Name()).Where(qb.Eq("job_id")).AllowFiltering().ToCql()
+	if err = r.query(stmt, names).Bind(jobId).SelectRelease(&existing); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get job schedule for job %s", jobId), &err)
+		err = errors.New("unable to update job schedule")
+		return
+	}
+
+	if len(existing) > 0 {
+		updatedSchedule.LastRunTime = existing[0].LastRunTime
+
+		stmt, names = qb.Delete(models.JobSchedules.Name()).Where(qb.Eq("job_id")).ToCql()
+		if err = r.query(stmt, names).Bind(jobId).ExecRelease(); err != nil {
+			r.Logger.Error(fmt.Sprintf("unable to delete job schedule for job %s", jobId), &err)
 			err = errors.New("unable to update job schedule")
 			return
 		}
 	}
+
+	if err = r.query(models.JobSchedules.Insert()).BindStruct(updatedSchedule).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to recreate job schedule for job %s", jobId), &err)
+		err = errors.New("unable to update job schedule")
+		return
+	}
+
+	jobSchedule = &updatedSchedule
+
 	return
 }
 
-// DeleteJob removes a job from the database by its ID.
-func (r *JobRepository) DeleteJob(jobId string) (err error) {
-	var job models.Job
-	stmt, names := qb.Select(models.Jobs.Name()).Where(qb.Eq("job_id")).AllowFiltering().ToCql()
-	if err = r.DB.Client.Query(stmt, names).Bind(jobId).Get(&job); err != nil {
-		r.Logger.Error(fmt.Sprintf("job %s not found", jobId), &err)
-		err = fmt.Errorf("job %s not found", jobId)
+// DeleteJob removes a job, along with the grants on it, if the principal is an admin of it and
+// returns the deleted job.
+func (r *JobRepository) DeleteJob(jobId string, principal models.Principal) (job *models.Job, err error) {
+	existing, err := r.authorizeJob(jobId, principal, models.RoleAdmin)
+	if err != nil {
 		return
 	}
+	res := *existing
 
-	r.Logger.Info(fmt.Sprintf("deleting job %s for user %s", jobId, job.UserID), nil)
-	stmt, names = qb.Delete(models.Jobs.Name()).Where(qb.Eq("user_id"), qb.Eq("job_id"), qb.Eq("status")).ToCql()
-	if err = r.DB.Client.Query(stmt, names).Bind(job.UserID, job.JobID, job.Status).ExecRelease(); err != nil {
+	r.Logger.Info(fmt.Sprintf("deleting job %s for user %s", jobId, res.UserID), nil)
+	stmt, names := qb.Delete(models.Jobs.Name()).Where(qb.Eq("user_id"), qb.Eq("job_id"), qb.Eq("status")).ToCql()
+	if err = r.query(stmt, names).Bind(res.UserID, res.JobID, res.Status).ExecRelease(); err != nil {
 		r.Logger.ErrorWithData(fmt.Sprintf("unable to delete job %s", jobId), &err, &map[string]any{
 			"jobId":  jobId,
-			"userId": job.UserID,
-			"status": job.Status,
+			"userId": res.UserID,
+			"status": res.Status,
 		})
 		err = fmt.Errorf("unable to delete job %s", jobId)
 		return
 	}
 
-	// TODO: Also delete any associated schedules, logs, etc.
+	stmt, names = qb.Delete(models.JobGrants.Name()).Where(qb.Eq("resource_type"), qb.Eq("resource_id")).ToCql()
+	if err = r.query(stmt, names).Bind(models.GrantResourceJob, jobId).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to delete grants on job %s", jobId), &err)
+		err = fmt.Errorf("unable to delete grants on job %s", jobId)
+		return
+	}
+
+	// the scheduler would otherwise keep finding the schedule once it is overdue
+	stmt, names = qb.Delete(models.JobSchedules.Name()).Where(qb.Eq("job_id")).ToCql()
+	if err = r.query(stmt, names).Bind(jobId).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to delete schedule of job %s", jobId), &err)
+		err = fmt.Errorf("unable to delete schedule of job %s", jobId)
+		return
+	}
+
+	// TODO: Also delete any associated logs, etc.
+
+	job = &res
 
 	return
 }
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/scylladb/gocqlx/v3/qb"
+)
+
+// ErrQuotaExceeded is returned when the owner of a job has used up one of their quotas.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+type QuotaRepository struct {
+	Repository
+	quotas models.QuotaConfig
+}
+
+func NewQuotaRepository(db *store.DBSession, logger *graylogger.GrayLogger, quotas models.QuotaConfig) *QuotaRepository {
+	return &QuotaRepository{
+		Repository: Repository{
+			DB:     db,
+			Logger: logger,
+		},
+		quotas: quotas,
+	}
+}
+
+// WithContext returns a copy of the repository whose queries and logs are part of the trace in ctx.
+func (r *QuotaRepository) WithContext(ctx context.Context) *QuotaRepository {
+	return &QuotaRepository{Repository: r.Repository.withContext(ctx), quotas: r.quotas}
+}
+
+// GetUsage retrieves a user's current usage against each of their quotas.
+func (r *QuotaRepository) GetUsage(userId string) (usage *models.Usage, err error) {
+	activeJobs, err := r.countActiveJobs(userId)
+	if err != nil {
+		return
+	}
+
+	counter, err := r.getCounter(userId, time.Now().UTC())
+	if err != nil {
+		return
+	}
+
+	usage = &models.Usage{
+		UserID:              userId,
+		Day:                 counter.Day,
+		ActiveJobs:          models.QuotaUsage{Used: activeJobs, Limit: int64(r.quotas.MaxActiveJobs)},
+		DailyExecutions:     models.QuotaUsage{Used: counter.Executions, Limit: int64(r.quotas.MaxDailyExecutions)},
+		DailySandboxSeconds: models.QuotaUsage{Used: counter.SandboxMs / 1000, Limit: int64(r.quotas.MaxDailySandboxTime.Seconds())},
+	}
+
+	return
+}
+
+// CheckJobQuota returns ErrQuotaExceeded if the user may not own another active job.
+func (r *QuotaRepository) CheckJobQuota(userId string) (err error) {
+	if r.quotas.MaxActiveJobs == 0 {
+		return
+	}
+
+	activeJobs, err := r.countActiveJobs(userId)
+	if err != nil {
+		return
+	}
+
+	if activeJobs >= int64(r.quotas.MaxActiveJobs) {
+		err = fmt.Errorf("%w: user %s already has %d active jobs", ErrQuotaExceeded, userId, activeJobs)
+	}
+
+	return
+}
+
+// CheckExecutionQuota returns ErrQuotaExceeded if the user's jobs may not run again today, either
+// because they have run the maximum number of times or used the maximum sandbox time.
+func (r *QuotaRepository) CheckExecutionQuota(userId string) (err error) {
+	if r.quotas.MaxDailyExecutions == 0 && r.quotas.MaxDailySandboxTime == 0 {
+		return
+	}
+
+	counter, err := r.getCounter(userId, time.Now().UTC())
+	if err != nil {
+		return
+	}
+
+	switch {
+	case r.quotas.MaxDailyExecutions > 0 && counter.Executions >= int64(r.quotas.MaxDailyExecutions):
+		err = fmt.Errorf("%w: user %s has run %d executions today", ErrQuotaExceeded, userId, counter.Executions)
+	case r.quotas.MaxDailySandboxTime > 0 && counter.SandboxMs >= r.quotas.MaxDailySandboxTime.Milliseconds():
+		err = fmt.Errorf("%w: user %s has used %s of sandbox time today", ErrQuotaExceeded, userId, time.Duration(counter.SandboxMs)*time.Millisecond)
+	}
+
+	return
+}
+
+// RecordExecution counts an execution of one of the user's jobs against today's quota.
+func (r *QuotaRepository) RecordExecution(userId string) (err error) {
+	return r.addToCounter(userId, "executions", 1)
+}
+
+// RecordSandboxTime counts sandbox wall-time used by one of the user's jobs against today's quota.
+func (r *QuotaRepository) RecordSandboxTime(userId string, d time.Duration) (err error) {
+	return r.addToCounter(userId, "sandbox_ms", d.Milliseconds())
+}
+
+// countActiveJobs counts the user's jobs that are not in a terminal status.
+func (r *QuotaRepository) countActiveJobs(userId string) (count int64, err error) {
+	var res []models.Job
+	stmt, names := qb.Select(models.Jobs.Name()).Columns("status").Where(qb.Eq("user_id")).ToCql()
+	if err = r.query(stmt, names).Bind(userId).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get jobs of user %s", userId), &err)
+		err = errors.New("unable to get jobs")
+		return
+	}
+
+	for _, job := range res {
+		if !models.IsTerminalStatus(job.Status) {
+			count++
+		}
+	}
+
+	return
+}
+
+// getCounter retrieves the user's usage counter for the UTC day of t. Days without usage have no
+// row and return an empty counter.
+func (r *QuotaRepository) getCounter(userId string, t time.Time) (counter models.UsageCounter, err error) {
+	day := t.Format(models.UsageDayFormat)
+
+	var res []models.UsageCounter
+	if err = r.query(models.UsageCounters.Get()).Bind(userId, day).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get usage of user %s on %s", userId, day), &err)
+		err = errors.New("unable to get usage")
+		return
+	}
+
+	if len(res) > 0 {
+		counter = res[0]
+	}
+	counter.UserID = userId
+	counter.Day = day
+
+	return
+}
+
+func (r *QuotaRepository) addToCounter(userId string, column string, delta int64) (err error) {
+	day := time.Now().UTC().Format(models.UsageDayFormat)
+
+	stmt, names := qb.Update(models.UsageCounters.Name()).Add(column).Where(qb.Eq("user_id"), qb.Eq("day")).ToCql()
+	if err = r.query(stmt, names).Bind(delta, userId, day).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to add to %s usage of user %s", column, userId), &err)
+		err = errors.New("unable to record usage")
+	}
+
+	return
+}
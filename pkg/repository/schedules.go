@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -12,33 +13,70 @@ import (
 
 type ScheduleRepository struct {
 	Repository
+	authorizer
 }
 
 func NewScheduleRepository(db *store.DBSession, logger *graylogger.GrayLogger) *ScheduleRepository {
-	return &ScheduleRepository{
-		Repository{
-			DB:     db,
-			Logger: logger,
-		},
+	repo := Repository{
+		DB:     db,
+		Logger: logger,
 	}
+	return newScheduleRepository(repo, &repo)
 }
 
-// GetSchedules retrieves all job schedules from the database, applying any filters specified in queryParams.
-func (r *ScheduleRepository) GetSchedules(queryParams map[string][]string) (jobSchedules *[]models.JobSchedule, err error) {
+// newScheduleRepository returns a repository that authorizes principals against the records loaded
+// by lookup.
+func newScheduleRepository(repo Repository, lookup accessLookup) *ScheduleRepository {
+	return &ScheduleRepository{repo, authorizer{lookup}}
+}
+
+// WithContext returns a copy of the repository whose queries and logs are part of the trace in ctx.
+func (r *ScheduleRepository) WithContext(ctx context.Context) *ScheduleRepository {
+	return newScheduleRepository(r.Repository.withContext(ctx), r.lookup.inContext(ctx))
+}
+
+// GetSchedules retrieves a page of the schedules of jobs the principal can view, applying any
+// filters specified in queryParams. Pages may hold fewer schedules than the limit.
+func (r *ScheduleRepository) GetSchedules(queryParams map[string][]string, page models.PageRequest, principal models.Principal) (jobSchedules *[]models.JobSchedule, nextToken string, err error) {
 	r.Logger.Info("retrieving all job schedules", nil)
 
-	var res []models.JobSchedule
-	q, err := r.getFilteredQuery(queryParams, models.JobSchedules.Name(), models.JobSchedules.Metadata().Columns)
-	if err != nil {
-		r.Logger.Error("unable to get filtered query for job schedules", &err)
-		err = errors.New("unable to get job schedules")
-		return
+	var visible map[string]struct{}
+	if !principal.IsAdmin {
+		if visible, err = r.visibleJobs(principal); err != nil {
+			return
+		}
 	}
 
-	if err = q.SelectRelease(&res); err != nil {
-		r.Logger.Error("unable to get job schedules from db", &err)
-		err = errors.New("unable to get job schedules")
-		return
+	res := []models.JobSchedule{}
+	for {
+		q, qErr := r.getFilteredQuery(queryParams, models.JobSchedules.Name(), models.JobSchedules.Metadata().Columns)
+		if qErr != nil {
+			err = qErr
+			r.Logger.Error("unable to get filtered query for job schedules", &err)
+			err = errors.New("unable to get job schedules")
+			return
+		}
+
+		var rows []models.JobSchedule
+		if nextToken, err = selectPage(q, page, &rows); err != nil {
+			r.Logger.Error("unable to get job schedules from db", &err)
+			if !errors.Is(err, ErrInvalidPageToken) {
+				err = errors.New("unable to get job schedules")
+			}
+			return
+		}
+
+		for _, schedule := range rows {
+			if _, ok := visible[schedule.JobID]; ok || principal.IsAdmin {
+				res = append(res, schedule)
+			}
+		}
+
+		// pages whose schedules were all filtered out are skipped rather than returned empty
+		if len(res) > 0 || nextToken == "" {
+			break
+		}
+		page.Token = nextToken
 	}
 
 	jobSchedules = &res
@@ -46,12 +84,16 @@ func (r *ScheduleRepository) GetSchedules(queryParams map[string][]string) (jobS
 	return
 }
 
-// GetSchedule retrieves a specific job schedule by its ID from the database.
-func (r *ScheduleRepository) GetSchedule(id string) (jobSchedule *models.JobSchedule, err error) {
+// GetSchedule retrieves the schedule of a job by the job's ID if the principal can view the job.
+func (r *ScheduleRepository) GetSchedule(id string, principal models.Principal) (jobSchedule *models.JobSchedule, err error) {
 	r.Logger.Info(fmt.Sprintf("retrieving job schedule %s", id), nil)
 
+	if _, err = r.authorizeJob(id, principal, models.RoleViewer); err != nil {
+		return
+	}
+
 	var res models.JobSchedule
-	if err = r.DB.Client.Query(models.JobSchedules.Select()).Bind(id).Get(&res); err != nil {
+	if err = r.query(models.JobSchedules.Select()).Bind(id).Get(&res); err != nil {
 		r.Logger.Error(fmt.Sprintf("unable to get job schedule %s from db", id), &err)
 		err = fmt.Errorf("unable to get job schedule %s", id)
 		return
@@ -62,11 +104,16 @@ func (r *ScheduleRepository) GetSchedule(id string) (jobSchedule *models.JobSche
 	return
 }
 
-// CreateSchedule inserts a new job schedule into the database.
-func (r *ScheduleRepository) CreateSchedule(scheduleData models.JobSchedule) (jobSchedule *models.JobSchedule, err error) {
+// CreateSchedule inserts a new job schedule into the database if the principal is an editor of
+// the job.
+func (r *ScheduleRepository) CreateSchedule(scheduleData models.JobSchedule, principal models.Principal) (jobSchedule *models.JobSchedule, err error) {
 	r.Logger.Info("creating new job schedule", nil)
 
-	if err = r.DB.Client.Query(models.JobSchedules.Insert()).BindStruct(&scheduleData).ExecRelease(); err != nil {
+	if _, err = r.authorizeJob(scheduleData.JobID, principal, models.RoleEditor); err != nil {
+		return
+	}
+
+	if err = r.query(models.JobSchedules.Insert()).BindStruct(&scheduleData).ExecRelease(); err != nil {
 		r.Logger.Error("unable to create job schedule in db", &err)
 		err = errors.New("unable to create job schedule")
 		return
@@ -77,18 +124,23 @@ func (r *ScheduleRepository) CreateSchedule(scheduleData models.JobSchedule) (jo
 	return
 }
 
-// UpdateSchedule modifies an existing job schedule in the database based on the provided updates.
-func (r *ScheduleRepository) UpdateSchedule(id string, scheduleUpdates models.JobScheduleUpdateRequest) (jobSchedule *models.JobSchedule, err error) {
+// UpdateSchedule modifies an existing job schedule in the database based on the provided updates
+// if the principal is an editor of the job.
+func (r *ScheduleRepository) UpdateSchedule(id string, scheduleUpdates models.JobScheduleUpdateRequest, principal models.Principal) (jobSchedule *models.JobSchedule, err error) {
 	r.Logger.Info(fmt.Sprintf("updating job schedule %s", id), nil)
 
+	if _, err = r.authorizeJob(id, principal, models.RoleEditor); err != nil {
+		return
+	}
+
 	var existingSchedule models.JobSchedule
-	if err = r.DB.Client.Query(models.JobSchedules.Select()).Bind(id).Get(&existingSchedule); err != nil {
+	if err = r.query(models.JobSchedules.Select()).Bind(id).Get(&existingSchedule); err != nil {
 		r.Logger.Error(fmt.Sprintf("unable to get job schedule %s in db", id), &err)
 		err = fmt.Errorf("unable to get job schedule %s", id)
 		return
 	}
 
-	if err = r.DB.Client.Query(models.JobSchedules.Delete()).BindMap(map[string]any{"job_id": id, "next_run_time": existingSchedule.NextRunTime}).ExecRelease(); err != nil {
+	if err = r.query(models.JobSchedules.Delete()).BindMap(map[string]any{"job_id": id, "next_run_time": existingSchedule.NextRunTime}).ExecRelease(); err != nil {
 		r.Logger.Error(fmt.Sprintf("unable to delete job schedule %s in db", id), &err)
 		err = fmt.Errorf("unable to update job schedule %s", id)
 		return
@@ -100,7 +152,7 @@ func (r *ScheduleRepository) UpdateSchedule(id string, scheduleUpdates models.Jo
 		return
 	}
 
-	if err = r.DB.Client.Query(models.JobSchedules.Insert()).BindStruct(&existingSchedule).ExecRelease(); err != nil {
+	if err = r.query(models.JobSchedules.Insert()).BindStruct(&existingSchedule).ExecRelease(); err != nil {
 		r.Logger.Error("unable to update job schedule in db", &err)
 		err = errors.New("unable to update job schedule")
 		return
@@ -110,18 +162,23 @@ func (r *ScheduleRepository) UpdateSchedule(id string, scheduleUpdates models.Jo
 	return
 }
 
-// DeleteSchedule removes a job schedule from the database by its ID.
-func (r *ScheduleRepository) DeleteSchedule(id string) (err error) {
+// DeleteSchedule removes a job schedule from the database by its ID if the principal is an
+// editor of the job. Admins may also remove the schedule left behind by a deleted job.
+func (r *ScheduleRepository) DeleteSchedule(id string, principal models.Principal) (err error) {
 	r.Logger.Info(fmt.Sprintf("deleting job schedule %s", id), nil)
 
+	if _, err = r.authorizeJob(id, principal, models.RoleEditor); err != nil && !(principal.IsAdmin && errors.Is(err, ErrJobNotFound)) {
+		return
+	}
+
 	var existingSchedule models.JobSchedule
-	if err = r.DB.Client.Query(models.JobSchedules.Select()).Bind(id).Get(&existingSchedule); err != nil {
+	if err = r.query(models.JobSchedules.Select()).Bind(id).Get(&existingSchedule); err != nil {
 		r.Logger.Error(fmt.Sprintf("unable to get job schedule %s in db", id), &err)
 		err = fmt.Errorf("unable to get job schedule %s", id)
 		return
 	}
 
-	if err = r.DB.Client.Query(models.JobSchedules.Delete()).BindMap(map[string]interface{}{"job_id": id, "next_run_time": existingSchedule.NextRunTime}).ExecRelease(); err != nil {
+	if err = r.query(models.JobSchedules.Delete()).BindMap(map[string]interface{}{"job_id": id, "next_run_time": existingSchedule.NextRunTime}).ExecRelease(); err != nil {
 		r.Logger.Error(fmt.Sprintf("unable to delete job schedule %s in db", id), &err)
 		err = fmt.Errorf("unable to delete job schedule %s", id)
 		return
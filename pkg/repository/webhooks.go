@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/oklog/ulid/v2"
+	"github.com/scylladb/gocqlx/v3/qb"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type WebhookRepository struct {
+	Repository
+}
+
+func NewWebhookRepository(db *store.DBSession, logger *graylogger.GrayLogger) *WebhookRepository {
+	return &WebhookRepository{
+		Repository{
+			DB:     db,
+			Logger: logger,
+		},
+	}
+}
+
+// WithContext returns a copy of the repository whose queries and logs are part of the trace in ctx.
+func (r *WebhookRepository) WithContext(ctx context.Context) *WebhookRepository {
+	return &WebhookRepository{r.Repository.withContext(ctx)}
+}
+
+// GetWebhooks retrieves the webhooks of a user, or of every user if the user is an admin.
+func (r *WebhookRepository) GetWebhooks(userId string, isAdmin bool) (webhooks *[]models.Webhook, err error) {
+	q := qb.Select(models.Webhooks.Name())
+	if !isAdmin {
+		q.Where(qb.Eq("user_id"))
+	}
+
+	stmt, names := q.ToCql()
+	transaction := r.query(stmt, names)
+	if !isAdmin {
+		transaction.Bind(userId)
+	}
+
+	var res []models.Webhook
+	if err = transaction.SelectRelease(&res); err != nil {
+		r.Logger.ErrorWithData("unable to get webhooks", &err, &map[string]any{
+			"userId":  userId,
+			"isAdmin": isAdmin,
+		})
+		err = errors.New("unable to get webhooks")
+		return
+	}
+
+	webhooks = &res
+
+	return
+}
+
+// GetWebhook retrieves a webhook by its ID if it belongs to the user or the user is an admin.
+func (r *WebhookRepository) GetWebhook(webhookId string, userId string, isAdmin bool) (webhook *models.Webhook, err error) {
+	q := qb.Select(models.Webhooks.Name()).Where(qb.Eq("webhook_id"))
+	if !isAdmin {
+		q.Where(qb.Eq("user_id"))
+	}
+
+	stmt, names := q.AllowFiltering().ToCql()
+	transaction := r.query(stmt, names)
+	if isAdmin {
+		transaction.Bind(webhookId)
+	} else {
+		transaction.Bind(webhookId, userId)
+	}
+
+	var res []models.Webhook
+	if err = transaction.SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get webhook %s", webhookId), &err)
+		err = fmt.Errorf("unable to get webhook %s", webhookId)
+		return
+	}
+
+	if len(res) == 0 {
+		err = ErrWebhookNotFound
+		return
+	}
+
+	webhook = &res[0]
+
+	return
+}
+
+// CreateWebhook stores a new webhook for a user with a generated signing secret.
+func (r *WebhookRepository) CreateWebhook(webhookData models.Webhook, userId string) (webhook *models.Webhook, err error) {
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		r.Logger.Error("unable to generate webhook secret", &err)
+		err = errors.New("unable to generate webhook secret")
+		return
+	}
+
+	webhookData.WebhookID = ulid.Make().String()
+	webhookData.UserID = userId
+	webhookData.Secret = hex.EncodeToString(secret)
+	webhookData.CreatedAt = time.Now().UTC()
+
+	if err = r.query(models.Webhooks.Insert()).BindStruct(&webhookData).ExecRelease(); err != nil {
+		r.Logger.Error("unable to create webhook", &err)
+		err = errors.New("unable to create webhook")
+		return
+	}
+
+	webhook = &webhookData
+
+	return
+}
+
+// DeleteWebhook removes a webhook if it belongs to the user or the user is an admin.
+func (r *WebhookRepository) DeleteWebhook(webhookId string, userId string, isAdmin bool) (err error) {
+	webhook, err := r.GetWebhook(webhookId, userId, isAdmin)
+	if err != nil {
+		return
+	}
+
+	if err = r.query(models.Webhooks.Delete()).BindStruct(webhook).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to delete webhook %s", webhookId), &err)
+		err = fmt.Errorf("unable to delete webhook %s", webhookId)
+	}
+
+	return
+}
+
+// SaveDelivery creates or updates a delivery log entry.
+func (r *WebhookRepository) SaveDelivery(delivery models.WebhookDelivery) (err error) {
+	delivery.UpdatedAt = time.Now().UTC()
+
+	if err = r.query(models.WebhookDeliveries.Insert()).BindStruct(&delivery).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to save delivery %s of webhook %s", delivery.DeliveryID, delivery.WebhookID), &err)
+		err = errors.New("unable to save webhook delivery")
+	}
+
+	return
+}
+
+// GetPendingDeliveries retrieves the deliveries of every webhook that have not succeeded or
+// failed yet.
+func (r *WebhookRepository) GetPendingDeliveries() (deliveries *[]models.WebhookDelivery, err error) {
+	var res []models.WebhookDelivery
+	stmt, names := qb.Select(models.WebhookDeliveries.Name()).Where(qb.Eq("status")).AllowFiltering().ToCql()
+	if err = r.query(stmt, names).Bind(models.DeliveryStatusPending).SelectRelease(&res); err != nil {
+		r.Logger.Error("unable to get pending webhook deliveries", &err)
+		err = errors.New("unable to get pending webhook deliveries")
+		return
+	}
+
+	deliveries = &res
+
+	return
+}
+
+// ClaimDelivery takes over a pending delivery by bumping its update time, provided nobody saved
+// it since it was read. Only one of the replicas claiming the same delivery succeeds.
+func (r *WebhookRepository) ClaimDelivery(delivery models.WebhookDelivery) (claimed *models.WebhookDelivery, err error) {
+	res := delivery
+	res.UpdatedAt = time.Now().UTC()
+
+	stmt, names := qb.Update(models.WebhookDeliveries.Name()).
+		Set("updated_at").
+		Where(qb.Eq("webhook_id"), qb.Eq("delivery_id")).
+		If(qb.EqNamed("updated_at", "previous_updated_at")).
+		ToCql()
+	applied, err := r.query(stmt, names).Bind(res.UpdatedAt, res.WebhookID, res.DeliveryID, delivery.UpdatedAt).ExecCASRelease()
+	if err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to claim delivery %s of webhook %s", delivery.DeliveryID, delivery.WebhookID), &err)
+		err = errors.New("unable to claim webhook delivery")
+		return
+	}
+
+	if applied {
+		claimed = &res
+	}
+
+	return
+}
+
+// GetDeliveries retrieves a page of a webhook's deliveries, newest first.
+func (r *WebhookRepository) GetDeliveries(webhookId string, page models.PageRequest) (deliveries *[]models.WebhookDelivery, nextToken string, err error) {
+	var res []models.WebhookDelivery
+	q := r.query(models.WebhookDeliveries.Select()).Bind(webhookId)
+	if nextToken, err = selectPage(q, page, &res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get deliveries of webhook %s", webhookId), &err)
+		if !errors.Is(err, ErrInvalidPageToken) {
+			err = errors.New("unable to get webhook deliveries")
+		}
+		return
+	}
+
+	deliveries = &res
+
+	return
+}
+
+// GetDelivery retrieves a single delivery of a webhook.
+func (r *WebhookRepository) GetDelivery(webhookId string, deliveryId string) (delivery *models.WebhookDelivery, err error) {
+	var res models.WebhookDelivery
+	if err = r.query(models.WebhookDeliveries.Get()).Bind(webhookId, deliveryId).GetRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get delivery %s of webhook %s", deliveryId, webhookId), &err)
+		err = fmt.Errorf("unable to get delivery %s", deliveryId)
+		return
+	}
+
+	delivery = &res
+
+	return
+}
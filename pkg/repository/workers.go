@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/scylladb/gocqlx/v3/qb"
+)
+
+// ErrWorkerNotFound is returned for workers that never registered. Workers receiving it for a
+// heartbeat register again.
+var ErrWorkerNotFound = errors.New("worker not found")
+
+type WorkerRepository struct {
+	Repository
+}
+
+func NewWorkerRepository(db *store.DBSession, logger *graylogger.GrayLogger) *WorkerRepository {
+	return &WorkerRepository{
+		Repository{
+			DB:     db,
+			Logger: logger,
+		},
+	}
+}
+
+// WithContext returns a copy of the repository whose queries and logs are part of the trace in ctx.
+func (r *WorkerRepository) WithContext(ctx context.Context) *WorkerRepository {
+	return &WorkerRepository{r.Repository.withContext(ctx)}
+}
+
+// GetWorkers retrieves every registered worker.
+func (r *WorkerRepository) GetWorkers() (workers *[]models.WorkerNode, err error) {
+	var res []models.WorkerNode
+	stmt, names := qb.Select(models.Workers.Name()).ToCql()
+	if err = r.query(stmt, names).SelectRelease(&res); err != nil {
+		r.Logger.Error("unable to get workers", &err)
+		err = errors.New("unable to get workers")
+		return
+	}
+
+	workers = &res
+
+	return
+}
+
+// GetWorker retrieves a registered worker by its ID.
+func (r *WorkerRepository) GetWorker(workerId string) (worker *models.WorkerNode, err error) {
+	var res []models.WorkerNode
+	if err = r.query(models.Workers.Get()).Bind(workerId).SelectRelease(&res); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to get worker %s", workerId), &err)
+		err = fmt.Errorf("unable to get worker %s", workerId)
+		return
+	}
+
+	if len(res) == 0 {
+		err = ErrWorkerNotFound
+		return
+	}
+
+	worker = &res[0]
+
+	return
+}
+
+// RegisterWorker records a worker that started, replacing what an earlier run of the same worker
+// registered. Registering counts as the worker's first heartbeat.
+func (r *WorkerRepository) RegisterWorker(workerId string, registration models.WorkerRegistration) (worker *models.WorkerNode, err error) {
+	now := time.Now().UTC()
+	res := models.WorkerNode{
+		WorkerID:      workerId,
+		Host:          registration.Host,
+		Capacity:      registration.Capacity,
+		Languages:     registration.Languages,
+		Version:       registration.Version,
+		RegisteredAt:  now,
+		LastHeartbeat: now,
+	}
+
+	if err = r.query(models.Workers.Insert()).BindStruct(&res).ExecRelease(); err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to register worker %s", workerId), &err)
+		err = fmt.Errorf("unable to register worker %s", workerId)
+		return
+	}
+
+	worker = &res
+
+	return
+}
+
+// Heartbeat records that a registered worker is alive along with its current load.
+func (r *WorkerRepository) Heartbeat(workerId string, heartbeat models.WorkerHeartbeat) (worker *models.WorkerNode, err error) {
+	now := time.Now().UTC()
+
+	// IF EXISTS keeps heartbeats of unregistered workers from creating partial rows
+	stmt, names := qb.Update(models.Workers.Name()).Set("current_load", "last_heartbeat").Where(qb.Eq("worker_id")).Existing().ToCql()
+	applied, err := r.query(stmt, names).Bind(heartbeat.CurrentLoad, now, workerId).ExecCASRelease()
+	if err != nil {
+		r.Logger.Error(fmt.Sprintf("unable to record heartbeat of worker %s", workerId), &err)
+		err = fmt.Errorf("unable to record heartbeat of worker %s", workerId)
+		return
+	}
+
+	if !applied {
+		err = ErrWorkerNotFound
+		return
+	}
+
+	return r.GetWorker(workerId)
+}
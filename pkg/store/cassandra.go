@@ -1,11 +1,14 @@
 package store
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
 	"github.com/gocql/gocql"
 	"github.com/julianstephens/distributed-job-manager/pkg/config"
+	"github.com/julianstephens/distributed-job-manager/pkg/metrics"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
 	"github.com/scylladb/gocqlx/v3"
 )
 
@@ -36,9 +39,19 @@ func initStore(ks string) error {
 	var err error
 	cluster := gocql.NewCluster(fmt.Sprintf("%s:%s", conf.Cassandra.Host, conf.Cassandra.Port))
 	cluster.Keyspace = ks
+	cluster.QueryObserver = queryObservers{metrics.QueryObserver{}, tracing.QueryObserver{}}
 
 	if Session, err = gocqlx.WrapSession(cluster.CreateSession()); err != nil {
 		return err
 	}
 	return nil
 }
+
+// queryObservers passes every query observation on to each of its observers.
+type queryObservers []gocql.QueryObserver
+
+func (o queryObservers) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	for _, observer := range o {
+		observer.ObserveQuery(ctx, q)
+	}
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// amqpCarrier reads and writes trace context in AMQP message headers.
+type amqpCarrier amqp091.Table
+
+func (c amqpCarrier) Get(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+func (c amqpCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+func (c amqpCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// StartPublish starts a producer span for a message sent to destination and writes its context
+// into headers, which is allocated if nil. The caller must end the span once the message is sent.
+func StartPublish(ctx context.Context, destination string, headers amqp091.Table) (context.Context, trace.Span, amqp091.Table) {
+	ctx, span := Tracer().Start(ctx, "publish "+destination,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystemRabbitmq, semconv.MessagingOperationTypePublish, semconv.MessagingDestinationName(destination)),
+	)
+
+	if headers == nil {
+		headers = amqp091.Table{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, amqpCarrier(headers))
+
+	return ctx, span, headers
+}
+
+// StartProcess starts a consumer span for a message received from source, continuing the trace
+// the publisher wrote into its headers. The caller must end the span once the message is handled.
+func StartProcess(ctx context.Context, source string, msg amqp091.Delivery) (context.Context, trace.Span) {
+	if msg.Headers != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, amqpCarrier(msg.Headers))
+	}
+
+	return Tracer().Start(ctx, "process "+source,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(semconv.MessagingSystemRabbitmq, semconv.MessagingOperationTypeDeliver, semconv.MessagingDestinationName(source)),
+	)
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gocql/gocql"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryObserver records a client span for every query attempt made in a traced context. Queries
+// made outside a trace are not recorded, so background polling does not start traces of its own.
+type QueryObserver struct{}
+
+func (QueryObserver) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	operation, table := describeQuery(q.Statement)
+
+	_, span := Tracer().Start(ctx, strings.TrimSpace(operation+" "+table),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(q.Start),
+		trace.WithAttributes(
+			semconv.DBSystemCassandra,
+			semconv.DBNamespace(q.Keyspace),
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(q.Statement),
+		),
+	)
+	if q.Err != nil {
+		span.RecordError(q.Err)
+		span.SetStatus(codes.Error, q.Err.Error())
+	}
+	span.End(trace.WithTimestamp(q.End))
+}
+
+// describeQuery returns the operation of a CQL statement and the table it acts on, if found.
+func describeQuery(statement string) (operation string, table string) {
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	operation = strings.ToUpper(fields[0])
+
+	after := map[string]string{"SELECT": "FROM", "DELETE": "FROM", "INSERT": "INTO"}[operation]
+	for i, field := range fields[:len(fields)-1] {
+		if (after != "" && strings.EqualFold(field, after)) || (operation == "UPDATE" && i == 0) {
+			return operation, fields[i+1]
+		}
+	}
+
+	return operation, ""
+}
@@ -0,0 +1,66 @@
+// Package tracing sets up OpenTelemetry tracing for the DJM services.
+//
+// Trace context is propagated in W3C traceparent headers, both on HTTP requests and on AMQP
+// messages. When tracing is enabled spans are exported over OTLP/HTTP to the endpoint named by
+// the standard OTEL_EXPORTER_OTLP_ENDPOINT variables.
+package tracing
+
+import (
+	"context"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of DJM's own spans.
+const instrumentationName = "github.com/julianstephens/distributed-job-manager"
+
+// Tracer returns the tracer DJM's own spans are started with.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup installs the W3C trace context propagator and, when tracing is enabled, a tracer
+// provider exporting the spans of service over OTLP. The returned function flushes and stops the
+// exporter.
+func Setup(ctx context.Context, conf models.TracingConfig, service string) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !conf.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(service)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// IDs returns the trace and span IDs of the span in ctx, or empty strings if there is none.
+func IDs(ctx context.Context) (traceId string, spanId string) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", ""
+	}
+	return sc.TraceID().String(), sc.SpanID().String()
+}
@@ -2,8 +2,10 @@ package utils
 
 import (
 	"encoding/json"
+	"math/rand/v2"
 	"os"
 	"reflect"
+	"time"
 
 	"github.com/julianstephens/distributed-job-manager/pkg/logger"
 )
@@ -111,3 +113,14 @@ func StructToMap(obj any) map[string]any {
 	}
 	return result
 }
+
+// Backoff returns the delay before retry number attempt (starting at 1): base doubled for every
+// previous attempt, capped at limit, with up to half of it randomized to spread out retries.
+func Backoff(attempt int, base time.Duration, limit time.Duration) time.Duration {
+	delay := base << max(attempt-1, 0)
+	if delay <= 0 || delay > limit {
+		delay = limit
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseSelector parses an equality-based label selector such as "team=data,env=prod".
+func ParseSelector(selector string) (map[string]string, error) {
+	requirements := make(map[string]string)
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(term, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid selector term %q, expected key=value", term)
+		}
+		requirements[key] = strings.TrimSpace(value)
+	}
+
+	if len(requirements) == 0 {
+		return nil, fmt.Errorf("selector %q has no terms", selector)
+	}
+
+	return requirements, nil
+}
+
+// MatchesSelector reports whether labels satisfy every term of selector. Invalid selectors
+// match nothing.
+func MatchesSelector(selector string, labels map[string]string) bool {
+	requirements, err := ParseSelector(selector)
+	if err != nil {
+		return false
+	}
+
+	for k, v := range requirements {
+		if val, ok := labels[k]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
@@ -44,6 +44,22 @@ func (p *Parser) Parse(unsafeInput string) error {
 	return nil
 }
 
+// ParseSanitized extracts the code blocks of a payload that Parse has already sanitized.
+func (p *Parser) ParseSanitized(sanitized string) error {
+	doc, err := html.Parse(strings.NewReader(sanitized))
+	if err != nil {
+		return err
+	}
+
+	data := DocumentData{}
+	p.ExtractCodeBlocks(doc, &data)
+
+	p.SanitizedInput = sanitized
+	p.Result = data.CodeBlocks
+
+	return nil
+}
+
 func (p *Parser) ExtractCodeBlocks(n *html.Node, data *DocumentData) {
 	if n.Type == html.ElementNode && n.Data == "pre" {
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
@@ -55,7 +71,10 @@ func (p *Parser) ExtractCodeBlocks(n *html.Node, data *DocumentData) {
 						break
 					}
 				}
-				content := c.FirstChild.Data
+				content := ""
+				if c.FirstChild != nil {
+					content = c.FirstChild.Data
+				}
 				data.CodeBlocks = append(data.CodeBlocks, CodeBlock{
 					Language: language,
 					Content:  strings.ReplaceAll(content, "'", "\""),
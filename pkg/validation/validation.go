@@ -0,0 +1,118 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/utils"
+)
+
+// ClockSkew is how far in the past a timestamp may be and still pass the 'notpast' rule.
+const ClockSkew = time.Minute
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Setup registers the custom validators on Gin's default binding engine.
+func Setup() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected binding validator engine")
+	}
+	return Register(v)
+}
+
+// Register adds the custom validators and JSON field naming to the given validator.
+func Register(v *validator.Validate) error {
+	v.RegisterTagNameFunc(jsonFieldName)
+
+	validators := map[string]validator.Func{
+		"frequency":     isFrequency,
+		"cron":          isCron,
+		"timezone":      isTimeZone,
+		"jobstatus":     isJobStatus,
+		"notpast":       isNotPast,
+		"eventtype":     isEventType,
+		"labelselector": isLabelSelector,
+		"slug":          isSlug,
+		"scope":         isScope,
+		"role":          isRole,
+	}
+	for tag, fn := range validators {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidCron reports whether expr is a standard five-field cron expression or descriptor.
+func ValidCron(expr string) bool {
+	_, err := models.ParseCron(expr)
+	return err == nil
+}
+
+func jsonFieldName(fld reflect.StructField) string {
+	name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+	if name == "-" || name == "" {
+		return fld.Name
+	}
+	return name
+}
+
+func isFrequency(fl validator.FieldLevel) bool {
+	return slices.Contains(models.JobFrequencies, fl.Field().String())
+}
+
+func isCron(fl validator.FieldLevel) bool {
+	return ValidCron(fl.Field().String())
+}
+
+func isTimeZone(fl validator.FieldLevel) bool {
+	tz := fl.Field().String()
+	if tz == "" || tz == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(tz)
+	return err == nil
+}
+
+func isJobStatus(fl validator.FieldLevel) bool {
+	return slices.Contains(models.JobStatuses, fl.Field().String())
+}
+
+func isNotPast(fl validator.FieldLevel) bool {
+	t, ok := fl.Field().Interface().(time.Time)
+	if !ok {
+		return false
+	}
+	return !t.Before(time.Now().Add(-ClockSkew))
+}
+
+func isEventType(fl validator.FieldLevel) bool {
+	return slices.Contains(models.EventTypes, fl.Field().String())
+}
+
+func isLabelSelector(fl validator.FieldLevel) bool {
+	_, err := utils.ParseSelector(fl.Field().String())
+	return err == nil
+}
+
+func isSlug(fl validator.FieldLevel) bool {
+	return slugPattern.MatchString(fl.Field().String())
+}
+
+func isScope(fl validator.FieldLevel) bool {
+	return slices.Contains(models.Scopes, fl.Field().String())
+}
+
+func isRole(fl validator.FieldLevel) bool {
+	return slices.Contains(models.Roles, fl.Field().String())
+}
@@ -0,0 +1,116 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+func newValidator(t *testing.T) *validator.Validate {
+	t.Helper()
+	v := validator.New()
+	v.SetTagName("binding")
+	if err := Register(v); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	return v
+}
+
+func TestValidators(t *testing.T) {
+	v := newValidator(t)
+
+	tests := []struct {
+		name  string
+		tag   string
+		value any
+		valid bool
+	}{
+		{"one-time frequency", "frequency", models.JobFrequencyOnce, true},
+		{"hourly frequency", "frequency", models.JobFrequencyHourly, true},
+		{"monthly frequency", "frequency", models.JobFrequencyMonthly, true},
+		{"unknown frequency", "frequency", "fortnightly", false},
+		{"frequency in wrong case", "frequency", "Daily", false},
+		{"empty frequency", "frequency", "", false},
+
+		{"five field cron", "cron", "*/5 * * * *", true},
+		{"cron with ranges and lists", "cron", "0 9-17 * * 1,3,5", true},
+		{"cron descriptor", "cron", "@daily", true},
+		{"cron interval descriptor", "cron", "@every 1h", true},
+		{"cron with seconds field", "cron", "0 */5 * * * *", false},
+		{"cron with too few fields", "cron", "* * *", false},
+		{"cron minute out of range", "cron", "60 * * * *", false},
+		{"unknown cron descriptor", "cron", "@fortnightly", false},
+		{"empty cron", "cron", "", false},
+
+		{"frequency accepted by frequency or cron", "frequency|cron", models.JobFrequencyWeekly, true},
+		{"cron accepted by frequency or cron", "frequency|cron", "30 2 * * *", true},
+		{"neither frequency nor cron", "frequency|cron", "sometimes", false},
+
+		{"UTC time zone", "timezone", "UTC", true},
+		{"IANA time zone", "timezone", "America/New_York", true},
+		{"unknown time zone", "timezone", "Mars/Olympus_Mons", false},
+		{"local time zone", "timezone", "Local", false},
+		{"empty time zone", "timezone", "", false},
+
+		{"pending status", "jobstatus", models.JobStatusPending, true},
+		{"failed status", "jobstatus", models.JobStatusFailed, true},
+		{"unknown status", "jobstatus", "paused", false},
+		{"status in wrong case", "jobstatus", "Completed", false},
+
+		{"future time", "notpast", time.Now().Add(time.Hour), true},
+		{"time within clock skew", "notpast", time.Now().Add(-ClockSkew / 2), true},
+		{"time beyond clock skew", "notpast", time.Now().Add(-2 * ClockSkew), false},
+		{"time that is not a timestamp", "notpast", "2030-01-01T00:00:00Z", false},
+
+		{"slug", "slug", "data-team_2", true},
+		{"slug with capitals", "slug", "Data", false},
+		{"slug starting with a dash", "slug", "-data", false},
+		{"slug with spaces", "slug", "data team", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Var(tt.value, tt.tag)
+			if tt.valid && err != nil {
+				t.Errorf("%q with %s: got error %v, want valid", tt.value, tt.tag, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("%q with %s: got valid, want error", tt.value, tt.tag)
+			}
+		})
+	}
+}
+
+func TestFieldErrorsUseJSONNames(t *testing.T) {
+	v := newValidator(t)
+
+	req := models.JobUpdateRequest{
+		Frequency: new(string),
+		TimeZone:  new(string),
+	}
+	*req.Frequency = "sometimes"
+	*req.TimeZone = "Mars/Olympus_Mons"
+
+	var errs validator.ValidationErrors
+	if err := v.Struct(req); !errors.As(err, &errs) {
+		t.Fatalf("Struct() error = %v, want validation errors", err)
+	}
+
+	fields := map[string]string{}
+	for _, fe := range errs {
+		fields[fe.Field()] = fe.Tag()
+	}
+
+	want := map[string]string{"frequency": "frequency|cron", "time_zone": "timezone"}
+	for field, tag := range want {
+		if fields[field] != tag {
+			t.Errorf("field %s failed %q, want %q (all errors: %v)", field, fields[field], tag, fields)
+		}
+	}
+	if len(fields) != len(want) {
+		t.Errorf("got errors on %v, want only %v", fields, want)
+	}
+}
@@ -0,0 +1,96 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{"public address", "https://93.184.216.34/hook", false},
+		{"public IPv6 address", "https://[2606:2800:220:1:248:1893:25c8:1946]/hook", false},
+		{"loopback", "http://127.0.0.1:15672/api/policies", true},
+		{"localhost", "http://localhost/hook", true},
+		{"private network", "http://10.0.3.7:9042/", true},
+		{"private class B network", "http://172.20.0.5/", true},
+		{"private class C network", "http://192.168.1.1/", true},
+		{"cloud metadata service", "http://169.254.169.254/latest/meta-data/", true},
+		{"unspecified address", "http://0.0.0.0/", true},
+		{"IPv6 loopback", "http://[::1]/", true},
+		{"IPv6 unique local address", "http://[fd00::1]/", true},
+		{"IPv4-mapped loopback", "http://[::ffff:127.0.0.1]/", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkURL(context.Background(), tt.url)
+			if tt.wantErr && !errors.Is(err, ErrForbiddenAddress) {
+				t.Errorf("checkURL(%s) error = %v, want %v", tt.url, err, ErrForbiddenAddress)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("checkURL(%s) error = %v, want nil", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for _, addr := range []string{"8.8.8.8", "2001:4860:4860::8888"} {
+		if !publicIP(net.ParseIP(addr)) {
+			t.Errorf("publicIP(%s) = false, want true", addr)
+		}
+	}
+	for _, addr := range []string{"127.0.0.53", "10.1.2.3", "169.254.0.1", "fe80::1", "224.0.0.1", "::"} {
+		if publicIP(net.ParseIP(addr)) {
+			t.Errorf("publicIP(%s) = true, want false", addr)
+		}
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	_, err := newClient(time.Second, false).Get(srv.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Get(%s) error = %v, want %v", srv.URL, err, ErrForbiddenAddress)
+	}
+
+	res, err := newClient(time.Second, true).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get(%s) with private addresses allowed: error = %v", srv.URL, err)
+	}
+	res.Body.Close()
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hook" {
+			http.Redirect(w, r, "/internal", http.StatusFound)
+			return
+		}
+		t.Errorf("redirect to %s was followed", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	res, err := newClient(time.Second, true).Get(srv.URL + "/hook")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusFound {
+		t.Errorf("status = %d, want %d", res.StatusCode, http.StatusFound)
+	}
+}
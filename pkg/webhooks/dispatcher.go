@@ -0,0 +1,359 @@
+// Package webhooks delivers job events to user-registered webhooks.
+//
+// Every jobsvc replica consumes the same durable queue bound to the events exchange, so each
+// event is matched against the webhooks of its owner exactly once. An event is only acknowledged
+// once its deliveries are stored. Payloads are signed with the webhook's secret and retried with
+// exponential backoff, and every attempt is recorded in the delivery log. Deliveries left pending
+// by a replica that stopped are resumed from the log by the others, or by it once it is back.
+// Each replica runs a fixed number of deliveries at once; events wait to be consumed until a
+// delivery slot is free.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/events"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
+	"github.com/julianstephens/distributed-job-manager/pkg/utils"
+	"github.com/oklog/ulid/v2"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	SignatureHeader = "X-DJM-Signature-256"
+	EventHeader     = "X-DJM-Event"
+	DeliveryHeader  = "X-DJM-Delivery"
+
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 5 * time.Minute
+
+	// dispatchRetryDelay holds back an event whose deliveries could not be stored before it is
+	// consumed again.
+	dispatchRetryDelay = 5 * time.Second
+)
+
+type Dispatcher struct {
+	conf    *models.Config
+	conn    *amqp091.Connection
+	repo    *repository.WebhookRepository
+	jobRepo *repository.JobRepository
+	logger  *graylogger.GrayLogger
+	client  *http.Client
+	// slots holds a token for every delivery in progress
+	slots chan struct{}
+}
+
+// ErrBusy is returned by Redeliver when every delivery slot is in use.
+var ErrBusy = errors.New("too many webhook deliveries in progress")
+
+func NewDispatcher(conf *models.Config, conn *amqp091.Connection, repo *repository.WebhookRepository, jobRepo *repository.JobRepository, logger *graylogger.GrayLogger) *Dispatcher {
+	return &Dispatcher{
+		conf:    conf,
+		conn:    conn,
+		repo:    repo,
+		jobRepo: jobRepo,
+		logger:  logger,
+		client:  newClient(conf.Webhook.Timeout, conf.Webhook.AllowPrivateAddresses),
+		slots:   make(chan struct{}, max(conf.Webhook.MaxConcurrent, 1)),
+	}
+}
+
+// CheckURL fails with ErrForbiddenAddress if a webhook URL resolves to an address that is not
+// public, unless private addresses are allowed. Deliveries check the address they connect to
+// again, since its host may resolve differently by then.
+func (d *Dispatcher) CheckURL(ctx context.Context, rawURL string) error {
+	if d.conf.Webhook.AllowPrivateAddresses {
+		return nil
+	}
+	return checkURL(ctx, rawURL)
+}
+
+// Sign returns the signature header value of body for secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run consumes job events and starts a delivery for every matching webhook until ctx is
+// cancelled or the channel closes. Meanwhile it resumes abandoned deliveries every resume
+// interval.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ch, err := d.conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueDeclare(d.conf.Webhook.QueueName, true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("unable to declare webhook queue: %w", err)
+	}
+
+	if err = ch.QueueBind(q.Name, "", d.conf.Rabbit.EventsExchange, false, nil); err != nil {
+		return fmt.Errorf("unable to bind webhook queue: %w", err)
+	}
+
+	msgs, err := ch.ConsumeWithContext(ctx, q.Name, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("unable to consume webhook queue: %w", err)
+	}
+
+	go d.resumeDeliveries(ctx)
+
+	for msg := range msgs {
+		// the events exchange also carries execution output, which is never sent to webhooks
+		if msg.Type == events.MessageTypeJobEvent {
+			var event models.JobEvent
+			if err := json.Unmarshal(msg.Body, &event); err != nil {
+				d.logger.Error("failed to unmarshal job event for webhooks", &err)
+			} else {
+				msgCtx, span := tracing.StartProcess(ctx, q.Name, msg)
+				err = d.dispatch(msgCtx, event)
+				span.End()
+
+				if err != nil {
+					d.logger.Error(fmt.Sprintf("failed to dispatch event %s to webhooks", event.EventID), &err)
+					time.Sleep(dispatchRetryDelay)
+					if err := msg.Nack(false, true); err != nil {
+						d.logger.Error("failed to requeue job event for webhooks", &err)
+					}
+					continue
+				}
+			}
+		}
+
+		if err := msg.Ack(false); err != nil {
+			d.logger.Error("failed to ack job event for webhooks", &err)
+		}
+	}
+
+	return nil
+}
+
+// dispatch starts a delivery of event to every matching webhook of its owner. It fails if the
+// webhooks could not be read or a delivery could not be stored, so the event is consumed again.
+func (d *Dispatcher) dispatch(ctx context.Context, event models.JobEvent) error {
+	hooks, err := d.repo.WithContext(ctx).GetWebhooks(event.UserID, false)
+	if err != nil {
+		return err
+	}
+
+	var labels map[string]string
+	for _, hook := range *hooks {
+		if !slices.Contains(hook.EventTypes, event.Type) {
+			continue
+		}
+		if hook.JobID != "" && hook.JobID != event.JobID {
+			continue
+		}
+		if hook.LabelSelector != "" {
+			if labels == nil {
+				job, err := d.jobRepo.WithContext(ctx).GetJob(event.JobID, models.SystemPrincipal)
+				if err != nil {
+					continue
+				}
+				labels = utils.If(job.Labels != nil, job.Labels, map[string]string{})
+			}
+			if !utils.MatchesSelector(hook.LabelSelector, labels) {
+				continue
+			}
+		}
+
+		payload, err := json.Marshal(event)
+		if err != nil {
+			d.logger.Error(fmt.Sprintf("failed to marshal event %s for webhook %s", event.EventID, hook.WebhookID), &err)
+			continue
+		}
+
+		delivery, err := d.NewDelivery(ctx, hook, event.EventID, event.Type, string(payload))
+		if err != nil {
+			return err
+		}
+		// a delivery that never gets a slot stays pending and is resumed later
+		d.start(ctx, hook, delivery)
+	}
+
+	return nil
+}
+
+// resumeDeliveries resumes abandoned deliveries every resume interval until ctx is cancelled.
+func (d *Dispatcher) resumeDeliveries(ctx context.Context) {
+	ticker := time.NewTicker(d.conf.Webhook.ResumeInterval)
+	defer ticker.Stop()
+
+	for {
+		d.resume(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resume continues the pending deliveries that have not been saved for longer than the longest
+// wait between two attempts, so that no replica can still be working on them.
+func (d *Dispatcher) resume(ctx context.Context) {
+	repo := d.repo.WithContext(ctx)
+
+	pending, err := repo.GetPendingDeliveries()
+	if err != nil {
+		return
+	}
+
+	abandonedBefore := time.Now().UTC().Add(-(retryMaxDelay + 2*d.conf.Webhook.Timeout))
+	for _, delivery := range *pending {
+		if delivery.UpdatedAt.After(abandonedBefore) {
+			continue
+		}
+
+		hook, err := repo.GetWebhook(delivery.WebhookID, "", true)
+		if err != nil {
+			// the webhook was deleted, so the delivery can never be made
+			if errors.Is(err, repository.ErrWebhookNotFound) {
+				delivery.Status = models.DeliveryStatusFailed
+				delivery.ErrorMessage = "webhook was deleted"
+				_ = repo.SaveDelivery(delivery)
+			}
+			continue
+		}
+
+		claimed, err := repo.ClaimDelivery(delivery)
+		if err != nil || claimed == nil {
+			continue
+		}
+
+		d.logger.Info(fmt.Sprintf("resuming delivery %s of webhook %s after %d attempts", delivery.DeliveryID, delivery.WebhookID, delivery.Attempts), nil)
+		if !d.start(ctx, *hook, *claimed) {
+			return
+		}
+	}
+}
+
+// start delivers in the background once a delivery slot is free, waiting for one until ctx is
+// done. It reports whether the delivery was started.
+func (d *Dispatcher) start(ctx context.Context, hook models.Webhook, delivery models.WebhookDelivery) bool {
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+
+	go d.deliverInSlot(ctx, hook, delivery)
+
+	return true
+}
+
+// deliverInSlot delivers and then frees the slot that was taken for the delivery.
+func (d *Dispatcher) deliverInSlot(ctx context.Context, hook models.Webhook, delivery models.WebhookDelivery) {
+	defer func() { <-d.slots }()
+	d.Deliver(ctx, hook, delivery)
+}
+
+// Redeliver sends the payload of a previous delivery again as a new delivery in the background,
+// failing with ErrBusy rather than waiting if every delivery slot is in use. The delivery runs
+// until ctx is done.
+func (d *Dispatcher) Redeliver(ctx context.Context, hook models.Webhook, previous models.WebhookDelivery) (models.WebhookDelivery, error) {
+	select {
+	case d.slots <- struct{}{}:
+	default:
+		return models.WebhookDelivery{}, ErrBusy
+	}
+
+	delivery, err := d.NewDelivery(ctx, hook, previous.EventID, previous.EventType, previous.Payload)
+	if err != nil {
+		<-d.slots
+		return delivery, err
+	}
+
+	go d.deliverInSlot(ctx, hook, delivery)
+
+	return delivery, nil
+}
+
+// NewDelivery creates a pending delivery log entry for a payload.
+func (d *Dispatcher) NewDelivery(ctx context.Context, hook models.Webhook, eventId string, eventType string, payload string) (models.WebhookDelivery, error) {
+	delivery := models.WebhookDelivery{
+		WebhookID:  hook.WebhookID,
+		DeliveryID: ulid.Make().String(),
+		EventID:    eventId,
+		EventType:  eventType,
+		Payload:    payload,
+		Status:     models.DeliveryStatusPending,
+		CreatedAt:  time.Now().UTC(),
+	}
+	err := d.repo.WithContext(ctx).SaveDelivery(delivery)
+	return delivery, err
+}
+
+// Deliver posts the delivery's payload to the webhook until it succeeds or the configured number
+// of attempts is exhausted, recording each attempt.
+func (d *Dispatcher) Deliver(ctx context.Context, hook models.Webhook, delivery models.WebhookDelivery) models.WebhookDelivery {
+	for {
+		delivery.Attempts++
+		delivery.StatusCode, delivery.ErrorMessage = d.post(ctx, hook, delivery)
+
+		switch {
+		case delivery.ErrorMessage == "":
+			delivery.Status = models.DeliveryStatusSucceeded
+		case delivery.Attempts >= d.conf.Webhook.MaxAttempts:
+			delivery.Status = models.DeliveryStatusFailed
+		}
+		_ = d.repo.WithContext(ctx).SaveDelivery(delivery)
+
+		if delivery.Status != models.DeliveryStatusPending {
+			return delivery
+		}
+
+		select {
+		case <-ctx.Done():
+			return delivery
+		case <-time.After(utils.Backoff(delivery.Attempts, retryBaseDelay, retryMaxDelay)):
+		}
+	}
+}
+
+// post sends a single delivery attempt, returning the response status and an error message if
+// the attempt failed.
+func (d *Dispatcher) post(ctx context.Context, hook models.Webhook, delivery models.WebhookDelivery) (int, string) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "djm-webhooks/0.1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.DeliveryID)
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Sprintf("webhook responded with %s", res.Status)
+	}
+
+	return res.StatusCode, ""
+}
@@ -0,0 +1,28 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+func TestDeliveriesWaitForASlot(t *testing.T) {
+	d := &Dispatcher{slots: make(chan struct{}, 1)}
+	d.slots <- struct{}{}
+
+	if _, err := d.Redeliver(context.Background(), models.Webhook{}, models.WebhookDelivery{}); !errors.Is(err, ErrBusy) {
+		t.Errorf("Redeliver() with every slot in use: error = %v, want %v", err, ErrBusy)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if d.start(ctx, models.Webhook{}, models.WebhookDelivery{}) {
+		t.Error("start() with every slot in use started a delivery after ctx was done")
+	}
+
+	if len(d.slots) != 1 {
+		t.Errorf("%d slots in use, want 1", len(d.slots))
+	}
+}
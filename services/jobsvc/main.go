@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/compile"
 	"github.com/julianstephens/distributed-job-manager/pkg/config"
+	"github.com/julianstephens/distributed-job-manager/pkg/deadletters"
+	"github.com/julianstephens/distributed-job-manager/pkg/events"
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/health"
 	"github.com/julianstephens/distributed-job-manager/pkg/httputil"
 	"github.com/julianstephens/distributed-job-manager/pkg/logger"
+	"github.com/julianstephens/distributed-job-manager/pkg/middleware"
 	"github.com/julianstephens/distributed-job-manager/pkg/queue"
+	"github.com/julianstephens/distributed-job-manager/pkg/reaper"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
 	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
+	"github.com/julianstephens/distributed-job-manager/pkg/validation"
+	"github.com/julianstephens/distributed-job-manager/pkg/webhooks"
 	"github.com/julianstephens/distributed-job-manager/services/jobsvc/router"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -30,6 +41,13 @@ import (
 func main() {
 	conf := config.GetConfig()
 
+	shutdownTracing, err := tracing.Setup(context.Background(), conf.Tracing, "jobsvc")
+	if err != nil {
+		logger.Fatalf("unable to set up tracing: %v", err)
+		return
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := store.GetDB(conf.Cassandra.Keyspace)
 	if err != nil {
 		logger.Fatalf("unable to get cassandra store connection: %v", err)
@@ -52,7 +70,91 @@ func main() {
 
 	gin.DefaultWriter = qWriter
 
-	r := router.Setup(conf, db, log)
+	if err := validation.Setup(); err != nil {
+		logger.Fatalf("unable to register request validators: %v", err)
+		return
+	}
+
+	tokens, err := middleware.NewJWTManager(context.Background(), conf)
+	if err != nil {
+		logger.Fatalf("unable to set up token verification: %v", err)
+		return
+	}
+
+	conn, err := queue.GetConnection(conf.Rabbit.Username, conf.Rabbit.Password, "", conf)
+	if err != nil {
+		logger.Fatalf("unable to get queue connection: %v", err)
+		return
+	}
+	defer queue.CloseConnection(conf.Rabbit.Username)
+
+	bus, err := events.NewBus(conf, conn, repository.NewEventRepository(db, log), log)
+	if err != nil {
+		logger.Fatalf("unable to create event bus: %v", err)
+		return
+	}
+	defer bus.Close()
+
+	go func() {
+		if err := bus.Listen(context.Background()); err != nil {
+			logger.Fatalf("unable to listen for job events: %v", err)
+		}
+	}()
+
+	dispatcher := webhooks.NewDispatcher(conf, conn, repository.NewWebhookRepository(db, log), repository.NewJobRepository(db, log), log)
+	go func() {
+		if err := dispatcher.Run(context.Background()); err != nil {
+			logger.Fatalf("unable to dispatch webhooks: %v", err)
+		}
+	}()
+
+	// executions have to be where jobsvc looks for them before workers report on them
+	if copied, err := repository.NewExecutionRepository(db, log).CopyLegacyExecutions(); err != nil {
+		log.Error("failed to copy legacy job executions", &err)
+	} else if copied > 0 {
+		log.Info(fmt.Sprintf("copied %d legacy job executions", copied), nil)
+	}
+
+	executionReaper := reaper.NewReaper(conf, repository.NewWorkerRepository(db, log), repository.NewExecutionRepository(db, log), repository.NewJobRepository(db, log), bus, log)
+	go executionReaper.Run(context.Background())
+
+	limits, err := middleware.NewRateLimitStore(conf.RateLimit, db)
+	if err != nil {
+		logger.Fatalf("unable to set up rate limiting: %v", err)
+		return
+	}
+
+	compiler, err := compile.NewClient(conf, conn)
+	if err != nil {
+		logger.Fatalf("unable to create compile client: %v", err)
+		return
+	}
+	defer compiler.Close()
+
+	deadLetters, err := deadletters.NewManager(conf, conn, repository.NewDeadLetterRepository(db, log), log)
+	if err != nil {
+		logger.Fatalf("unable to set up dead-letter queue: %v", err)
+		return
+	}
+	defer deadLetters.Close()
+
+	// without the policy jobs the broker drops from the work queue are lost instead of
+	// dead-lettered, which does not keep jobs from being run
+	if err = queue.ApplyDeadLetterPolicy(conf); err != nil {
+		log.Error("failed to apply the dead-letter policy of the work queue", &err)
+	}
+
+	go func() {
+		if err := deadLetters.Run(context.Background()); err != nil {
+			logger.Fatalf("unable to collect dead letters: %v", err)
+		}
+	}()
+
+	checker := health.New()
+	checker.Add("cassandra", health.Cassandra(db))
+	checker.Add("rabbitmq", health.Rabbit(conn, bus.Channel(), compiler.Channel(), deadLetters.Channel()))
+
+	r := router.Setup(conf, db, log, bus, dispatcher, tokens, limits, checker, compiler, deadLetters)
 	r.GET("/api/v1/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	r.NoRoute(func(c *gin.Context) {
@@ -1,55 +1,118 @@
 package router
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
+	"github.com/julianstephens/distributed-job-manager/pkg/compile"
 	"github.com/julianstephens/distributed-job-manager/pkg/controller"
+	"github.com/julianstephens/distributed-job-manager/pkg/deadletters"
+	"github.com/julianstephens/distributed-job-manager/pkg/events"
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/health"
+	"github.com/julianstephens/distributed-job-manager/pkg/metrics"
 	"github.com/julianstephens/distributed-job-manager/pkg/middleware"
 	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/ratelimit"
+	"github.com/julianstephens/distributed-job-manager/pkg/repository"
 	"github.com/julianstephens/distributed-job-manager/pkg/store"
+	"github.com/julianstephens/distributed-job-manager/pkg/webhooks"
 	docs "github.com/julianstephens/distributed-job-manager/services/jobsvc/docs"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 const BasePath = "/api/v1"
 
-func Setup(conf *models.Config, db *store.DBSession, log *graylogger.GrayLogger) *gin.Engine {
+func Setup(conf *models.Config, db *store.DBSession, log *graylogger.GrayLogger, bus *events.Bus, dispatcher *webhooks.Dispatcher, tokens *middleware.JWTManager, limits ratelimit.Store, checker *health.Checker, compiler *compile.Client, deadLetters *deadletters.Manager) *gin.Engine {
 	r := gin.New()
 
+	// probes and scrapes would otherwise flood the trace backend
+	r.Use(otelgin.Middleware("jobsvc", otelgin.WithFilter(func(req *http.Request) bool {
+		switch req.URL.Path {
+		case "/healthz", "/readyz", "/metrics":
+			return false
+		}
+		return true
+	})))
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
-	r.Use(gzip.Gzip(gzip.DefaultCompression))
+	r.Use(middleware.RequestID())
+	r.Use(metrics.HTTP())
+	// event and log streams are flushed as they are written, which compression would buffer
+	r.Use(gzip.Gzip(
+		gzip.DefaultCompression,
+		gzip.WithExcludedPaths([]string{BasePath + "/events"}),
+		gzip.WithExcludedPathsRegexs([]string{"^" + BasePath + "/executions/[^/]+/logs"}),
+	))
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Last-Event-ID", middleware.RequestIDHeader, "traceparent", "tracestate"},
+		ExposeHeaders:    []string{"Content-Length", middleware.RequestIDHeader, "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
 	docs.SwaggerInfo.BasePath = BasePath
 
-	baseGroup := r.Group(BasePath, middleware.Guard())
+	checker.Register(r)
+	metrics.Register(r)
 
-	jobAPI := controller.NewJobController(db, conf, log)
+	baseGroup := r.Group(BasePath,
+		middleware.Guard(tokens, repository.NewAPIKeyRepository(db, log)),
+		middleware.RateLimit(conf.RateLimit, limits, log),
+	)
+
+	jobAPI := controller.NewJobController(db, conf, log, bus, compiler)
+	grantAPI := controller.NewGrantController(db, conf, log)
 	jobGroup := baseGroup.Group("/jobs", middleware.RequireScopes("read:jobs", "write:jobs"))
 	{
 		jobGroup.GET("/", jobAPI.GetJobs)
 		jobGroup.GET("/:id", jobAPI.GetJob)
 		jobGroup.POST("", jobAPI.CreateJob)
+		jobGroup.POST("/validate", jobAPI.ValidatePayload)
 		jobGroup.PATCH("/:id", jobAPI.UpdateJob)
 		jobGroup.DELETE("/:id", jobAPI.DeleteJob)
+		jobGroup.POST("/:id/run", jobAPI.RunJob)
+		jobGroup.GET("/:id/grants", grantAPI.GetJobGrants)
+		jobGroup.PUT("/:id/grants", grantAPI.SaveJobGrant)
+		jobGroup.DELETE("/:id/grants/:subjectType/:subjectId", grantAPI.DeleteJobGrant)
+	}
+
+	projectGroup := baseGroup.Group("/projects", middleware.RequireScopes("read:jobs", "write:jobs"))
+	{
+		projectGroup.GET("/:id/grants", grantAPI.GetProjectGrants)
+		projectGroup.PUT("/:id/grants", grantAPI.SaveProjectGrant)
+		projectGroup.DELETE("/:id/grants/:subjectType/:subjectId", grantAPI.DeleteProjectGrant)
 	}
 
-	executionAPI := controller.NewExecutionController(db, conf, log)
+	manifestAPI := controller.NewManifestController(db, conf, log, bus)
+	manifestGroup := baseGroup.Group("/manifests", middleware.RequireScopes("read:jobs", "write:jobs"))
+	{
+		manifestGroup.GET("", manifestAPI.ExportManifests)
+		manifestGroup.POST("/apply", manifestAPI.ApplyManifests)
+	}
+
+	executionAPI := controller.NewExecutionController(db, conf, log, bus)
 	executionGroup := baseGroup.Group("/executions", middleware.RequireScopes("read:executions", "write:executions"))
 	{
 		executionGroup.POST("/", executionAPI.CreateExecution)
 		executionGroup.PATCH("/:id", executionAPI.UpdateExecution)
+		executionGroup.POST("/:id/output", executionAPI.AppendOutput)
+	}
+	baseGroup.GET("/executions/:id/logs", middleware.RequireScopes("read:jobs"), executionAPI.GetExecutionLogs)
+	baseGroup.GET("/jobs/:id/executions", middleware.RequireScopes("read:jobs"), executionAPI.GetJobExecutions)
+
+	workerAPI := controller.NewWorkerController(db, conf, log)
+	workerGroup := baseGroup.Group("/workers", middleware.RequireScopes("read:workers", "write:workers"))
+	{
+		workerGroup.GET("/", workerAPI.GetWorkers)
+		workerGroup.GET("/:id", workerAPI.GetWorker)
+		workerGroup.PUT("/:id", workerAPI.RegisterWorker)
+		workerGroup.POST("/:id/heartbeat", workerAPI.WorkerHeartbeat)
 	}
 
 	scheduleAPI := controller.NewScheduleController(db, conf, log)
@@ -62,5 +125,43 @@ func Setup(conf *models.Config, db *store.DBSession, log *graylogger.GrayLogger)
 		scheduleGroup.DELETE("/:id", scheduleAPI.DeleteSchedule)
 	}
 
+	eventAPI := controller.NewEventController(db, conf, log, bus)
+	baseGroup.GET("/events", middleware.RequireScopes("read:jobs"), eventAPI.StreamEvents)
+
+	webhookAPI := controller.NewWebhookController(db, conf, log, dispatcher)
+	webhookGroup := baseGroup.Group("/webhooks", middleware.RequireScopes("read:jobs", "write:jobs"))
+	{
+		webhookGroup.GET("/", webhookAPI.GetWebhooks)
+		webhookGroup.GET("/:id", webhookAPI.GetWebhook)
+		webhookGroup.POST("", webhookAPI.CreateWebhook)
+		webhookGroup.DELETE("/:id", webhookAPI.DeleteWebhook)
+		webhookGroup.GET("/:id/deliveries", webhookAPI.GetDeliveries)
+		webhookGroup.POST("/:id/deliveries/:deliveryId/redeliver", webhookAPI.Redeliver)
+	}
+
+	apiKeyAPI := controller.NewAPIKeyController(db, conf, log)
+	apiKeyGroup := baseGroup.Group("/api-keys", middleware.RequireScopes())
+	{
+		apiKeyGroup.GET("/", apiKeyAPI.GetAPIKeys)
+		apiKeyGroup.POST("", apiKeyAPI.CreateAPIKey)
+		apiKeyGroup.DELETE("/:id", apiKeyAPI.RevokeAPIKey)
+	}
+
+	usageAPI := controller.NewUsageController(db, conf, log)
+	baseGroup.GET("/usage", middleware.RequireScopes(), usageAPI.GetUsage)
+
+	auditAPI := controller.NewAuditController(db, conf, log)
+	baseGroup.GET("/audit", middleware.RequireScopes("admin"), auditAPI.GetAuditEntries)
+
+	deadLetterAPI := controller.NewDeadLetterController(db, conf, log, deadLetters)
+	deadLetterGroup := baseGroup.Group("/dead-letters", middleware.RequireScopes("admin"))
+	{
+		deadLetterGroup.GET("", deadLetterAPI.GetDeadLetters)
+		deadLetterGroup.DELETE("", deadLetterAPI.PurgeDeadLetters)
+		deadLetterGroup.GET("/:id", deadLetterAPI.GetDeadLetter)
+		deadLetterGroup.DELETE("/:id", deadLetterAPI.DeleteDeadLetter)
+		deadLetterGroup.POST("/:id/replay", deadLetterAPI.ReplayDeadLetter)
+	}
+
 	return r
 }
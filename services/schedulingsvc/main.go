@@ -2,18 +2,27 @@ package main
 
 import (
 	"context"
-	"time"
 
 	"github.com/julianstephens/distributed-job-manager/pkg/config"
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/health"
 	"github.com/julianstephens/distributed-job-manager/pkg/logger"
+	"github.com/julianstephens/distributed-job-manager/pkg/metrics"
 	"github.com/julianstephens/distributed-job-manager/pkg/queue"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
 	"github.com/julianstephens/distributed-job-manager/services/schedulingsvc/scheduler"
 )
 
 func main() {
 	conf := config.GetConfig()
 
+	shutdownTracing, err := tracing.Setup(context.Background(), conf.Tracing, "schedulingsvc")
+	if err != nil {
+		logger.Fatalf("unable to set up tracing: %v", err)
+		return
+	}
+	defer shutdownTracing(context.Background())
+
 	log, err := graylogger.NewLogger("schedsvc")
 	if err != nil {
 		logger.Fatalf("unable to init logger: %v", err)
@@ -30,9 +39,16 @@ func main() {
 	defer queue.CloseConnection(conf.Rabbit.Username)
 	defer sched.ScheduleCh.Close()
 
+	checker := health.New()
+	checker.Add("rabbitmq", health.Rabbit(sched.Conn, sched.ScheduleCh))
+	checker.Add("jobsvc", health.Reachable(conf.JobAPIEndpoint))
+	go func() {
+		logger.Fatalf("%v", checker.Serve(conf.Schedule.Host+":"+conf.Schedule.Port, metrics.Register))
+	}()
+
 	log.Info("scheduling service initialized", nil)
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	sched.Run(ctx)
@@ -3,25 +3,30 @@ package scheduler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/julianstephens/distributed-job-manager/pkg/auth0client"
+	"github.com/julianstephens/distributed-job-manager/pkg/client"
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/metrics"
 	"github.com/julianstephens/distributed-job-manager/pkg/models"
 	"github.com/julianstephens/distributed-job-manager/pkg/queue"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
 	"github.com/julianstephens/distributed-job-manager/pkg/utils"
 	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type Scheduler struct {
 	baseURL    string
 	conf       *models.Config
-	api        *JobAPI
+	api        *client.Client
 	logger     *graylogger.GrayLogger
+	Conn       *amqp091.Connection
 	ScheduleCh *amqp091.Channel
 }
 
@@ -34,14 +39,17 @@ func NewScheduler(config *models.Config, logger *graylogger.GrayLogger) (*Schedu
 	if err != nil {
 		return nil, err
 	}
+	if err = queue.DeclareWorkQueue(ch, config); err != nil {
+		return nil, err
+	}
 	return &Scheduler{
 		conf: config,
-		api: NewJobAPI(
-			auth0client.NewAuth0Client(config.Schedule.Auth0ClientID, config.Schedule.Auth0ClientSecret),
-			logger,
+		api: client.New(
 			config.JobAPIEndpoint,
+			client.WithAuth(client.NewServiceAuth(config, models.SchedulerSubject, config.Schedule.Auth0ClientID, config.Schedule.Auth0ClientSecret)),
 		),
 		logger:     logger,
+		Conn:       conn,
 		ScheduleCh: ch,
 	}, nil
 }
@@ -59,50 +67,108 @@ func (s *Scheduler) Run(ctx context.Context) {
 	tick.Stop()
 }
 
-func (s *Scheduler) pollTable(ctx context.Context, ch *amqp091.Channel) error {
-	startTime := time.Now().Add(time.Second * 60)
-	endTime := startTime.Add(time.Second * 60)
+func (s *Scheduler) pollTable(ctx context.Context, ch *amqp091.Channel) (err error) {
+	poll := metrics.NewPoll()
+	ctx, span := tracing.Tracer().Start(ctx, "scheduler.poll")
+	defer func() {
+		poll.Done(err)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	log := s.logger.WithContext(ctx)
+
+	// overdue schedules are included so that jobs run right away, retried soon or held back by a
+	// quota are not missed once their time is past the lookahead
+	now := time.Now()
+	endTime := now.Add(time.Second * 120)
 
-	s.logger.Info(fmt.Sprintf("looking for scheduled jobs between %s and %s", startTime.Format(time.RFC3339), endTime.Format(time.RFC3339)), nil)
-	queuedSchedules, err := s.api.GetSchedules(&startTime, &endTime)
+	log.Info(fmt.Sprintf("looking for scheduled jobs due before %s", endTime.Format(time.RFC3339)), nil)
+	queuedSchedules, err := s.api.ListSchedules(ctx, client.ScheduleQuery{NextRunBefore: &endTime})
 	if err != nil {
+		log.Error("failed to fetch scheduled jobs", &err)
 		return err
 	}
 
-	s.logger.Info(fmt.Sprintf("found %d scheduled jobs", len(*queuedSchedules)), nil)
+	log.Info(fmt.Sprintf("found %d scheduled jobs", len(queuedSchedules.Items)), nil)
+	poll.Add(metrics.ScheduleFound, len(queuedSchedules.Items))
 
-	for _, sched := range *queuedSchedules {
-		job, err := s.api.GetJob(sched.JobID)
+	for _, sched := range queuedSchedules.Items {
+		job, err := s.api.GetJob(ctx, sched.JobID)
+		if errors.Is(err, client.ErrNotFound) {
+			s.pruneSchedule(ctx, sched, "it was deleted")
+			poll.Add(metrics.ScheduleSkipped, 1)
+			continue
+		}
 		if err != nil {
-			s.logger.Error(fmt.Sprintf("failed to get job %s for scheduling", sched.JobID), &err)
-			return err
+			log.Error(fmt.Sprintf("failed to get job %s for scheduling", sched.JobID), &err)
+			poll.Add(metrics.ScheduleFailed, 1)
+			continue
 		}
 
 		if job.Status != models.JobStatusPending {
-			return nil
+			// a finished job never runs again, so its schedule would be listed as overdue forever
+			if models.IsTerminalStatus(job.Status) && sched.NextRunTime.Before(now) {
+				s.pruneSchedule(ctx, sched, "it is "+job.Status)
+			}
+			poll.Add(metrics.ScheduleSkipped, 1)
+			continue
+		}
+
+		usage, err := s.api.GetUsage(ctx, job.UserID)
+		if err != nil {
+			log.Error(fmt.Sprintf("failed to get quota usage of user %s", job.UserID), &err)
+			poll.Add(metrics.ScheduleFailed, 1)
+			continue
+		}
+
+		if usage.DailyExecutions.Exhausted() || usage.DailySandboxSeconds.Exhausted() {
+			log.Info(fmt.Sprintf("not sending job %s to queue: user %s has used their daily quota", job.JobID, job.UserID), nil)
+			poll.Add(metrics.ScheduleSkipped, 1)
+			continue
 		}
 
 		jobJson, err := json.Marshal(job)
 		if err != nil {
-			s.logger.Error("failed to marshal scheduled job to json", &err)
+			log.Error("failed to marshal scheduled job to json", &err)
+			poll.Add(metrics.ScheduleFailed, 1)
 			return err
 		}
 
-		if err := ch.PublishWithContext(ctx, s.conf.Rabbit.Name, "", false, false, amqp091.Publishing{ContentType: "application/json", Body: jobJson}); err != nil {
-			s.logger.Error(fmt.Sprintf("failed to publish job %s to queue", job.JobID), &err)
+		pubCtx, pubSpan, headers := tracing.StartPublish(ctx, s.conf.Rabbit.Name, nil)
+		err = ch.PublishWithContext(pubCtx, s.conf.Rabbit.Name, "", false, false, amqp091.Publishing{ContentType: "application/json", Headers: headers, Body: jobJson})
+		pubSpan.End()
+		if err != nil {
+			log.Error(fmt.Sprintf("failed to publish job %s to queue", job.JobID), &err)
+			poll.Add(metrics.ScheduleFailed, 1)
 			return err
 		}
-		s.logger.Info(fmt.Sprintf("sent job %s to queue", job.JobID), nil)
+		log.Info(fmt.Sprintf("sent job %s to queue", job.JobID), nil)
+		poll.Add(metrics.ScheduleDispatched, 1)
 
-		if _, err = s.api.UpdateJob(job.JobID, &models.JobUpdateRequest{Status: utils.StringPtr(models.JobStatusScheduled)}); err != nil {
+		if _, err = s.api.UpdateJob(ctx, job.JobID, models.JobUpdateRequest{Status: utils.StringPtr(models.JobStatusScheduled)}); err != nil {
+			log.Error(fmt.Sprintf("failed to update job %s status to scheduled", job.JobID), &err)
 			return err
 		}
-		s.logger.Info(fmt.Sprintf("updated job %s status to scheduled", job.JobID), nil)
+		log.Info(fmt.Sprintf("updated job %s status to scheduled", job.JobID), nil)
 	}
 
 	return nil
 }
 
+// pruneSchedule deletes the schedule of a job that will not run again, logging failures since the
+// next poll tries again.
+func (s *Scheduler) pruneSchedule(ctx context.Context, sched models.JobSchedule, reason string) {
+	log := s.logger.WithContext(ctx)
+	if err := s.api.DeleteSchedule(ctx, sched.JobID); err != nil && !errors.Is(err, client.ErrNotFound) {
+		log.Error(fmt.Sprintf("failed to delete schedule of job %s", sched.JobID), &err)
+		return
+	}
+	log.Info(fmt.Sprintf("deleted schedule of job %s: %s", sched.JobID, reason), nil)
+}
+
 func (s *Scheduler) runner(ctx context.Context, tick *time.Ticker, ch *amqp091.Channel) {
 	for range tick.C {
 		s.pollTable(ctx, ch)
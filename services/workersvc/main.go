@@ -1,14 +1,25 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
+	"github.com/julianstephens/distributed-job-manager/pkg/client"
 	"github.com/julianstephens/distributed-job-manager/pkg/config"
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/health"
 	"github.com/julianstephens/distributed-job-manager/pkg/logger"
+	"github.com/julianstephens/distributed-job-manager/pkg/metrics"
 	"github.com/julianstephens/distributed-job-manager/pkg/models"
 	"github.com/julianstephens/distributed-job-manager/pkg/queue"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
 	"github.com/julianstephens/distributed-job-manager/pkg/utils"
 	"github.com/julianstephens/distributed-job-manager/services/workersvc/worker"
 	"github.com/rabbitmq/amqp091-go"
@@ -16,6 +27,18 @@ import (
 
 func main() {
 	conf := config.GetConfig()
+	// workers are told apart by ID, so fall back to the container's hostname
+	if conf.WorkerID == "" {
+		conf.WorkerID, _ = os.Hostname()
+		conf.Worker.ID = conf.WorkerID
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), conf.Tracing, "workersvc")
+	if err != nil {
+		logger.Fatalf("unable to set up tracing: %v", err)
+		return
+	}
+	defer shutdownTracing(context.Background())
 
 	log, err := graylogger.NewLogger("worker")
 	if err != nil {
@@ -39,10 +62,34 @@ func main() {
 	}
 	defer ch.Close()
 
+	if err = queue.DeclareWorkQueue(ch, conf); err != nil {
+		logger.Fatalf("unable to declare work queue: %v", err)
+		return
+	}
+
+	// requeued and dead-lettered jobs are only acknowledged once their copies are confirmed
+	if err = ch.Confirm(false); err != nil {
+		logger.Fatalf("unable to put queue channel in confirm mode: %v", err)
+		return
+	}
+
+	pool := worker.NewSandboxPool(conf.SandboxCount)
+	pool.UserLimit = conf.Worker.MaxSandboxesPerUser
+	pool.ScheduleCleanup()
+	log.Info(fmt.Sprintf("sandbox pool created with %d sandboxes", conf.SandboxCount), nil)
+	metrics.RegisterSandboxPool(conf.SandboxCount, pool.Usage)
+
+	// hold no more unacknowledged jobs than there are sandboxes to run them
+	concurrency := max(pool.AvailableCount(), 1)
+	if err = ch.Qos(concurrency, 0, false); err != nil {
+		logger.Fatalf("unable to set queue prefetch: %v", err)
+		return
+	}
+
 	msgs, err := ch.Consume(
 		conf.Rabbit.Name,
-		"",
-		true,
+		conf.WorkerID,
+		false,
 		false,
 		false,
 		false,
@@ -53,60 +100,298 @@ func main() {
 		return
 	}
 
-	pool := worker.NewSandboxPool(conf.SandboxCount)
-	pool.ScheduleCleanup()
-	log.Info(fmt.Sprintf("sandbox pool created with %d sandboxes", conf.SandboxCount), nil)
+	checker := health.New()
+	checker.Add("rabbitmq", health.Rabbit(conn, ch))
+	checker.Add("jobsvc", health.Reachable(conf.JobAPIEndpoint))
+	checker.Add("sandboxes", func(context.Context) error { return pool.Ready() })
+	go func() {
+		logger.Fatalf("%v", checker.Serve(conf.Worker.Host+":"+conf.Worker.Port, metrics.Register))
+	}()
+
+	compiler := worker.NewCompiler(log, pool)
+	go func() {
+		if err := compiler.Run(context.Background(), conn); err != nil {
+			logger.Fatalf("unable to answer compile checks: %v", err)
+		}
+	}()
 
 	runner := worker.NewRunner(log)
 	reporter := worker.NewReporter(log)
+	go reporter.RunHeartbeats(context.Background(), pool)
 
-	forever := make(chan bool)
-	go processJobs(runner, pool, reporter, log, msgs)
-	<-forever
+	// stop taking jobs on shutdown, letting the ones already running finish
+	shutdown, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-shutdown.Done()
+		log.Info("worker shutting down, waiting for running jobs...", nil)
+		if err := ch.Cancel(conf.WorkerID, false); err != nil {
+			log.Error("failed to cancel queue consumer", &err)
+		}
+	}()
+
+	processJobs(shutdown, conf, ch, concurrency, runner, pool, reporter, log, msgs)
+	if shutdown.Err() == nil {
+		logger.Fatalf("job queue consumer closed")
+	}
+	log.Info("worker stopped", nil)
 }
 
-func processJobs(runner *worker.Runner, pool *worker.SandboxPool, reporter *worker.Reporter, log *graylogger.GrayLogger, msgs <-chan amqp091.Delivery) {
-	log.Info("worker started, waiting for jobs...", nil)
+// outcome is how a delivery is settled once processJob is done with it.
+type outcome int
 
-	var job models.Job
+const (
+	// ack removes the delivery from the queue: the execution was reported or the job was turned
+	// away for good, e.g. by a quota.
+	ack outcome = iota
+	// requeue returns the delivery to the queue after a failure that may not happen again, until it
+	// has been delivered too often.
+	requeue
+	// discard dead-letters a delivery that can never be processed.
+	discard
+	// postpone returns the delivery to the queue without counting the delivery, for a job that
+	// has to wait its turn.
+	postpone
+)
+
+// processJobs runs the jobs of the deliveries until the channel closes, at most concurrency at a
+// time. Further deliveries wait in the channel, and the prefetch limit keeps the broker from
+// sending more than the worker can run. Every delivery is acknowledged only once processJob is
+// done with it, so jobs held by a worker that crashes are delivered again. Jobs still waiting for
+// their start time when shutdown is done are returned to the queue.
+func processJobs(shutdown context.Context, conf *models.Config, ch *amqp091.Channel, concurrency int, runner *worker.Runner, pool *worker.SandboxPool, reporter *worker.Reporter, log *graylogger.GrayLogger, msgs <-chan amqp091.Delivery) {
+	log.Info(fmt.Sprintf("worker started, waiting for jobs to run %d at a time...", concurrency), nil)
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
 	for d := range msgs {
-		if err := json.Unmarshal(d.Body, &job); err != nil {
-			log.Error("failed to unmarshal job", &err)
-			return
-		}
+		metrics.MessagesConsumed.Inc()
 
-		log.Info(fmt.Sprintf("worker received job %s for user %s", job.JobID, job.UserID), nil)
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
 
-		jobExec, err := reporter.RegisterExecution(job.JobID)
-		if err != nil {
-			log.Error(fmt.Sprintf("failed to register job execution for job %s", job.JobID), &err)
-			return
-		}
-		log.Info(fmt.Sprintf("registered job execution %s for job %s", jobExec.ExecutionID, jobExec.JobID), nil)
+			ctx, span := tracing.StartProcess(context.Background(), conf.Rabbit.Name, d)
+			defer span.End()
+
+			result, cause := processJob(ctx, shutdown, d, runner, pool, reporter, log.WithContext(ctx))
+			settle(ctx, conf, ch, d, result, cause, log.WithContext(ctx))
+		}()
+	}
+	wg.Wait()
+}
+
+// settle acknowledges a delivery according to the outcome of processing it. Requeued deliveries
+// are published again with their delivery count raised, since the broker does not count them,
+// and dead-lettered with cause once they reach the configured maximum. A delivery is only
+// acknowledged once the broker has confirmed the copy that replaces it.
+func settle(ctx context.Context, conf *models.Config, ch *amqp091.Channel, d amqp091.Delivery, result outcome, cause error, log *graylogger.GrayLogger) {
+	deliveries := queue.Deliveries(d.Headers) + 1
+
+	var err error
+	switch {
+	case result == ack:
+		err = d.Ack(false)
+	case result == discard:
+		err = deadLetter(ctx, conf, ch, d, deliveries, cause.Error(), log)
+	case result == postpone:
+		err = republish(ctx, conf, ch, d, deliveries-1, log)
+	case deliveries >= conf.Rabbit.MaxDeliveries:
+		err = deadLetter(ctx, conf, ch, d, deliveries, fmt.Sprintf("gave up after %d deliveries: %v", deliveries, cause), log)
+	default:
+		err = republish(ctx, conf, ch, d, deliveries, log)
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to settle delivery %d", d.DeliveryTag), &err)
+	}
+}
+
+// republish sends a delivery back to the work queue through the delay queue, so that it is not
+// redelivered straight away.
+func republish(ctx context.Context, conf *models.Config, ch *amqp091.Channel, d amqp091.Delivery, deliveries int, log *graylogger.GrayLogger) error {
+	headers := maps.Clone(d.Headers)
+	if headers == nil {
+		headers = amqp091.Table{}
+	}
+	headers[queue.HeaderDeliveries] = int32(deliveries)
+
+	if err := forward(ctx, ch, "", conf.Rabbit.DelayQueue, d, headers); err != nil {
+		// the broker delivers it again all the same, only without counting the delivery
+		log.Error("failed to requeue delivery", &err)
+		return d.Nack(false, true)
+	}
+	return d.Ack(false)
+}
 
-		req, err := runner.NewRequest(job, jobExec.ExecutionID)
-		if err != nil {
-			log.Error(fmt.Sprintf("failed to create request for job %s", job.JobID), &err)
-			return
+// deadLetter sends a delivery to the dead-letter exchange along with why the worker gave up on it.
+func deadLetter(ctx context.Context, conf *models.Config, ch *amqp091.Channel, d amqp091.Delivery, deliveries int, reason string, log *graylogger.GrayLogger) error {
+	log.Info(fmt.Sprintf("dead-lettering delivery %d: %s", d.DeliveryTag, reason), nil)
+
+	headers := maps.Clone(d.Headers)
+	if headers == nil {
+		headers = amqp091.Table{}
+	}
+	headers[queue.HeaderDeliveries] = int32(deliveries)
+	headers[queue.HeaderDeadLetterReason] = reason
+	headers[queue.HeaderWorkerID] = conf.WorkerID
+
+	if err := forward(ctx, ch, conf.Rabbit.DeadLetterExchange, "", d, headers); err != nil {
+		// requeued rather than rejected, since the broker drops a rejected message if the work queue
+		// has no dead-letter exchange
+		log.Error("failed to dead-letter delivery", &err)
+		return d.Nack(false, true)
+	}
+	return d.Ack(false)
+}
+
+// publishMu serializes publishes on the consumer channel, which jobs settle concurrently.
+var publishMu sync.Mutex
+
+// errNotConfirmed is returned when the broker negatively acknowledges a published message.
+var errNotConfirmed = errors.New("broker did not confirm the message")
+
+// forward publishes the body of a delivery as a persistent message with new headers, and waits
+// for the broker to confirm it, so the delivery is not acknowledged while its copy may be lost.
+func forward(ctx context.Context, ch *amqp091.Channel, exchange string, key string, d amqp091.Delivery, headers amqp091.Table) error {
+	pubCtx, span, headers := tracing.StartPublish(ctx, utils.If(exchange == "", key, exchange), headers)
+	defer span.End()
+
+	msg := amqp091.Publishing{ContentType: d.ContentType, DeliveryMode: amqp091.Persistent, Headers: headers, Body: d.Body}
+
+	publishMu.Lock()
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(pubCtx, exchange, key, false, false, msg)
+	publishMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	confirmed, err := confirmation.WaitContext(pubCtx)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return errNotConfirmed
+	}
+	return nil
+}
+
+// processJob runs the job in a delivery and reports how the delivery should be settled along with
+// the failure that caused it to be requeued or discarded. Running jobs are not stopped by
+// shutdown, only jobs yet to start.
+func processJob(ctx context.Context, shutdown context.Context, d amqp091.Delivery, runner *worker.Runner, pool *worker.SandboxPool, reporter *worker.Reporter, log *graylogger.GrayLogger) (outcome, error) {
+	var job models.Job
+	if err := json.Unmarshal(d.Body, &job); err != nil {
+		log.Error("failed to unmarshal job", &err)
+		return discard, fmt.Errorf("message could not be decoded: %w", err)
+	}
+
+	log.Info(fmt.Sprintf("worker received job %s for user %s%s", job.JobID, job.UserID, utils.If(d.Redelivered, " again", "")), nil)
+
+	// waited out before anything is reserved, so a job scheduled for later holds no sandbox
+	if wait := worker.UntilStart(job); wait > 0 {
+		log.Info(fmt.Sprintf("waiting %v before starting job %s", wait, job.JobID), nil)
+		if err := worker.WaitForStart(shutdown, job); err != nil {
+			log.Info(fmt.Sprintf("returning job %s to the queue: the worker is shutting down", job.JobID), nil)
+			return postpone, nil
 		}
-		data, _ := json.Marshal(req)
-		log.Info(fmt.Sprintf("created request for job %s with execution ID %s", job.JobID, req.ExecutionID), utils.StringPtr(string(data)))
+	}
 
-		box, err := pool.Reserve(job.UserID)
-		if err != nil {
-			log.Error(fmt.Sprintf("failed to reserve sandbox for user %s", job.UserID), &err)
-			return
+	// checked up front so that a postponed job does not leave a cancelled execution behind each time
+	if pool.AtLimit(job.UserID) {
+		log.Info(fmt.Sprintf("postponing job %s: user %s holds the maximum number of sandboxes", job.JobID, job.UserID), nil)
+		return postpone, nil
+	}
+
+	jobExec, err := reporter.RegisterExecution(ctx, job.JobID)
+	if errors.Is(err, client.ErrQuotaExceeded) {
+		log.Info(fmt.Sprintf("returning job %s to the scheduler: user %s has used a daily quota", job.JobID, job.UserID), nil)
+		if err = reporter.ReturnJob(ctx, job.JobID); err != nil {
+			return requeue, err
 		}
-		log.Info(fmt.Sprintf("reserved sandbox %d for user %s", box.ID, job.UserID), nil)
+		return ack, nil
+	}
+	if errors.Is(err, client.ErrNotFound) {
+		log.Info(fmt.Sprintf("not running job %s: it was deleted", job.JobID), nil)
+		return ack, nil
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to register job execution for job %s", job.JobID), &err)
+		return requeue, err
+	}
+	log.Info(fmt.Sprintf("registered job execution %s for job %s", jobExec.ExecutionID, jobExec.JobID), nil)
 
-		req.BoxID = box.ID
+	req, err := runner.NewRequest(job, jobExec.ExecutionID)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to create request for job %s", job.JobID), &err)
+		rejectExecution(ctx, reporter, jobExec.ExecutionID, "payload could not be read", log)
+		return ack, nil
+	}
+	data, _ := json.Marshal(req)
+	log.Info(fmt.Sprintf("created request for job %s with execution ID %s", job.JobID, req.ExecutionID), utils.StringPtr(string(data)))
 
-		if err := runner.RunCode(*req, reporter); err != nil {
-			log.Error(fmt.Sprintf("failed to run code for job %s in sandbox %d", job.JobID, box.ID), &err)
-			return
+	hasQuota, err := reporter.HasSandboxQuota(ctx, job.UserID)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to check sandbox quota for user %s", job.UserID), &err)
+		abandonExecution(ctx, reporter, jobExec.ExecutionID, "sandbox time quota could not be checked", log)
+		return requeue, err
+	}
+	if !hasQuota {
+		log.Info(fmt.Sprintf("returning job %s to the scheduler: user %s has used their daily sandbox time quota", job.JobID, job.UserID), nil)
+		abandonExecution(ctx, reporter, jobExec.ExecutionID, "daily sandbox time quota exceeded", log)
+		if err = reporter.ReturnJob(ctx, job.JobID); err != nil {
+			return requeue, err
 		}
+		return ack, nil
+	}
 
-		pool.Release(job.UserID)
+	// waits for other users' jobs to take their fair share, and for compile checks holding some
+	box, err := pool.Acquire(ctx, jobExec.ExecutionID, job.UserID)
+	if errors.Is(err, worker.ErrSandboxLimit) {
+		log.Info(fmt.Sprintf("postponing job %s: user %s holds the maximum number of sandboxes", job.JobID, job.UserID), nil)
+		abandonExecution(ctx, reporter, jobExec.ExecutionID, "user holds the maximum number of sandboxes", log)
+		return postpone, nil
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to reserve sandbox for user %s", job.UserID), &err)
+		abandonExecution(ctx, reporter, jobExec.ExecutionID, "no sandbox was available", log)
+		return requeue, err
+	}
+	log.Info(fmt.Sprintf("reserved sandbox %d for user %s", box.ID, job.UserID), nil)
+	defer func() {
+		pool.Release(jobExec.ExecutionID)
 		log.Info(fmt.Sprintf("released sandbox %d for user %s", box.ID, job.UserID), nil)
+	}()
+
+	req.BoxID = box.ID
+
+	if err := runner.RunCode(ctx, *req, reporter); err != nil {
+		log.Error(fmt.Sprintf("failed to run code for job %s in sandbox %d", job.JobID, box.ID), &err)
+		// an execution whose result could not be reported is left for the redelivery to replace
+		if !errors.Is(err, worker.ErrNotReported) {
+			abandonExecution(ctx, reporter, jobExec.ExecutionID, "the sandbox failed to run the job", log)
+		}
+		return requeue, err
+	}
+
+	return ack, nil
+}
+
+// rejectExecution fails an execution that will not run, logging failures since the delivery is
+// settled either way.
+func rejectExecution(ctx context.Context, reporter *worker.Reporter, executionId string, reason string, log *graylogger.GrayLogger) {
+	if _, err := reporter.RejectExecution(ctx, executionId, reason); err != nil {
+		log.Error(fmt.Sprintf("failed to reject execution %s", executionId), &err)
+	}
+}
+
+// abandonExecution cancels an execution whose delivery is going back to the queue, so that the
+// redelivery does not also trigger a retry of the job.
+func abandonExecution(ctx context.Context, reporter *worker.Reporter, executionId string, reason string, log *graylogger.GrayLogger) {
+	if _, err := reporter.AbandonExecution(ctx, executionId, reason); err != nil {
+		log.Error(fmt.Sprintf("failed to abandon execution %s", executionId), &err)
 	}
 }
@@ -0,0 +1,188 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/compile"
+	"github.com/julianstephens/distributed-job-manager/pkg/config"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
+	"github.com/oklog/ulid/v2"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// CompileTimeout bounds how long compiling a single code block may take.
+const CompileTimeout = 30 * time.Second
+
+// compileCheckUser is who the sandbox pool counts compile checks against, so that they share a user's
+// limits and fair share rather than competing with jobs one by one.
+const compileCheckUser = "compile"
+
+// diagnosticPattern matches a compiler error such as '/tmp/djm/123.go:5:2: undefined: x'.
+var diagnosticPattern = regexp.MustCompile(`^(\S+\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// Compiler answers compile checks from the job service. Each check reserves a sandbox, builds
+// every code block in it without running them and wipes the sandbox afterwards.
+type Compiler struct {
+	config *models.Config
+	log    *graylogger.GrayLogger
+	pool   *SandboxPool
+}
+
+func NewCompiler(log *graylogger.GrayLogger, pool *SandboxPool) *Compiler {
+	return &Compiler{
+		config: config.GetConfig(),
+		log:    log,
+		pool:   pool,
+	}
+}
+
+// Run answers compile checks until ctx is cancelled or the channel closes.
+func (c *Compiler) Run(ctx context.Context, conn *amqp091.Connection) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	q, err := compile.DeclareQueue(ch, c.config)
+	if err != nil {
+		return err
+	}
+
+	msgs, err := ch.ConsumeWithContext(ctx, q.Name, "", true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("unable to consume compile queue: %w", err)
+	}
+
+	for d := range msgs {
+		msgCtx, span := tracing.StartProcess(ctx, q.Name, d)
+		res := c.check(msgCtx, d.Body)
+
+		if d.ReplyTo != "" {
+			body, _ := json.Marshal(res)
+			if err := ch.PublishWithContext(msgCtx, "", d.ReplyTo, false, false, amqp091.Publishing{
+				ContentType:   "application/json",
+				CorrelationId: d.CorrelationId,
+				Body:          body,
+			}); err != nil {
+				c.log.WithContext(msgCtx).Error(fmt.Sprintf("failed to answer compile check %s", d.CorrelationId), &err)
+			}
+		}
+		span.End()
+	}
+
+	return nil
+}
+
+func (c *Compiler) check(ctx context.Context, body []byte) models.CompileResponse {
+	log := c.log.WithContext(ctx)
+
+	var req models.CompileRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.Error("failed to unmarshal compile check", &err)
+		return models.CompileResponse{Error: "malformed compile check"}
+	}
+
+	owner := "compile:" + ulid.Make().String()
+	box, err := c.pool.Reserve(owner, compileCheckUser)
+	if err != nil {
+		return models.CompileResponse{Error: err.Error()}
+	}
+	defer func() {
+		if err := c.pool.Reset(owner); err != nil {
+			log.Error(fmt.Sprintf("failed to reset sandbox %d after compile check", box.ID), &err)
+		}
+	}()
+
+	res := models.CompileResponse{Diagnostics: []models.PayloadDiagnostic{}}
+	for i, block := range req.Blocks {
+		diagnostics, err := c.compileBlock(ctx, box.ID, block)
+		if err != nil {
+			log.Error(fmt.Sprintf("failed to compile block %d in sandbox %d", i+1, box.ID), &err)
+			return models.CompileResponse{Error: err.Error()}
+		}
+		for _, d := range diagnostics {
+			d.Block = i + 1
+			d.Language = block.Language
+			res.Diagnostics = append(res.Diagnostics, d)
+		}
+	}
+
+	return res
+}
+
+// compileBlock builds a code block in a sandbox and returns the compiler's diagnostics, which
+// are empty if it compiled. An error is returned only if the build could not be attempted.
+func (c *Compiler) compileBlock(ctx context.Context, boxId int, block models.CompileBlock) ([]models.PayloadDiagnostic, error) {
+	name, err := writeToTempFile([]byte(block.Content), block.Language, *c.config)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(name)
+
+	ctx, cancel := context.WithTimeout(ctx, CompileTimeout)
+	defer cancel()
+
+	out, err := isolateCommand(ctx, boxId, c.config.TempDir, "build", "-o", "/dev/null", name).Output()
+	if err == nil {
+		return nil, nil
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return []models.PayloadDiagnostic{{Message: fmt.Sprintf("compiling took longer than %v", CompileTimeout)}}, nil
+	}
+	// isolate exits with status 2 when the sandbox itself fails
+	if exitErr.ExitCode() == 2 {
+		return nil, fmt.Errorf("isolate error: %s", strings.TrimSpace(string(exitErr.Stderr)))
+	}
+
+	diagnostics := parseDiagnostics(string(out), filepath.Base(name))
+	if len(diagnostics) == 0 {
+		diagnostics = append(diagnostics, models.PayloadDiagnostic{Message: strings.TrimSpace(string(out))})
+	}
+
+	return diagnostics, nil
+}
+
+// parseDiagnostics maps the go build output of fileName to line and column diagnostics. Output
+// that does not point into the file, such as errors of the go tool itself, is kept as a
+// diagnostic of the whole block.
+func parseDiagnostics(output string, fileName string) []models.PayloadDiagnostic {
+	var diagnostics []models.PayloadDiagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		// '# command-line-arguments' headers and blank lines carry nothing
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		m := diagnosticPattern.FindStringSubmatch(line)
+		if m == nil || filepath.Base(m[1]) != fileName {
+			diagnostics = append(diagnostics, models.PayloadDiagnostic{Message: line})
+			continue
+		}
+
+		d := models.PayloadDiagnostic{Message: m[4]}
+		d.Line, _ = strconv.Atoi(m[2])
+		d.Column, _ = strconv.Atoi(m[3])
+		diagnostics = append(diagnostics, d)
+	}
+
+	return diagnostics
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/client"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/utils"
+)
+
+// Version is the version a worker reports when it registers. Release builds set it with
+// -ldflags "-X github.com/julianstephens/distributed-job-manager/services/workersvc/worker.Version=<version>".
+var Version = "dev"
+
+// RunHeartbeats registers the worker with jobsvc and then reports the load of its sandbox pool
+// every heartbeat interval until ctx is cancelled. The worker registers again whenever jobsvc does
+// not know it, e.g. after a failed registration. Failures are logged and retried on the next tick.
+func (r *Reporter) RunHeartbeats(ctx context.Context, pool *SandboxPool) {
+	registered := r.register(ctx) == nil
+
+	ticker := time.NewTicker(r.conf.Worker.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !registered {
+			registered = r.register(ctx) == nil
+			continue
+		}
+
+		_, reserved := pool.Usage()
+		_, err := r.api.SendWorkerHeartbeat(ctx, r.conf.WorkerID, models.WorkerHeartbeat{CurrentLoad: reserved})
+		switch {
+		case errors.Is(err, client.ErrNotFound):
+			registered = r.register(ctx) == nil
+		case err != nil:
+			r.log.Error(fmt.Sprintf("failed to send heartbeat of worker %s", r.conf.WorkerID), &err)
+		}
+	}
+}
+
+// register reports the worker's host, capacity, languages and version to jobsvc.
+func (r *Reporter) register(ctx context.Context) error {
+	host, err := os.Hostname()
+	if err != nil {
+		host = r.conf.Worker.Host
+	}
+
+	registration := models.WorkerRegistration{
+		Host:      host,
+		Capacity:  r.conf.SandboxCount,
+		Languages: slices.Sorted(maps.Keys(utils.GetSupportedLanguages())),
+		Version:   Version,
+	}
+
+	if _, err = r.api.RegisterWorker(ctx, r.conf.WorkerID, registration); err != nil {
+		r.log.Error(fmt.Sprintf("failed to register worker %s", r.conf.WorkerID), &err)
+		return err
+	}
+
+	r.log.Info(fmt.Sprintf("registered worker %s with %d sandboxes", r.conf.WorkerID, registration.Capacity), nil)
+	return nil
+}
@@ -0,0 +1,97 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/client"
+	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+const (
+	OutputFlushInterval = time.Second
+	OutputChunkSize     = 4 * 1024
+)
+
+// OutputStream batches the output of a running execution and sends it to jobsvc in ordered
+// chunks, at least every OutputFlushInterval.
+type OutputStream struct {
+	ctx         context.Context
+	executionId string
+	api         *client.Client
+	log         *graylogger.GrayLogger
+	lines       chan string
+	done        chan struct{}
+	seq         int
+}
+
+func newOutputStream(ctx context.Context, executionId string, api *client.Client, log *graylogger.GrayLogger) *OutputStream {
+	s := &OutputStream{
+		ctx:         ctx,
+		executionId: executionId,
+		api:         api,
+		log:         log,
+		lines:       make(chan string, 256),
+		done:        make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// WriteLine queues a line of output to be sent.
+func (s *OutputStream) WriteLine(line string) {
+	s.lines <- line
+}
+
+// Close sends any buffered output and waits for the stream to finish.
+func (s *OutputStream) Close() {
+	close(s.lines)
+	<-s.done
+}
+
+func (s *OutputStream) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(OutputFlushInterval)
+	defer ticker.Stop()
+
+	var buf strings.Builder
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				s.flush(&buf)
+				return
+			}
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+			if buf.Len() >= OutputChunkSize {
+				s.flush(&buf)
+			}
+		case <-ticker.C:
+			s.flush(&buf)
+		}
+	}
+}
+
+// flush sends the buffered output. A chunk that cannot be delivered is dropped from the live
+// stream; the complete output is still reported when the execution finishes.
+func (s *OutputStream) flush(buf *strings.Builder) {
+	if buf.Len() == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+
+	chunk := models.ExecutionOutputChunk{Seq: s.seq, Data: buf.String()}
+	if err := s.api.AppendExecutionOutput(ctx, s.executionId, chunk); err != nil {
+		s.log.Error(fmt.Sprintf("failed to stream output chunk %d of execution %s", s.seq, s.executionId), &err)
+	}
+
+	s.seq++
+	buf.Reset()
+}
@@ -1,10 +1,11 @@
 package worker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
-	"github.com/julianstephens/distributed-job-manager/pkg/auth0client"
+	"github.com/julianstephens/distributed-job-manager/pkg/client"
 	"github.com/julianstephens/distributed-job-manager/pkg/config"
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
 	"github.com/julianstephens/distributed-job-manager/pkg/models"
@@ -12,56 +13,53 @@ import (
 )
 
 type Reporter struct {
-	conf    *models.Config
-	token   *string
-	client  *auth0client.Auth0Client
-	baseURL string
-	log     *graylogger.GrayLogger
-	api     *JobAPI
+	conf *models.Config
+	log  *graylogger.GrayLogger
+	api  *client.Client
 }
 
 func NewReporter(log *graylogger.GrayLogger) *Reporter {
 	conf := config.GetConfig()
-	r := &Reporter{
-		conf:   conf,
-		token:  nil,
-		log:    log,
-		client: auth0client.NewAuth0Client(conf.Auth0Worker.ClientId, conf.Auth0Worker.ClientSecret),
-	}
-	r.baseURL = r.conf.JobAPIEndpoint
-	r.api = NewJobAPI(r.client, log, r.baseURL)
-	return r
+	return &Reporter{
+		conf: conf,
+		log:  log,
+		api: client.New(
+			conf.JobAPIEndpoint,
+			client.WithAuth(client.NewServiceAuth(conf, models.WorkerSubjectPrefix+conf.WorkerID, conf.Auth0Worker.ClientId, conf.Auth0Worker.ClientSecret)),
+		),
+	}
 }
 
-func (r *Reporter) RegisterExecution(jobId string) (*models.JobExecution, error) {
+func (r *Reporter) RegisterExecution(ctx context.Context, jobId string) (*models.JobExecution, error) {
 	exec := models.JobExecution{
 		JobID:    jobId,
 		WorkerID: r.conf.WorkerID,
 		Status:   models.JobStatusScheduled,
 	}
 
-	data, err := r.api.CreateExecution(exec)
+	data, err := r.api.CreateExecution(ctx, exec)
 	if err != nil {
+		r.log.WithContext(ctx).Error(fmt.Sprintf("failed to create execution for job %s", jobId), &err)
 		return nil, err
 	}
 
 	if data == nil {
 		msg := "no data returned from execution registration"
-		r.log.Error(msg, nil)
+		r.log.WithContext(ctx).Error(msg, nil)
 		return nil, errors.New(msg)
 	}
 
 	return data, nil
 }
 
-func (r *Reporter) StartExecution(executionId string) (*models.JobExecution, error) {
+func (r *Reporter) StartExecution(ctx context.Context, executionId string) (*models.JobExecution, error) {
 	status := models.JobStatusInProgress
 
 	update := models.JobExecutionUpdateRequest{
 		Status: &status,
 	}
 
-	data, err := r.api.UpdateExecution(executionId, update)
+	data, err := r.updateExecution(ctx, executionId, update)
 	if err != nil {
 		return nil, err
 	}
@@ -73,25 +71,108 @@ func (r *Reporter) StartExecution(executionId string) (*models.JobExecution, err
 	return data, nil
 }
 
-func (r *Reporter) CompleteExecution(executionId string, response RunnerResponse) (*models.JobExecution, error) {
-	r.log.Info(fmt.Sprintf("completing execution %s with status %s", executionId, utils.If(response.Error == nil, "completed", "failed")), nil)
+func (r *Reporter) CompleteExecution(ctx context.Context, executionId string, response RunnerResponse) (*models.JobExecution, error) {
+	update := completion(response)
+	r.log.WithContext(ctx).Info(fmt.Sprintf("completing execution %s with status %s", executionId, *update.Status), nil)
 
-	update := models.JobExecutionUpdateRequest{
+	data, err := r.updateExecution(ctx, executionId, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if data == nil {
+		return nil, fmt.Errorf("no data returned from execution completion")
+	}
+
+	return data, nil
+}
+
+// completion is the update that reports a finished run: failed if the program failed, so that
+// jobsvc retries its job, and completed otherwise.
+func completion(response RunnerResponse) models.JobExecutionUpdateRequest {
+	return models.JobExecutionUpdateRequest{
 		StartTime:    &response.StartTime,
 		EndTime:      &response.EndTime,
 		Status:       utils.StringPtr(utils.If(response.Error == nil, models.JobStatusCompleted, models.JobStatusFailed)),
 		ErrorMessage: response.Error,
 		Output:       response.Output,
 	}
+}
 
-	data, err := r.api.UpdateExecution(executionId, update)
+// HasSandboxQuota reports whether a user's jobs may use more sandbox time today.
+func (r *Reporter) HasSandboxQuota(ctx context.Context, userId string) (bool, error) {
+	usage, err := r.api.GetUsage(ctx, userId)
 	if err != nil {
+		r.log.WithContext(ctx).Error(fmt.Sprintf("failed to get quota usage of user %s", userId), &err)
+		return false, err
+	}
+
+	return !usage.DailySandboxSeconds.Exhausted(), nil
+}
+
+// RejectExecution fails an execution that was not run.
+func (r *Reporter) RejectExecution(ctx context.Context, executionId string, reason string) (*models.JobExecution, error) {
+	return r.CompleteExecution(ctx, executionId, RunnerResponse{Error: &reason})
+}
+
+// AbandonExecution cancels an execution that will not run because its job is going back to the
+// queue, so it is neither retried nor counted as a failed attempt.
+func (r *Reporter) AbandonExecution(ctx context.Context, executionId string, reason string) (*models.JobExecution, error) {
+	update := models.JobExecutionUpdateRequest{
+		Status:       utils.StringPtr(models.JobStatusCancelled),
+		ErrorMessage: &reason,
+	}
+
+	return r.updateExecution(ctx, executionId, update)
+}
+
+// ReturnJob puts a job that was not run back to pending, so that the scheduler dispatches it
+// again once its owner may run jobs.
+func (r *Reporter) ReturnJob(ctx context.Context, jobId string) error {
+	update := models.JobUpdateRequest{
+		Status: utils.StringPtr(models.JobStatusPending),
+	}
+
+	if _, err := r.api.UpdateJob(ctx, jobId, update); err != nil {
+		r.log.WithContext(ctx).Error(fmt.Sprintf("failed to return job %s to the scheduler", jobId), &err)
+		return err
+	}
+
+	return nil
+}
+
+// StreamOutput starts sending the output of a running execution to jobsvc.
+func (r *Reporter) StreamOutput(ctx context.Context, executionId string) *OutputStream {
+	return newOutputStream(ctx, executionId, r.api, r.log.WithContext(ctx))
+}
+
+// updateExecution reports an execution update and mirrors the resulting status onto its job. The
+// job of a failed execution is left to jobsvc, which retries or fails it. Executions are only
+// cancelled when abandoned, and the job of an abandoned execution stays scheduled since its
+// delivery is going back to the queue.
+func (r *Reporter) updateExecution(ctx context.Context, executionId string, update models.JobExecutionUpdateRequest) (*models.JobExecution, error) {
+	execution, err := r.api.UpdateExecution(ctx, executionId, update)
+	if err != nil {
+		r.log.WithContext(ctx).Error(fmt.Sprintf("failed to update execution %s", executionId), &err)
 		return nil, err
 	}
 
-	if data == nil {
-		return nil, fmt.Errorf("no data returned from execution completion")
+	jobUpdates := models.JobUpdateRequest{}
+	if update.Status != nil {
+		switch *update.Status {
+		case models.JobStatusCancelled:
+			jobUpdates.Status = utils.StringPtr(models.JobStatusScheduled)
+		case models.JobStatusInProgress:
+			jobUpdates.Status = utils.StringPtr(models.JobStatusInProgress)
+		}
 	}
 
-	return data, nil
+	if jobUpdates.Status != nil {
+		if _, err = r.api.UpdateJob(ctx, execution.JobID, jobUpdates); err != nil {
+			r.log.WithContext(ctx).Error(fmt.Sprintf("failed to update status of job %s", execution.JobID), &err)
+			return nil, fmt.Errorf("failed to update job status: %w", err)
+		}
+	}
+
+	return execution, nil
 }
@@ -14,11 +14,17 @@ import (
 
 	htmlparse "golang.org/x/net/html"
 
+	"github.com/julianstephens/distributed-job-manager/pkg/client"
 	"github.com/julianstephens/distributed-job-manager/pkg/config"
 	"github.com/julianstephens/distributed-job-manager/pkg/graylogger"
 	"github.com/julianstephens/distributed-job-manager/pkg/logger"
+	"github.com/julianstephens/distributed-job-manager/pkg/metrics"
 	"github.com/julianstephens/distributed-job-manager/pkg/models"
+	"github.com/julianstephens/distributed-job-manager/pkg/tracing"
 	"github.com/julianstephens/distributed-job-manager/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type RunnerRequest struct {
@@ -52,6 +58,9 @@ type Runner struct {
 
 const Timeout = 120 * time.Second
 
+// ErrNotReported is returned by RunCode when the job ran but its result could not be reported.
+var ErrNotReported = errors.New("execution result was not reported")
+
 func NewRunner(log *graylogger.GrayLogger) *Runner {
 	return &Runner{
 		config: config.GetConfig(),
@@ -68,6 +77,9 @@ func (r *Runner) NewRequest(job models.Job, executionID string) (*RunnerRequest,
 
 	d := utils.DocumentData{}
 	r.parser.ExtractCodeBlocks(doc, &d)
+	if len(d.CodeBlocks) == 0 {
+		return nil, errors.New("payload has no code blocks")
+	}
 
 	return &RunnerRequest{
 		config:            r.config,
@@ -79,7 +91,34 @@ func (r *Runner) NewRequest(job models.Job, executionID string) (*RunnerRequest,
 	}, nil
 }
 
-func (r *Runner) RunCode(in RunnerRequest, reporter *Reporter) error {
+// startLead is how long before its execution time a job may start.
+const startLead = 10 * time.Second
+
+// UntilStart returns how long a job has to wait before it may start, or zero if it may start now.
+func UntilStart(job models.Job) time.Duration {
+	return max(time.Until(job.ExecutionTime)-startLead, 0)
+}
+
+// WaitForStart blocks until a job may start, returning ctx's error if ctx is done first.
+func WaitForStart(ctx context.Context, job models.Job) error {
+	wait := UntilStart(job)
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) RunCode(ctx context.Context, in RunnerRequest, reporter *Reporter) error {
+	log := r.log.WithContext(ctx)
 	block := in.Blocks[0]
 
 	name, err := writeToTempFile([]byte(block.Content), block.Language, *r.config)
@@ -88,84 +127,82 @@ func (r *Runner) RunCode(in RunnerRequest, reporter *Reporter) error {
 	}
 	defer os.Remove(name)
 
-	timeToStart := time.Until(in.ExpectedStartTime) - (10 * time.Second)
-	if timeToStart > 0 {
-		r.log.Info(fmt.Sprintf("waiting %v before starting job %s", timeToStart, in.JobID), nil)
-		time.Sleep(timeToStart)
-	}
-
-	r.log.Info(fmt.Sprintf("starting execution %s for job %s", in.ExecutionID, in.JobID), nil)
+	log.Info(fmt.Sprintf("starting execution %s for job %s", in.ExecutionID, in.JobID), nil)
 
-	if _, err = reporter.StartExecution(in.ExecutionID); err != nil {
+	if _, err = reporter.StartExecution(ctx, in.ExecutionID); err != nil {
 		return err
 	}
 
 	results := make(chan Result)
 
-	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	runCtx, span := tracing.Tracer().Start(ctx, "sandbox.run", trace.WithAttributes(
+		attribute.String("djm.execution.id", in.ExecutionID),
+		attribute.String("djm.job.id", in.JobID),
+		attribute.String("djm.language", block.Language),
+		attribute.Int("djm.sandbox.id", in.BoxID),
+	))
+	runCtx, cancel := context.WithTimeout(runCtx, Timeout)
 	defer cancel()
 
-	go runBlock(ctx, in.BoxID, r.config.TempDir, name, results)
+	output := reporter.StreamOutput(ctx, in.ExecutionID)
+	start := time.Now()
+	go runBlock(runCtx, in.BoxID, r.config.TempDir, name, output.WriteLine, results)
 
 	result := <-results
+	output.Close()
+
+	switch {
+	case result.Err != nil:
+		span.RecordError(result.Err)
+		span.SetStatus(codes.Error, result.Err.Error())
+	case result.Value.Error != nil:
+		span.SetStatus(codes.Error, *result.Value.Error)
+	}
+	span.End()
+
+	outcome := utils.If(result.Err == nil && result.Value.Error == nil, metrics.ExecutionCompleted, metrics.ExecutionFailed)
+	metrics.ExecutionDuration.WithLabelValues(block.Language, outcome).Observe(time.Since(start).Seconds())
 
 	if result.Err != nil {
 		logger.Errorf("execution %s failed with error: %v", in.ExecutionID, result.Err)
-		r.log.Error(fmt.Sprintf("execution %s failed", in.ExecutionID), &result.Err)
+		log.Error(fmt.Sprintf("execution %s failed", in.ExecutionID), &result.Err)
 		return result.Err
 	}
 
-	r.log.Info(fmt.Sprintf("execution %s completed successfully", in.ExecutionID), utils.StringPtr(string(utils.MustMarshalJson(result))))
-	if _, err = reporter.CompleteExecution(in.ExecutionID, result.Value); err != nil {
-		return err
+	log.Info(fmt.Sprintf("execution %s %s", in.ExecutionID, utils.If(result.Value.Error == nil, "completed successfully", "failed")), utils.StringPtr(string(utils.MustMarshalJson(result))))
+	if _, err = reporter.CompleteExecution(ctx, in.ExecutionID, result.Value); err != nil {
+		// the execution was settled without this worker, e.g. failed by the reaper while the
+		// worker was unresponsive, and its job retried from there
+		if errors.Is(err, client.ErrConflict) {
+			log.Warn(fmt.Sprintf("execution %s was settled before its result was reported", in.ExecutionID), nil)
+			return nil
+		}
+		return fmt.Errorf("%w: %w", ErrNotReported, err)
 	}
 
 	return nil
 }
 
-func runBlock(ctx context.Context, boxId int, tempDir string, fileName string, results chan<- Result) {
-	cmd := exec.CommandContext(ctx,
-		"isolate",
-		fmt.Sprintf("--box-id=%v", boxId),
-		// max size (in KB) of files that can be created per execution = 5MB
-		"--fsize=5120",
-		// makes directory visible in the sandbox
-		fmt.Sprintf("--dir=%v", tempDir),
-		// give read write access to the go cache dir as it needs to be cleaned
-		"--dir=/root/.cache/go-build:rw",
-		// if sandbox is busy, wait instead of returning error right away
-		// instead of serving 25/100 requests in 10 sandbox, it's gonna serve all
-		"--wait",
-		// to keep the child process in parent’s network namespace and communicate with the outside world
-		"--share-net",
-		"--processes=100",
-		// unlimited open files
-		"--open-files=0",
-		"--env=GOROOT",
-		"--env=GOPATH",
-		"--env=GO111MODULE=on",
-		"--env=HOME",
-		// makes commands visible in the sandbox e.g. 'ls', 'echo' or other installed command
-		"--env=PATH",
-		// log package writes to stderr instead of stdout, so we need to redirect this to stdout.
-		// only exit code determines if the program ran successfully or not
-		"--stderr-to-stdout",
-		"--run",
-		"--",
-		"/usr/local/go/bin/go",
-		"run",
-		fileName,
-	)
+// runBlock runs a code file in a sandbox, passing each line of output to onLine as it is
+// written and sending the complete result to results.
+func runBlock(ctx context.Context, boxId int, tempDir string, fileName string, onLine func(string), results chan<- Result) {
+	cmd := isolateCommand(ctx, boxId, tempDir, "run", fileName)
 
 	cmd.WaitDelay = Timeout - (10 * time.Second) // give some time to isolate to clean up the sandbox
 
+	results <- runCommand(ctx, cmd, onLine)
+}
+
+// runCommand runs a sandboxed command, passing each line of its output to onLine. A program that
+// fails, e.g. by exiting non-zero or running out of time, is a result with its Error set; Err is
+// only set if the sandbox itself could not run it.
+func runCommand(ctx context.Context, cmd *exec.Cmd, onLine func(string)) Result {
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
 	stdoutpipe, err := cmd.StdoutPipe()
 	if err != nil {
-		results <- Result{Value: RunnerResponse{}, Err: err}
-		return
+		return Result{Value: RunnerResponse{}, Err: err}
 	}
 
 	res := RunnerResponse{
@@ -173,35 +210,81 @@ func runBlock(ctx context.Context, boxId int, tempDir string, fileName string, r
 	}
 	err = cmd.Start()
 	if err != nil {
-		results <- Result{Value: res, Err: err}
-		return
+		return Result{Value: res, Err: err}
 	}
 
+	var output strings.Builder
 	scanner := bufio.NewScanner(stdoutpipe)
 	for scanner.Scan() {
 		m := scanner.Text()
-		res.Output = utils.StringPtr(m)
+		output.WriteString(m)
+		output.WriteByte('\n')
+		onLine(m)
 	}
+	res.Output = utils.StringPtr(output.String())
 
 	err = cmd.Wait()
+	res.EndTime = time.Now().UTC()
 	if err != nil {
 		if strings.Contains(stderr.String(), "box is currently in use by another process") {
-			results <- Result{Value: res, Err: ErrSandboxBusy}
-			return
+			return Result{Value: res, Err: ErrSandboxBusy}
 		}
 
 		if strings.Contains(err.Error(), "exit status 2") {
-			results <- Result{Value: res, Err: fmt.Errorf("isolate error: %v", stderr.String())}
-			return
+			return Result{Value: res, Err: fmt.Errorf("isolate error: %v", stderr.String())}
 		}
 
-		// TODO: process error responses
-		// for _, errStr := range strings.Split(stderr.String(), "\n") {
-		// }
+		res.Error = utils.StringPtr(failureMessage(ctx, err, stderr.String()))
 	}
 
-	res.EndTime = time.Now().UTC()
-	results <- Result{Value: res, Err: nil}
+	return Result{Value: res, Err: nil}
+}
+
+// failureMessage says why a program failed: isolate's status message, such as 'Exited with error
+// status 1', or the exit error if it gave none.
+func failureMessage(ctx context.Context, err error, stderr string) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Sprintf("execution timed out after %v", Timeout)
+	}
+	if msg := strings.TrimSpace(stderr); msg != "" {
+		return msg
+	}
+	return err.Error()
+}
+
+// isolateCommand runs a go subcommand in a sandbox with tempDir mounted.
+func isolateCommand(ctx context.Context, boxId int, tempDir string, goArgs ...string) *exec.Cmd {
+	args := []string{
+		fmt.Sprintf("--box-id=%v", boxId),
+		// max size (in KB) of files that can be created per execution = 5MB
+		"--fsize=5120",
+		// makes directory visible in the sandbox
+		fmt.Sprintf("--dir=%v", tempDir),
+		// give read write access to the go cache dir as it needs to be cleaned
+		"--dir=/root/.cache/go-build:rw",
+		// if sandbox is busy, wait instead of returning error right away
+		// instead of serving 25/100 requests in 10 sandbox, it's gonna serve all
+		"--wait",
+		// to keep the child process in parent’s network namespace and communicate with the outside world
+		"--share-net",
+		"--processes=100",
+		// unlimited open files
+		"--open-files=0",
+		"--env=GOROOT",
+		"--env=GOPATH",
+		"--env=GO111MODULE=on",
+		"--env=HOME",
+		// makes commands visible in the sandbox e.g. 'ls', 'echo' or other installed command
+		"--env=PATH",
+		// log package writes to stderr instead of stdout, so we need to redirect this to stdout.
+		// only exit code determines if the program ran successfully or not
+		"--stderr-to-stdout",
+		"--run",
+		"--",
+		"/usr/local/go/bin/go",
+	}
+
+	return exec.CommandContext(ctx, "isolate", append(args, goArgs...)...)
 }
 
 func writeToTempFile(b []byte, lang string, conf models.Config) (string, error) {
@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/julianstephens/distributed-job-manager/pkg/models"
+)
+
+func TestRunCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    string
+		timeout   time.Duration
+		wantError string
+		wantOut   string
+	}{
+		{"success", "echo done", time.Minute, "", "done\n"},
+		{"non-zero exit", "echo partial; echo 'Exited with error status 3' >&2; exit 1", time.Minute, "Exited with error status 3", "partial\n"},
+		{"non-zero exit without status message", "exit 3", time.Minute, "exit status 3", ""},
+		{"timeout", "exec sleep 5", 100 * time.Millisecond, "execution timed out after " + Timeout.String(), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			var lines []string
+			result := runCommand(ctx, exec.CommandContext(ctx, "sh", "-c", tt.script), func(line string) { lines = append(lines, line) })
+			if result.Err != nil {
+				t.Fatalf("runCommand() error = %v, want the failure reported in the response", result.Err)
+			}
+
+			res := result.Value
+			if tt.wantError == "" && res.Error != nil {
+				t.Errorf("runCommand() response error = %q, want none", *res.Error)
+			}
+			if tt.wantError != "" && (res.Error == nil || *res.Error != tt.wantError) {
+				t.Errorf("runCommand() response error = %v, want %q", res.Error, tt.wantError)
+			}
+			if res.Output == nil || *res.Output != tt.wantOut {
+				t.Errorf("runCommand() output = %v, want %q", res.Output, tt.wantOut)
+			}
+			if res.EndTime.Before(res.StartTime) {
+				t.Errorf("runCommand() ended at %v, before it started at %v", res.EndTime, res.StartTime)
+			}
+		})
+	}
+}
+
+// TestFailedRunIsReportedAsFailed checks that a program exiting non-zero is reported with the
+// failed status, which is what makes jobsvc apply the job's retry policy.
+func TestFailedRunIsReportedAsFailed(t *testing.T) {
+	ctx := context.Background()
+	result := runCommand(ctx, exec.CommandContext(ctx, "sh", "-c", "exit 1"), func(string) {})
+
+	update := completion(result.Value)
+	if *update.Status != models.JobStatusFailed {
+		t.Fatalf("completion() status = %s, want %s", *update.Status, models.JobStatusFailed)
+	}
+	if update.ErrorMessage == nil || *update.ErrorMessage == "" {
+		t.Errorf("completion() error message = %v, want the exit status", update.ErrorMessage)
+	}
+
+	update = completion(RunnerResponse{})
+	if *update.Status != models.JobStatusCompleted {
+		t.Errorf("completion() status = %s, want %s", *update.Status, models.JobStatusCompleted)
+	}
+}
@@ -1,6 +1,7 @@
 package worker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os/exec"
@@ -18,21 +19,35 @@ const (
 
 type Sandbox struct {
 	ID         int
+	UserID     string
 	ExpiresAt  time.Time
 	LastUsedAt time.Time
 }
 
+// SandboxPool hands out sandboxes under reservations, such as executions, so a user can hold
+// several at once. UserLimit caps how many sandboxes a single user may hold, 0 for no cap. While
+// users wait for a sandbox, a released one goes to the waiting user holding the fewest, so one user
+// cannot monopolize the pool.
 type SandboxPool struct {
 	mu                            sync.Mutex
 	Count                         int
+	UserLimit                     int
 	Reserved                      map[string]*Sandbox
 	AvailableBoxes                map[int]bool
 	SandboxTTL                    time.Duration
 	CleanupFrequency              time.Duration
 	InactivityExpirationThreshold time.Duration
+	// waiting counts the Acquire calls of each user that are waiting for a sandbox
+	waiting map[string]int
+	// released is closed and replaced whenever a sandbox is released or a waiter leaves, waking
+	// Acquire calls
+	released chan struct{}
 }
 
-var ErrSandboxBusy error = errors.New("sandbox busy")
+var (
+	ErrSandboxBusy  error = errors.New("sandbox busy")
+	ErrSandboxLimit error = errors.New("user holds the maximum number of sandboxes")
+)
 
 func NewSandboxPool(count int) *SandboxPool {
 	s := make(map[int]bool)
@@ -53,32 +68,153 @@ func NewSandboxPool(count int) *SandboxPool {
 		SandboxTTL:                    DefaultSandboxTTL,
 		CleanupFrequency:              DefaultCleanupFrequency,
 		InactivityExpirationThreshold: DefaultInactivityExpirationThreshold,
+		waiting:                       make(map[string]int),
+		released:                      make(chan struct{}),
 	}
 }
 
 func (s *SandboxPool) AvailableCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return len(s.AvailableBoxes)
 }
 
-func (s *SandboxPool) GetSandbox(userID string) (*Sandbox, bool) {
+// Ready returns an error if no sandbox could be initialized, so the pool can never run a job.
+func (s *SandboxPool) Ready() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.AvailableBoxes)+len(s.Reserved) == 0 {
+		return fmt.Errorf("none of the %d sandboxes could be initialized", s.Count)
+	}
+	return nil
+}
+
+// Usage returns how many initialized sandboxes are available and how many are reserved.
+func (s *SandboxPool) Usage() (available int, reserved int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	b, ok := s.Reserved[userID]
+	return len(s.AvailableBoxes), len(s.Reserved)
+}
+
+func (s *SandboxPool) GetSandbox(reservationID string) (*Sandbox, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.Reserved[reservationID]
 	return b, ok
 }
 
-func (s *SandboxPool) UpdateLastUsed(userID string) {
-	box := s.Reserved[userID]
+func (s *SandboxPool) UpdateLastUsed(reservationID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	box := s.Reserved[reservationID]
 	if box != nil {
 		box.LastUsedAt = time.Now()
 	}
 }
 
-func (s *SandboxPool) Reserve(userID string) (*Sandbox, error) {
+// AtLimit reports whether userID holds as many sandboxes as UserLimit allows.
+func (s *SandboxPool) AtLimit(userID string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.UserLimit > 0 && s.held(userID) >= s.UserLimit
+}
+
+// Reserve takes a sandbox for userID under reservationID without waiting. It fails with
+// ErrSandboxLimit if the user holds UserLimit sandboxes and with ErrSandboxBusy if none is
+// available to them.
+func (s *SandboxPool) Reserve(reservationID string, userID string) (*Sandbox, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reserve(reservationID, userID)
+}
+
+// Acquire reserves a sandbox like Reserve, but waits for one to be released while none is
+// available to userID. It gives up when ctx is done, and right away with ErrSandboxLimit since
+// the user's own sandboxes may be held by jobs waiting on this one.
+func (s *SandboxPool) Acquire(ctx context.Context, reservationID string, userID string) (*Sandbox, error) {
+	waiting := false
+	defer func() {
+		if waiting {
+			s.mu.Lock()
+			s.unwait(userID)
+			s.mu.Unlock()
+		}
+	}()
+
+	for {
+		s.mu.Lock()
+		if len(s.AvailableBoxes)+len(s.Reserved) == 0 {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("none of the %d sandboxes could be initialized", s.Count)
+		}
+		box, err := s.reserve(reservationID, userID)
+		if errors.Is(err, ErrSandboxBusy) && !waiting {
+			s.waiting[userID]++
+			waiting = true
+		}
+		released := s.released
+		s.mu.Unlock()
+		if !errors.Is(err, ErrSandboxBusy) {
+			return box, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// held counts the sandboxes reserved by userID. The caller must hold s.mu.
+func (s *SandboxPool) held(userID string) int {
+	n := 0
+	for _, box := range s.Reserved {
+		if box.UserID == userID {
+			n++
+		}
+	}
+	return n
+}
+
+// deferTo reports whether another waiting user holds fewer sandboxes than userID and so should
+// get the next one. The caller must hold s.mu.
+func (s *SandboxPool) deferTo(userID string) bool {
+	own := s.held(userID)
+	for other := range s.waiting {
+		if other != userID && s.held(other) < own {
+			return true
+		}
+	}
+	return false
+}
+
+// unwait removes a waiting Acquire call of userID and wakes the others, which may have been
+// deferring to it. The caller must hold s.mu.
+func (s *SandboxPool) unwait(userID string) {
+	s.waiting[userID]--
+	if s.waiting[userID] <= 0 {
+		delete(s.waiting, userID)
+	}
+	s.notify()
+}
+
+// notify wakes every waiting Acquire call. The caller must hold s.mu.
+func (s *SandboxPool) notify() {
+	close(s.released)
+	s.released = make(chan struct{})
+}
 
-	if len(s.AvailableBoxes) == 0 {
+// reserve takes an available sandbox for userID under reservationID. The caller must hold s.mu.
+func (s *SandboxPool) reserve(reservationID string, userID string) (*Sandbox, error) {
+	if _, ok := s.Reserved[reservationID]; ok {
+		return nil, fmt.Errorf("reservation %s already holds a sandbox", reservationID)
+	}
+	if s.UserLimit > 0 && s.held(userID) >= s.UserLimit {
+		return nil, ErrSandboxLimit
+	}
+	if len(s.AvailableBoxes) == 0 || s.deferTo(userID) {
 		return nil, ErrSandboxBusy
 	}
 
@@ -90,21 +226,50 @@ func (s *SandboxPool) Reserve(userID string) (*Sandbox, error) {
 
 	box := &Sandbox{
 		ID:         selected,
+		UserID:     userID,
 		ExpiresAt:  time.Now().Add(s.SandboxTTL),
 		LastUsedAt: time.Now(),
 	}
 
-	s.Reserved[userID] = box
+	s.Reserved[reservationID] = box
 	delete(s.AvailableBoxes, selected)
 
 	return box, nil
 }
 
-func (s *SandboxPool) Release(userID string) {
+func (s *SandboxPool) Release(reservationID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	box := s.Reserved[reservationID]
+	if box == nil {
+		return
+	}
+	s.AvailableBoxes[box.ID] = true
+	delete(s.Reserved, reservationID)
+
+	s.notify()
+}
+
+// Reset wipes the sandbox held by reservationID and returns it to the pool. If the sandbox cannot
+// be reset it stays reserved until Cleanup retries.
+func (s *SandboxPool) Reset(reservationID string) error {
 	s.mu.Lock()
-	s.AvailableBoxes[s.Reserved[userID].ID] = true
-	delete(s.Reserved, userID)
+	box := s.Reserved[reservationID]
 	s.mu.Unlock()
+	if box == nil {
+		return nil
+	}
+
+	if err := s.delete(box.ID); err != nil {
+		return err
+	}
+	if err := s.init(box.ID); err != nil {
+		return err
+	}
+
+	s.Release(reservationID)
+	return nil
 }
 
 func (s *SandboxPool) init(boxID int) error {
@@ -130,7 +295,7 @@ func (s *SandboxPool) Cleanup() {
 
 	releaseQueue := []string{}
 
-	for userID, box := range s.Reserved {
+	for reservationID, box := range s.Reserved {
 		if box == nil {
 			continue
 		}
@@ -151,13 +316,13 @@ func (s *SandboxPool) Cleanup() {
 				continue
 			}
 
-			releaseQueue = append(releaseQueue, userID)
+			releaseQueue = append(releaseQueue, reservationID)
 		}
 	}
 	s.mu.Unlock()
 
-	for _, user := range releaseQueue {
-		s.Release(user)
+	for _, reservationID := range releaseQueue {
+		s.Release(reservationID)
 	}
 
 	logger.Infof("%d sandboxes cleaned", len(releaseQueue))